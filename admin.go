@@ -0,0 +1,166 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/marcopaganini/quotes-exporter/provider"
+)
+
+const (
+	// watchlistPath is the prefix of the watchlist admin endpoints.
+	watchlistPath = "/-/watchlists"
+	// cachePath is the prefix of the cache admin endpoints.
+	cachePath = "/-/cache"
+)
+
+// cacheItem describes a cache entry in the cache admin endpoints.
+type cacheItem struct {
+	Key      string          `json:"key"`
+	Kind     string          `json:"kind"`
+	Expires  time.Time       `json:"expires"`
+	Quote    *provider.Quote `json:"quote,omitempty"`
+	Provider string          `json:"provider,omitempty"`
+	Fetched  *time.Time      `json:"fetched,omitempty"`
+}
+
+// adminToken holds the bearer token for the admin API. The admin API is
+// disabled when nil.
+var adminToken *secret
+
+// authorized returns true if the request carries the admin bearer token.
+func authorized(r *http.Request) bool {
+	if adminToken == nil {
+		return false
+	}
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return subtle.ConstantTimeCompare([]byte(token), []byte(adminToken.Get())) == 1
+}
+
+// adminOnly wraps a handler so it only runs for authorized requests.
+func adminOnly(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !authorized(r) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="quotes-exporter"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		h(w, r)
+	}
+}
+
+// querySymbols returns the symbols in the symbols query parameters of a
+// request, given as ?symbols=A,B&symbols=C.
+func querySymbols(r *http.Request) []string {
+	var symbols []string
+	for _, v := range r.URL.Query()["symbols"] {
+		for _, s := range strings.Split(v, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				symbols = append(symbols, s)
+			}
+		}
+	}
+	return symbols
+}
+
+// watchlistHandler manages watchlists at runtime:
+//
+//	GET    /-/watchlists                      Return all watchlists.
+//	POST   /-/watchlists/NAME?symbols=A,B     Add symbols to (or create) a watchlist.
+//	DELETE /-/watchlists/NAME?symbols=A,B     Remove symbols from a watchlist.
+//	DELETE /-/watchlists/NAME                 Delete a watchlist.
+func watchlistHandler(w http.ResponseWriter, r *http.Request) {
+	name := strings.Trim(strings.TrimPrefix(r.URL.Path, watchlistPath), "/")
+	log := logger("admin").With("remote", r.RemoteAddr, "method", r.Method, "watchlist", name)
+
+	symbols := querySymbols(r)
+
+	var err error
+	switch {
+	case r.Method == http.MethodGet && name == "":
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(watchlists.all())
+		return
+	case name == "" || strings.Contains(name, "/"):
+		http.Error(w, "invalid watchlist name", http.StatusBadRequest)
+		return
+	case r.Method == http.MethodPost:
+		if len(symbols) == 0 {
+			http.Error(w, "missing symbols", http.StatusBadRequest)
+			return
+		}
+		err = watchlists.add(name, symbols)
+	case r.Method == http.MethodDelete:
+		if _, ok := watchlists.get(name); !ok {
+			http.Error(w, "unknown watchlist", http.StatusNotFound)
+			return
+		}
+		err = watchlists.remove(name, symbols)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err != nil {
+		log.Error("Error saving watchlists", "error", err)
+		http.Error(w, "error saving watchlists: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	log.Info("Updated watchlist", "symbols", symbols)
+
+	list, _ := watchlists.get(name)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string][]string{name: list})
+}
+
+// cacheHandler inspects and flushes the cache:
+//
+//	GET  /-/cache                       Return all cache entries.
+//	POST /-/cache/flush?symbols=A,B     Remove all entries of some symbols.
+//	POST /-/cache/flush                 Remove all entries.
+func cacheHandler(w http.ResponseWriter, r *http.Request) {
+	action := strings.Trim(strings.TrimPrefix(r.URL.Path, cachePath), "/")
+	log := logger("admin").With("remote", r.RemoteAddr, "method", r.Method)
+
+	switch {
+	case r.Method == http.MethodGet && action == "":
+		items := []cacheItem{}
+		for key, item := range cache.Storage.Items() {
+			ci := cacheItem{Key: key, Kind: cacheKind(key), Expires: time.Unix(0, item.Expiration)}
+			if q, ok := item.Object.(quote); ok {
+				ci.Quote = &q.Quote
+				ci.Provider = q.provider
+				ci.Fetched = &q.fetched
+			}
+			items = append(items, ci)
+		}
+		sort.Slice(items, func(i, j int) bool { return items[i].Key < items[j].Key })
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(items)
+	case action != "flush":
+		http.Error(w, "not found", http.StatusNotFound)
+	case r.Method != http.MethodPost:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	default:
+		symbols := querySymbols(r)
+		n := flushCache(symbols)
+		log.Info("Flushed cache", "symbols", symbols, "entries", n)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]int{"flushed": n})
+	}
+}
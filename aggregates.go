@@ -0,0 +1,114 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/marcopaganini/quotes-exporter/yahoo"
+)
+
+// aggregateCurrency is the currency watchlist market caps are summed in.
+const aggregateCurrency = "USD"
+
+var (
+	watchlistSymbolsDesc = prometheus.NewDesc(
+		"quotes_exporter_watchlist_symbols",
+		"Number of symbols in a watchlist, by direction of their daily change.",
+		[]string{"list", "direction"}, nil,
+	)
+	watchlistChangeDesc = prometheus.NewDesc(
+		"quotes_exporter_watchlist_change_percent_avg",
+		"Average daily change of the symbols in a watchlist, in percent.",
+		[]string{"list"}, nil,
+	)
+	watchlistMarketCapDesc = prometheus.NewDesc(
+		"quotes_exporter_watchlist_market_cap",
+		"Total market capitalization of the symbols in a watchlist.",
+		[]string{"list", "currency"}, nil,
+	)
+)
+
+// priceSummary returns the daily change and market capitalization of a
+// symbol. Results are cached just like quotes.
+func priceSummary(symbol string) (yahoo.Price, error) {
+	fetcher := func() (interface{}, error) {
+		ctx, cancel := upstreamContext(context.Background())
+		defer cancel()
+		return yahoo.PriceSummary(ctx, yahooSymbol(symbol))
+	}
+
+	pret, err, _ := memoized("price:"+symbol, fetcher)
+	if err != nil {
+		return yahoo.Price{}, err
+	}
+	p, ok := pret.(yahoo.Price)
+	if !ok {
+		return yahoo.Price{}, fmt.Errorf("invalid price data for %s: %v", symbol, pret)
+	}
+	return p, nil
+}
+
+// collectAggregates emits the number of symbols up and down on the day, the
+// average daily change and the total market capitalization of a watchlist.
+// Symbols that fail are left out of the aggregates.
+func collectAggregates(ch chan<- prometheus.Metric, list string) {
+	log := logger("aggregates").With("provider", "yahoo", "list", list)
+	symbols, ok := watchlists.get(list)
+	if !ok {
+		return
+	}
+
+	counts := map[string]float64{"up": 0, "down": 0, "unchanged": 0}
+	var changes, marketCap float64
+	for _, symbol := range symbols {
+		p, err := priceSummary(symbol)
+		if err != nil {
+			errorCount.Inc()
+			log.Error("Error fetching daily change", "symbol", symbol, "error", err)
+			continue
+		}
+
+		switch {
+		case p.ChangePercent > 0:
+			counts["up"]++
+		case p.ChangePercent < 0:
+			counts["down"]++
+		default:
+			counts["unchanged"]++
+		}
+		changes += p.ChangePercent
+
+		if p.MarketCap > 0 && p.Currency != "" {
+			rate, err := exchangeRate(p.Currency, aggregateCurrency)
+			if err != nil {
+				errorCount.Inc()
+				log.Error("Error converting market cap", "symbol", symbol, "from", p.Currency, "error", err)
+				continue
+			}
+			marketCap += p.MarketCap * rate
+		}
+	}
+
+	var n float64
+	for direction, count := range counts {
+		ch <- prometheus.MustNewConstMetric(watchlistSymbolsDesc, prometheus.GaugeValue, count, list, direction)
+		n += count
+	}
+	if n > 0 {
+		ch <- prometheus.MustNewConstMetric(watchlistChangeDesc, prometheus.GaugeValue, changes/n, list)
+	}
+	ch <- prometheus.MustNewConstMetric(watchlistMarketCapDesc, prometheus.GaugeValue, marketCap, list, aggregateCurrency)
+}
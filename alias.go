@@ -0,0 +1,66 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"strings"
+)
+
+// aliasConfig maps a user chosen symbol to the upstream symbol and a display
+// name. Both fields are optional.
+type aliasConfig struct {
+	// Symbol is the symbol to fetch from upstream (e.g. "BZ=F" for "BRENT").
+	Symbol string `json:"symbol"`
+	// Name is exported in the "name" label.
+	Name string `json:"name"`
+}
+
+// alias returns the alias configuration for a symbol, ignoring case.
+func alias(symbol string) (aliasConfig, bool) {
+	if a, ok := cfg.Aliases[symbol]; ok {
+		return a, true
+	}
+	for k, a := range cfg.Aliases {
+		if strings.EqualFold(k, symbol) {
+			return a, true
+		}
+	}
+	return aliasConfig{}, false
+}
+
+// upstreamSymbol returns the symbol to fetch from upstream for a symbol.
+// ISINs and CUSIPs are resolved to tickers.
+func upstreamSymbol(symbol string) string {
+	if a, ok := alias(symbol); ok && a.Symbol != "" {
+		symbol = a.Symbol
+	}
+	if idType := identifierType(symbol); idType != "" {
+		ticker, err := resolveIdentifier(idType, symbol)
+		if err != nil {
+			logger("openfigi").Error("Error resolving identifier", "provider", "openfigi", "symbol", symbol, "error", err)
+			return symbol
+		}
+		return ticker
+	}
+	return symbol
+}
+
+// displayName returns the name to export for a symbol.
+func displayName(symbol string) string {
+	if a, ok := alias(symbol); ok && a.Name != "" {
+		return a.Name
+	}
+	if b, ok := basket(symbol); ok && b.Name != "" {
+		return b.Name
+	}
+	return symbol
+}
@@ -0,0 +1,119 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package alpaca
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/marcopaganini/quotes-exporter/provider"
+)
+
+const snapshotsURL = "https://data.alpaca.markets/v2/stocks/snapshots?symbols=%s&feed=%s"
+
+// bar holds the aggregates of a trading day.
+type bar struct {
+	Open   float64 `json:"o"`
+	High   float64 `json:"h"`
+	Low    float64 `json:"l"`
+	Close  float64 `json:"c"`
+	Volume float64 `json:"v"`
+}
+
+// snapshot is the snapshot of a symbol.
+type snapshot struct {
+	LatestTrade struct {
+		Price float64   `json:"p"`
+		Time  time.Time `json:"t"`
+	} `json:"latestTrade"`
+	DailyBar     bar `json:"dailyBar"`
+	PrevDailyBar bar `json:"prevDailyBar"`
+}
+
+// Provider is the Alpaca market data quote provider.
+type Provider struct {
+	// Key and Secret return the API key ID and secret key. A per-request
+	// token holds both, as "ID:SECRET".
+	Key    func() string
+	Secret func() string
+	// Feed is the data feed: iex (free) or sip (paid, all US exchanges).
+	Feed string
+	// Limiter limits the requests sent upstream.
+	Limiter *provider.Limiter
+}
+
+// credentials returns the API key ID and secret key to use.
+func (p *Provider) credentials(ctx context.Context) (string, string) {
+	var id, secret string
+	if p.Key != nil {
+		id = p.Key()
+	}
+	if p.Secret != nil {
+		secret = p.Secret()
+	}
+	if token := provider.Token(ctx, ""); token != "" {
+		id, secret = token, ""
+		if i := strings.Index(token, ":"); i >= 0 {
+			id, secret = token[:i], token[i+1:]
+		}
+	}
+	return id, secret
+}
+
+// Quote returns the quotes of the given symbols with one snapshots request.
+func (p *Provider) Quote(ctx context.Context, symbols []string) ([]provider.Quote, error) {
+	id, secret := p.credentials(ctx)
+	if id == "" || secret == "" {
+		return nil, errors.New("missing Alpaca API key ID or secret key")
+	}
+	header := http.Header{
+		"Apca-Api-Key-Id":     []string{id},
+		"Apca-Api-Secret-Key": []string{secret},
+	}
+	feed := p.Feed
+	if feed == "" {
+		feed = "iex"
+	}
+	if err := p.Limiter.Allow(); err != nil {
+		return nil, err
+	}
+
+	var resp map[string]snapshot
+	u := fmt.Sprintf(snapshotsURL, url.QueryEscape(strings.ToUpper(strings.Join(symbols, ","))), url.QueryEscape(feed))
+	if err := provider.GetJSON(ctx, u, header, &resp); err != nil {
+		return nil, err
+	}
+
+	var ret []provider.Quote
+	for symbol, s := range resp {
+		if s.LatestTrade.Price == 0 {
+			continue
+		}
+		ret = append(ret, provider.Quote{
+			Symbol:        symbol,
+			Price:         s.LatestTrade.Price,
+			Currency:      "USD",
+			Time:          s.LatestTrade.Time,
+			Open:          s.DailyBar.Open,
+			High:          s.DailyBar.High,
+			Low:           s.DailyBar.Low,
+			Volume:        s.DailyBar.Volume,
+			PreviousClose: s.PrevDailyBar.Close,
+		})
+	}
+	return ret, nil
+}
@@ -0,0 +1,85 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package alphavantage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/marcopaganini/quotes-exporter/provider"
+)
+
+const quoteURL = "https://www.alphavantage.co/query?function=GLOBAL_QUOTE&symbol=%s&apikey=%s"
+
+// globalQuoteResponse is the response of the GLOBAL_QUOTE function. Alpha
+// Vantage reports errors and exceeded limits with HTTP 200 and a message.
+type globalQuoteResponse struct {
+	GlobalQuote struct {
+		Symbol string `json:"01. symbol"`
+		Price  string `json:"05. price"`
+	} `json:"Global Quote"`
+	ErrorMessage string `json:"Error Message"`
+	Note         string `json:"Note"`
+	Information  string `json:"Information"`
+}
+
+// Provider is the Alpha Vantage quote provider.
+type Provider struct {
+	// Key returns the API key.
+	Key func() string
+	// Limiter limits the requests sent upstream (the free tier allows 5
+	// requests per minute).
+	Limiter *provider.Limiter
+}
+
+// Quote returns the quotes of the given symbols, one request per symbol.
+func (p *Provider) Quote(ctx context.Context, symbols []string) ([]provider.Quote, error) {
+	var key string
+	if p.Key != nil {
+		key = p.Key()
+	}
+	key = provider.Token(ctx, key)
+	if key == "" {
+		return nil, errors.New("missing Alpha Vantage API key")
+	}
+
+	return provider.Each(ctx, symbols, func(ctx context.Context, symbol string) (provider.Quote, error) {
+		if err := p.Limiter.Allow(); err != nil {
+			return provider.Quote{}, fmt.Errorf("%s: %w", symbol, err)
+		}
+
+		var resp globalQuoteResponse
+		u := fmt.Sprintf(quoteURL, url.QueryEscape(strings.ToUpper(symbol)), url.QueryEscape(key))
+		if err := provider.GetJSON(ctx, u, nil, &resp); err != nil {
+			return provider.Quote{}, err
+		}
+		switch {
+		case resp.ErrorMessage != "":
+			return provider.Quote{}, fmt.Errorf("upstream error for %s: %s", symbol, resp.ErrorMessage)
+		case resp.Note != "" || resp.Information != "":
+			// Usually means the API call limit was exceeded.
+			return provider.Quote{}, fmt.Errorf("%v: %s%s", provider.ErrRateLimited, resp.Note, resp.Information)
+		case resp.GlobalQuote.Price == "":
+			return provider.Quote{}, fmt.Errorf("%w: %s", provider.ErrNotFound, symbol)
+		}
+
+		price, err := strconv.ParseFloat(resp.GlobalQuote.Price, 64)
+		if err != nil {
+			return provider.Quote{}, fmt.Errorf("invalid price for %s: %v", symbol, err)
+		}
+		return provider.Quote{Symbol: symbol, Price: price}, nil
+	})
+}
@@ -0,0 +1,101 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// basketConfig defines a custom basket of symbols.
+type basketConfig struct {
+	// Name is exported in the "name" label.
+	Name string `json:"name"`
+	// Symbols maps symbols to the number of units of each in the basket.
+	Symbols map[string]float64 `json:"symbols"`
+}
+
+// basketChangeDesc describes the daily change of a basket.
+var basketChangeDesc = prometheus.NewDesc(
+	"quotes_exporter_basket_change_percent",
+	"Change of a basket from the previous close of its symbols, in percent.",
+	[]string{"symbol", "name"}, nil,
+)
+
+// basket returns the basket configuration for a symbol, ignoring case.
+func basket(symbol string) (basketConfig, bool) {
+	for k, b := range cfg.Baskets {
+		if strings.EqualFold(k, symbol) {
+			return b, true
+		}
+	}
+	return basketConfig{}, false
+}
+
+// basketQuote returns the level of a basket: the sum of the prices of its
+// symbols times their units. The currency is only set if all symbols are
+// quoted in the same currency, and the previous close if all symbols report
+// theirs.
+func basketQuote(ctx context.Context, b basketConfig) (quote, error) {
+	var ret quote
+	currencies := map[string]bool{}
+	prevKnown := true
+	for symbol, units := range b.Symbols {
+		symbol := symbol
+		fetcher := func() (interface{}, error) {
+			return fetchQuote(ctx, symbol, "")
+		}
+		qret, err, _ := memoized(quoteKey(symbol, ""), fetcher)
+		if err != nil {
+			return quote{}, fmt.Errorf("%s: %v", symbol, err)
+		}
+		q, ok := qret.(quote)
+		if !ok {
+			return quote{}, fmt.Errorf("invalid quote data for %s: %v", symbol, qret)
+		}
+		ret.Price += q.Price * units
+		ret.PreviousClose += q.PreviousClose * units
+		prevKnown = prevKnown && q.PreviousClose != 0
+		currencies[q.Currency] = true
+	}
+	if !prevKnown {
+		ret.PreviousClose = 0
+	}
+	if len(currencies) == 1 {
+		for c := range currencies {
+			ret.Currency = c
+		}
+	}
+	return ret, nil
+}
+
+// collectBasketChange emits the daily change of a basket, based on the
+// previous close of its symbols.
+func collectBasketChange(ch chan<- prometheus.Metric, symbol string, b basketConfig, level float64) {
+	var prev float64
+	for s, units := range b.Symbols {
+		p, err := priceSummary(s)
+		if err != nil {
+			errorCount.Inc()
+			logger("basket").Error("Error fetching previous close", "provider", "yahoo", "symbol", s, "basket", symbol, "error", err)
+			return
+		}
+		prev += p.PreviousClose * units
+	}
+	if prev == 0 {
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(basketChangeDesc, prometheus.GaugeValue, (level-prev)/prev*100, symbol, displayName(symbol))
+}
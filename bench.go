@@ -0,0 +1,112 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	// Flags for the bench command.
+	benchConcurrency int
+	benchRequests    int
+
+	// quoteFetches counts quotes fetched from upstream (cache misses).
+	quoteFetches int64
+)
+
+// benchCmd scrapes the symbols given as arguments (or all watchlist symbols)
+// repeatedly and reports scrape latencies and upstream call counts.
+func benchCmd(fs *flag.FlagSet) error {
+	symbols := fs.Args()
+	if len(symbols) == 0 {
+		symbols = watchlists.symbols()
+	}
+	if len(symbols) == 0 {
+		return fmt.Errorf("no symbols to scrape")
+	}
+	if benchConcurrency < 1 || benchRequests < 1 {
+		return fmt.Errorf("concurrency and requests must be positive")
+	}
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(collector{symbols: symbols})
+
+	var (
+		mu        sync.Mutex
+		latencies []time.Duration
+		failed    int
+		wg        sync.WaitGroup
+	)
+	errorsBefore := counterValue(errorCount)
+	reqs := make(chan struct{})
+
+	start := time.Now()
+	for i := 0; i < benchConcurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range reqs {
+				t := time.Now()
+				_, err := registry.Gather()
+				d := time.Since(t)
+
+				mu.Lock()
+				latencies = append(latencies, d)
+				if err != nil {
+					failed++
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for i := 0; i < benchRequests; i++ {
+		reqs <- struct{}{}
+	}
+	close(reqs)
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	pct := func(p float64) time.Duration {
+		return latencies[int(p*float64(len(latencies)-1))]
+	}
+
+	fetches := atomic.LoadInt64(&quoteFetches)
+
+	fmt.Printf("Scrapes:          %d (%d symbols, concurrency %d) in %v\n", benchRequests, len(symbols), benchConcurrency, elapsed.Round(time.Millisecond))
+	fmt.Printf("Scrapes/second:   %.1f\n", float64(benchRequests)/elapsed.Seconds())
+	fmt.Printf("Failed scrapes:   %d\n", failed)
+	fmt.Printf("Latency:          min %v, p50 %v, p90 %v, p99 %v, max %v\n",
+		latencies[0], pct(0.5), pct(0.9), pct(0.99), latencies[len(latencies)-1])
+	fmt.Printf("Upstream quotes:  %d (%.2f per scrape)\n", fetches, float64(fetches)/float64(benchRequests))
+	fmt.Printf("Upstream errors:  %.0f\n", counterValue(errorCount)-errorsBefore)
+	return nil
+}
+
+// counterValue returns the current value of a counter.
+func counterValue(c prometheus.Counter) float64 {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(c)
+	mfs, err := registry.Gather()
+	if err != nil || len(mfs) == 0 || len(mfs[0].GetMetric()) == 0 {
+		return 0
+	}
+	return mfs[0].GetMetric()[0].GetCounter().GetValue()
+}
@@ -0,0 +1,119 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package brapi
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/marcopaganini/quotes-exporter/provider"
+)
+
+const (
+	quoteURL = "https://brapi.dev/api/quote/%s"
+)
+
+// result is a quote returned by the quote API.
+type result struct {
+	Symbol                     string  `json:"symbol"`
+	Currency                   string  `json:"currency"`
+	RegularMarketPrice         float64 `json:"regularMarketPrice"`
+	RegularMarketOpen          float64 `json:"regularMarketOpen"`
+	RegularMarketDayHigh       float64 `json:"regularMarketDayHigh"`
+	RegularMarketDayLow        float64 `json:"regularMarketDayLow"`
+	RegularMarketPreviousClose float64 `json:"regularMarketPreviousClose"`
+	RegularMarketVolume        float64 `json:"regularMarketVolume"`
+	RegularMarketChangePercent float64 `json:"regularMarketChangePercent"`
+	RegularMarketTime          string  `json:"regularMarketTime"`
+	MarketCap                  float64 `json:"marketCap"`
+	PriceEarnings              float64 `json:"priceEarnings"`
+}
+
+// quoteResponse is the response of the quote API.
+type quoteResponse struct {
+	Results []result `json:"results"`
+	Error   bool     `json:"error"`
+	Message string   `json:"message"`
+}
+
+// Provider is the brapi provider, for stocks, FIIs and BDRs listed on B3
+// (the Brazilian stock exchange). Symbols are B3 tickers (PETR4), with or
+// without the Yahoo ".SA" suffix.
+type Provider struct {
+	// Key returns the API token. A few tickers can be quoted without one.
+	Key func() string
+	// Limiter limits the requests sent upstream.
+	Limiter *provider.Limiter
+}
+
+// Quote returns the quotes of the given symbols, one request per symbol (the
+// free plan does not allow more than one ticker per request).
+func (p *Provider) Quote(ctx context.Context, symbols []string) ([]provider.Quote, error) {
+	var key string
+	if p.Key != nil {
+		key = p.Key()
+	}
+	key = provider.Token(ctx, key)
+
+	return provider.Each(ctx, symbols, func(ctx context.Context, symbol string) (provider.Quote, error) {
+		if err := p.Limiter.Allow(); err != nil {
+			return provider.Quote{}, fmt.Errorf("%s: %w", symbol, err)
+		}
+		code := ticker(symbol)
+		u := fmt.Sprintf(quoteURL, url.PathEscape(code))
+		if key != "" {
+			u += "?token=" + url.QueryEscape(key)
+		}
+
+		var resp quoteResponse
+		if err := provider.GetJSON(ctx, u, nil, &resp); err != nil {
+			return provider.Quote{}, err
+		}
+		if resp.Error {
+			return provider.Quote{}, fmt.Errorf("brapi error for %s: %s", code, resp.Message)
+		}
+		if len(resp.Results) == 0 || resp.Results[0].RegularMarketPrice == 0 {
+			return provider.Quote{}, fmt.Errorf("%w: %s", provider.ErrNotFound, code)
+		}
+
+		r := resp.Results[0]
+		q := provider.Quote{
+			Symbol:        symbol,
+			Price:         r.RegularMarketPrice,
+			Currency:      r.Currency,
+			Open:          r.RegularMarketOpen,
+			High:          r.RegularMarketDayHigh,
+			Low:           r.RegularMarketDayLow,
+			PreviousClose: r.RegularMarketPreviousClose,
+			Volume:        r.RegularMarketVolume,
+			MarketCap:     r.MarketCap,
+			PE:            r.PriceEarnings,
+			ChangePercent: r.RegularMarketChangePercent,
+		}
+		if q.Currency == "" {
+			q.Currency = "BRL"
+		}
+		if t, err := time.Parse(time.RFC3339, r.RegularMarketTime); err == nil {
+			q.Time = t
+		}
+		return q, nil
+	})
+}
+
+// ticker returns the B3 ticker of a symbol, dropping the Yahoo ".SA" suffix.
+func ticker(symbol string) string {
+	symbol = strings.ToUpper(symbol)
+	return strings.TrimSuffix(symbol, ".SA")
+}
@@ -0,0 +1,121 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// lastQuoteTTL is how long the last quotes of providers are kept, to be
+// served while their circuit is open.
+const lastQuoteTTL = 24 * time.Hour
+
+// errCircuitOpen is returned instead of calling a provider whose circuit
+// breaker is open.
+var errCircuitOpen = errors.New("provider circuit breaker open")
+
+var circuitOpenDesc = prometheus.NewDesc(
+	"quotes_exporter_provider_circuit_open",
+	"Whether calls to a provider are suspended after repeated failures (1) or not (0).",
+	[]string{"provider"}, nil,
+)
+
+// lastQuotes holds the last quote of each symbol served by the providers,
+// keyed by provider and provider symbol. Expired quotes are dropped hourly.
+var lastQuotes = struct {
+	sync.Mutex
+	quotes map[string]quote
+	pruned time.Time
+}{quotes: map[string]quote{}}
+
+// allowCircuit returns errCircuitOpen if calls to a provider are suspended.
+// Once the cooldown has passed, a single call goes through to check if the
+// provider recovered.
+func allowCircuit(name string) error {
+	if cfg.Breaker.Failures <= 0 {
+		return nil
+	}
+	providerHealth.Lock()
+	defer providerHealth.Unlock()
+
+	s, ok := providerHealth.states[name]
+	if !ok || !s.open {
+		return nil
+	}
+	if s.probing || time.Since(s.openedAt) < cfg.Breaker.Cooldown {
+		return errCircuitOpen
+	}
+	s.probing = true
+	return nil
+}
+
+// saveLastQuote saves the last quote of a provider symbol, served while the
+// circuit of the provider is open. Quotes are only kept if the circuit
+// breaker is enabled, and for lastQuoteTTL at most.
+func saveLastQuote(name, psym string, q quote) {
+	if cfg.Breaker.Failures <= 0 {
+		return
+	}
+	lastQuotes.Lock()
+	defer lastQuotes.Unlock()
+	lastQuotes.quotes[quoteKey(strings.ToUpper(psym), name)] = q
+
+	if time.Since(lastQuotes.pruned) < time.Hour {
+		return
+	}
+	for key, lq := range lastQuotes.quotes {
+		if time.Since(lq.fetched) > lastQuoteTTL {
+			delete(lastQuotes.quotes, key)
+		}
+	}
+	lastQuotes.pruned = time.Now()
+}
+
+// lastQuote returns the last quote of a provider symbol, if any and not
+// expired.
+func lastQuote(name, psym string) (quote, bool) {
+	lastQuotes.Lock()
+	defer lastQuotes.Unlock()
+	q, ok := lastQuotes.quotes[quoteKey(strings.ToUpper(psym), name)]
+	return q, ok && time.Since(q.fetched) <= lastQuoteTTL
+}
+
+// breakerCollector exports the state of the circuit breakers of the
+// providers used so far.
+type breakerCollector struct{}
+
+// Describe outputs description for prometheus timeseries.
+func (breakerCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- circuitOpenDesc
+}
+
+// Collect outputs the circuit breaker gauges.
+func (breakerCollector) Collect(ch chan<- prometheus.Metric) {
+	if cfg.Breaker.Failures <= 0 {
+		return
+	}
+	providerHealth.Lock()
+	defer providerHealth.Unlock()
+
+	for name, s := range providerHealth.states {
+		v := 0.0
+		if s.open {
+			v = 1
+		}
+		ch <- prometheus.MustNewConstMetric(circuitOpenDesc, prometheus.GaugeValue, v, name)
+	}
+}
@@ -0,0 +1,124 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/marcopaganini/quotes-exporter/provider"
+)
+
+// cacheEntry is a cached quote, as saved in the cache file.
+type cacheEntry struct {
+	Key      string         `json:"key"`
+	Quote    provider.Quote `json:"quote"`
+	Provider string         `json:"provider,omitempty"`
+	Fetched  time.Time      `json:"fetched"`
+	Expires  time.Time      `json:"expires"`
+}
+
+// loadCacheFile adds the unexpired quotes saved in the cache file to the
+// cache. A missing file is not an error.
+func loadCacheFile(fname string) error {
+	data, err := os.ReadFile(fname)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var entries []cacheEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	var n int
+	for _, e := range entries {
+		if !e.Expires.After(now) {
+			continue
+		}
+		q := quote{Quote: e.Quote, fetched: e.Fetched, provider: e.Provider}
+		cache.Storage.Set(e.Key, q, e.Expires.Sub(now))
+		n++
+	}
+	logger("cache").Info("Loaded cached quotes", "file", fname, "quotes", n)
+	return nil
+}
+
+// saveCacheFile saves the cached quotes to the cache file.
+func saveCacheFile(fname string) error {
+	entries := []cacheEntry{}
+	for key, item := range cache.Storage.Items() {
+		q, ok := item.Object.(quote)
+		if !ok {
+			continue
+		}
+		entries = append(entries, cacheEntry{
+			Key:      key,
+			Quote:    q.Quote,
+			Provider: q.provider,
+			Fetched:  q.fetched,
+			Expires:  time.Unix(0, item.Expiration),
+		})
+	}
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file and rename, so a crash never leaves a
+	// truncated cache file behind.
+	tmp, err := os.CreateTemp(filepath.Dir(fname), ".cache-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), fname)
+}
+
+// persistCache saves the cache to the cache file every interval, and once
+// more when the exporter is asked to stop, sending to done afterwards. It
+// never returns.
+func persistCache(fname string, interval time.Duration, stop <-chan os.Signal, done chan<- error) {
+	log := logger("cache").With("file", fname)
+	save := func() {
+		if err := saveCacheFile(fname); err != nil {
+			log.Error("Error saving cache", "error", err)
+		}
+	}
+
+	var tick <-chan time.Time
+	if interval > 0 {
+		tick = time.Tick(interval)
+	}
+	for {
+		select {
+		case <-tick:
+			save()
+		case sig := <-stop:
+			log.Info("Saving cache before exiting", "signal", sig)
+			save()
+			done <- nil
+		}
+	}
+}
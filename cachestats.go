@@ -0,0 +1,229 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"strings"
+	"time"
+
+	"github.com/kofalt/go-memoize"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	cacheEvictions = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "quotes_exporter_cache_evictions_total",
+			Help: "Count of entries removed from the cache.",
+		},
+		[]string{"kind"},
+	)
+
+	cacheHits = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "quotes_exporter_cache_hits_total",
+			Help: "Count of lookups served from the cache.",
+		},
+		[]string{"kind"},
+	)
+	cacheMisses = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "quotes_exporter_cache_misses_total",
+			Help: "Count of lookups missing from the cache, fetched from upstream.",
+		},
+		[]string{"kind"},
+	)
+
+	negativeCacheHits = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "quotes_exporter_negative_cache_hits_total",
+			Help: "Count of lookups of symbols cached as unknown upstream.",
+		},
+	)
+
+	cacheEntriesDesc = prometheus.NewDesc(
+		"quotes_exporter_cache_entries",
+		"Number of entries in the cache.",
+		[]string{"kind"}, nil,
+	)
+	cacheOldestDesc = prometheus.NewDesc(
+		"quotes_exporter_cache_oldest_entry_age_seconds",
+		"Age of the oldest entry in the cache.",
+		[]string{"kind"}, nil,
+	)
+)
+
+// setupCache creates the cache with the configured expiration and cleanup
+// interval. Must be called before the cache is used.
+func setupCache() {
+	cache = memoize.NewMemoizer(cfg.Cache.TTL, cfg.Cache.CleanupInterval)
+	cache.Storage.OnEvicted(func(key string, _ interface{}) {
+		cacheEvictions.WithLabelValues(cacheKind(key)).Inc()
+	})
+}
+
+// memoized returns the cached result of key, or calls fn to fetch and cache it,
+// counting cache hits and misses.
+func memoized(key string, fn func() (interface{}, error)) (interface{}, error, bool) {
+	v, err, cached := cache.Memoize(key, fn)
+	if cached {
+		cacheHits.WithLabelValues(cacheKind(key)).Inc()
+	} else {
+		cacheMisses.WithLabelValues(cacheKind(key)).Inc()
+	}
+	return v, err, cached
+}
+
+// memoizeQuote returns the cached quote of a symbol under key, or calls fn to
+// fetch and cache it. Memoize caches for cache.ttl, so fresh quotes that need
+// a different TTL are cached again.
+func memoizeQuote(key, symbol string, fn func() (interface{}, error)) (interface{}, error, bool) {
+	recordLookup(key)
+	qret, err, cached := memoized(key, fn)
+	if q, ok := qret.(quote); ok && err == nil && !cached {
+		if ttl := quoteTTL(symbol, q); ttl != cfg.Cache.TTL {
+			cache.Storage.Set(key, q, ttl)
+		}
+	}
+	return qret, err, cached
+}
+
+// quoteTTL returns how long to cache a quote of a symbol. Quotes of closed
+// markets may be cached for longer, and per asset type settings take
+// precedence over cache.ttl.
+func quoteTTL(symbol string, q quote) time.Duration {
+	if ttl, ok := closedMarketTTL(symbol, q); ok && ttl > cfg.Cache.TTL {
+		return ttl
+	}
+	if len(cfg.cacheTTLs) > 0 {
+		if ttl, ok := cfg.cacheTTLs[strings.ToUpper(assetType(symbol))]; ok {
+			return ttl
+		}
+	}
+	return cfg.Cache.TTL
+}
+
+// cacheKinds holds the kinds of data in the cache, used as key prefixes.
+var cacheKinds = map[string]bool{
+	"quote": true, "notfound": true, "price": true, "meta": true, "fx": true,
+	"events": true, "history": true, "holdings": true, "sentiment": true,
+	"spark": true, "exchange": true, "staking": true, "figi": true,
+}
+
+// cacheKind returns the kind of data held by a cache key, from its "kind:"
+// prefix. Keys without a known kind are "other", so symbols requested by
+// clients can't create new kinds.
+func cacheKind(key string) string {
+	if i := strings.Index(key, ":"); i > 0 && cacheKinds[key[:i]] {
+		return key[:i]
+	}
+	return "other"
+}
+
+// cacheSymbol returns the symbol of a cache key, if any. Keys hold the
+// symbol after the kind, followed by the provider or other parameters.
+// Quote symbols may contain ":", so only the provider is split off them.
+func cacheSymbol(key string) string {
+	switch kind := cacheKind(key); kind {
+	case "other":
+		return ""
+	case "quote", "notfound":
+		symbol, _ := splitQuoteKey(strings.TrimPrefix(key, "notfound:"))
+		return symbol
+	default:
+		key = key[len(kind)+1:]
+	}
+	if i := strings.Index(key, ":"); i >= 0 {
+		key = key[:i]
+	}
+	return key
+}
+
+// flushCache removes all cache entries of the given symbols, or all entries
+// if none, including expired quotes kept to be served while revalidated. It
+// returns the number of entries removed.
+func flushCache(symbols []string) int {
+	servedQuotes.Lock()
+	defer servedQuotes.Unlock()
+
+	if len(symbols) == 0 {
+		n := len(cache.Storage.Items())
+		cache.Storage.Flush()
+		servedQuotes.quotes = map[string]quote{}
+		return n
+	}
+
+	var n int
+	for key := range cache.Storage.Items() {
+		if containsFold(symbols, cacheSymbol(key)) {
+			cache.Storage.Delete(key)
+			n++
+		}
+	}
+	for key := range servedQuotes.quotes {
+		if containsFold(symbols, cacheSymbol(key)) {
+			delete(servedQuotes.quotes, key)
+		}
+	}
+	return n
+}
+
+// cacheCollector exports statistics about the contents of the cache.
+type cacheCollector struct{}
+
+// Describe outputs description for prometheus timeseries.
+func (cacheCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- cacheEntriesDesc
+	ch <- cacheOldestDesc
+	cacheHits.Describe(ch)
+	cacheMisses.Describe(ch)
+	cacheEvictions.Describe(ch)
+	negativeCacheHits.Describe(ch)
+	staleServed.Describe(ch)
+}
+
+// Collect outputs the cache statistics.
+func (cacheCollector) Collect(ch chan<- prometheus.Metric) {
+	now := time.Now()
+	entries := map[string]int{}
+	oldest := map[string]time.Duration{}
+
+	for key, item := range cache.Storage.Items() {
+		kind := cacheKind(key)
+		entries[kind]++
+
+		// Items only record their expiration, so derive their age from it,
+		// unless they know when they were fetched. Quotes may have their own
+		// TTL.
+		ttl := cfg.Cache.TTL
+		if kind == "notfound" {
+			ttl = cfg.Cache.NegativeTTL
+		}
+		age := ttl - time.Unix(0, item.Expiration).Sub(now)
+		if q, ok := item.Object.(quote); ok && !q.fetched.IsZero() {
+			age = now.Sub(q.fetched)
+		}
+		if age > oldest[kind] {
+			oldest[kind] = age
+		}
+	}
+
+	for kind, n := range entries {
+		ch <- prometheus.MustNewConstMetric(cacheEntriesDesc, prometheus.GaugeValue, float64(n), kind)
+		ch <- prometheus.MustNewConstMetric(cacheOldestDesc, prometheus.GaugeValue, oldest[kind].Seconds(), kind)
+	}
+	cacheHits.Collect(ch)
+	cacheMisses.Collect(ch)
+	cacheEvictions.Collect(ch)
+	negativeCacheHits.Collect(ch)
+	staleServed.Collect(ch)
+}
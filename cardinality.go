@@ -0,0 +1,64 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var symbolsDropped = prometheus.NewCounter(
+	prometheus.CounterOpts{
+		Name: "quotes_exporter_symbols_dropped_total",
+		Help: "Count of symbols not exported because the symbol limit was reached.",
+	},
+)
+
+// symbolGuard caps the number of distinct symbols exported within a sliding
+// time window, protecting Prometheus from cardinality explosions.
+type symbolGuard struct {
+	sync.Mutex
+	seen map[string]time.Time
+}
+
+// symbolLimit tracks the symbols exported recently.
+var symbolLimit = &symbolGuard{seen: map[string]time.Time{}}
+
+// allow returns true if series for a symbol may be exported. Symbols seen in
+// the last window are always allowed; new symbols are allowed while there
+// are fewer than max symbols in the window. A max of zero disables the limit.
+func (g *symbolGuard) allow(symbol string, max int, window time.Duration) bool {
+	if max <= 0 {
+		return true
+	}
+	symbol = strings.ToUpper(symbol)
+
+	g.Lock()
+	defer g.Unlock()
+
+	now := time.Now()
+	if _, ok := g.seen[symbol]; !ok {
+		for s, t := range g.seen {
+			if now.Sub(t) > window {
+				delete(g.seen, s)
+			}
+		}
+		if len(g.seen) >= max {
+			return false
+		}
+	}
+	g.seen[symbol] = now
+	return true
+}
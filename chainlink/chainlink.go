@@ -0,0 +1,220 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package chainlink
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/marcopaganini/quotes-exporter/provider"
+)
+
+const (
+	// Function selectors of the aggregator interface.
+	latestRoundDataSelector = "0xfeaf968c"
+	decimalsSelector        = "0x313ce567"
+)
+
+// feeds maps pairs to the addresses of Chainlink price feed proxies on the
+// Ethereum mainnet.
+var feeds = map[string]string{
+	"BTC/USD":  "0xF4030086522a5bEEa4988F8cA5B36dbC97BeE88c",
+	"ETH/USD":  "0x5f4eC3Df9cbd43714FE2740f5E3616155c5b8419",
+	"LINK/USD": "0x2c1d072e956AFFC0D435Cb7AC38EF18d24d9127c",
+	"EUR/USD":  "0xb49f677943BC038e9857d61E7d053CaA2C1734C1",
+}
+
+var addressRE = regexp.MustCompile(`^0x[0-9a-fA-F]{40}$`)
+
+// rpcRequest is a JSON-RPC request.
+type rpcRequest struct {
+	JSONRPC string        `json:"jsonrpc"`
+	ID      int           `json:"id"`
+	Method  string        `json:"method"`
+	Params  []interface{} `json:"params"`
+}
+
+// rpcResponse is a JSON-RPC response.
+type rpcResponse struct {
+	Result string `json:"result"`
+	Error  *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// Provider is the Chainlink provider, reading the latest answer of price
+// feed contracts through an Ethereum JSON-RPC endpoint. Symbols are pairs
+// (ETH/USD) or feed proxy addresses.
+type Provider struct {
+	// URL returns the JSON-RPC endpoint URL, which often holds an API key.
+	URL func() string
+	// Feeds maps extra pairs to feed proxy addresses.
+	Feeds map[string]string
+	// Limiter limits the requests sent upstream.
+	Limiter *provider.Limiter
+
+	mu       sync.Mutex
+	decimals map[string]int
+}
+
+// Quote returns the latest answers of the feeds of the given symbols, with
+// one call per feed (two the first time a feed is read).
+func (p *Provider) Quote(ctx context.Context, symbols []string) ([]provider.Quote, error) {
+	var endpoint string
+	if p.URL != nil {
+		endpoint = p.URL()
+	}
+	if endpoint == "" {
+		return nil, errors.New("missing Chainlink JSON-RPC endpoint URL")
+	}
+
+	return provider.Each(ctx, symbols, func(ctx context.Context, symbol string) (provider.Quote, error) {
+		address, ok := p.address(symbol)
+		if !ok {
+			return provider.Quote{}, fmt.Errorf("unknown Chainlink feed %q", symbol)
+		}
+		decimals, err := p.feedDecimals(ctx, endpoint, address)
+		if err != nil {
+			return provider.Quote{}, err
+		}
+
+		if err := p.Limiter.Allow(); err != nil {
+			return provider.Quote{}, fmt.Errorf("%s: %w", symbol, err)
+		}
+		data, err := call(ctx, endpoint, address, latestRoundDataSelector)
+		if err != nil {
+			return provider.Quote{}, fmt.Errorf("%s: %v", symbol, err)
+		}
+		// latestRoundData returns (roundId, answer, startedAt, updatedAt,
+		// answeredInRound), each in a 32 byte word.
+		if len(data) < 5*32 {
+			return provider.Quote{}, fmt.Errorf("%s: short latestRoundData result (%d bytes)", symbol, len(data))
+		}
+		answer := signed(data[32:64])
+		if answer.Sign() <= 0 {
+			return provider.Quote{}, fmt.Errorf("%s: invalid answer %v", symbol, answer)
+		}
+		price, _ := new(big.Float).Quo(new(big.Float).SetInt(answer), new(big.Float).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil))).Float64()
+
+		q := provider.Quote{Symbol: symbol, Price: price}
+		if i := strings.LastIndex(symbol, "/"); i >= 0 {
+			q.Currency = strings.ToUpper(symbol[i+1:])
+		}
+		if updated := new(big.Int).SetBytes(data[96:128]); updated.IsInt64() && updated.Int64() > 0 {
+			q.Time = time.Unix(updated.Int64(), 0)
+		}
+		return q, nil
+	})
+}
+
+// address returns the feed address of a symbol.
+func (p *Provider) address(symbol string) (string, bool) {
+	if addressRE.MatchString(symbol) {
+		return symbol, true
+	}
+	for pair, address := range p.Feeds {
+		if strings.EqualFold(pair, symbol) {
+			return address, true
+		}
+	}
+	address, ok := feeds[strings.ToUpper(symbol)]
+	return address, ok
+}
+
+// feedDecimals returns the number of decimals of the answers of a feed,
+// reading it from the contract the first time.
+func (p *Provider) feedDecimals(ctx context.Context, endpoint, address string) (int, error) {
+	p.mu.Lock()
+	d, ok := p.decimals[address]
+	p.mu.Unlock()
+	if ok {
+		return d, nil
+	}
+
+	if err := p.Limiter.Allow(); err != nil {
+		return 0, err
+	}
+	data, err := call(ctx, endpoint, address, decimalsSelector)
+	if err != nil {
+		return 0, fmt.Errorf("error reading decimals of %s: %v", address, err)
+	}
+	n := new(big.Int).SetBytes(data)
+	if len(data) == 0 || !n.IsInt64() || n.Int64() > 36 {
+		return 0, fmt.Errorf("invalid decimals of %s: %x", address, data)
+	}
+	d = int(n.Int64())
+
+	p.mu.Lock()
+	if p.decimals == nil {
+		p.decimals = map[string]int{}
+	}
+	p.decimals[address] = d
+	p.mu.Unlock()
+	return d, nil
+}
+
+// call runs eth_call against the latest block and returns the result.
+func call(ctx context.Context, endpoint, address, data string) ([]byte, error) {
+	body, err := json.Marshal(rpcRequest{
+		JSONRPC: "2.0",
+		ID:      1,
+		Method:  "eth_call",
+		Params:  []interface{}{map[string]string{"to": address, "data": data}, "latest"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := provider.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, provider.NewHTTPError(resp)
+	}
+
+	var r rpcResponse
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return nil, fmt.Errorf("error decoding response: %v", err)
+	}
+	if r.Error != nil {
+		return nil, fmt.Errorf("JSON-RPC error %d: %s", r.Error.Code, r.Error.Message)
+	}
+	return hex.DecodeString(strings.TrimPrefix(r.Result, "0x"))
+}
+
+// signed returns the value of a two's complement 256 bit word.
+func signed(word []byte) *big.Int {
+	n := new(big.Int).SetBytes(word)
+	if len(word) > 0 && word[0]&0x80 != 0 {
+		n.Sub(n, new(big.Int).Lsh(big.NewInt(1), uint(len(word)*8)))
+	}
+	return n
+}
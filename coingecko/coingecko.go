@@ -0,0 +1,135 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package coingecko
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/marcopaganini/quotes-exporter/provider"
+)
+
+const priceURL = "https://api.coingecko.com/api/v3/simple/price?ids=%s&vs_currencies=%s&include_market_cap=true&include_24hr_vol=true&include_last_updated_at=true"
+
+// coinIDs maps common tickers to CoinGecko coin ids. Other symbols are used
+// as coin ids.
+var coinIDs = map[string]string{
+	"BTC":   "bitcoin",
+	"ETH":   "ethereum",
+	"USDT":  "tether",
+	"BNB":   "binancecoin",
+	"SOL":   "solana",
+	"XRP":   "ripple",
+	"USDC":  "usd-coin",
+	"ADA":   "cardano",
+	"DOGE":  "dogecoin",
+	"TRX":   "tron",
+	"DOT":   "polkadot",
+	"MATIC": "matic-network",
+	"LTC":   "litecoin",
+	"AVAX":  "avalanche-2",
+	"LINK":  "chainlink",
+	"ATOM":  "cosmos",
+	"XLM":   "stellar",
+	"XMR":   "monero",
+}
+
+// Provider is the CoinGecko quote provider. Symbols are coin ids (bitcoin)
+// or tickers (BTC), optionally followed by the currency to quote them in
+// (BTC-EUR).
+type Provider struct {
+	// Key returns the (optional) demo API key.
+	Key func() string
+	// Currency is the currency to quote coins in when the symbol has none.
+	Currency string
+	// Limiter limits the requests sent upstream.
+	Limiter *provider.Limiter
+}
+
+// coin returns the coin id and the currency of a symbol.
+func (p *Provider) coin(symbol string) (string, string) {
+	currency := p.Currency
+	if currency == "" {
+		currency = "usd"
+	}
+	if i := strings.LastIndex(symbol, "-"); i > 0 {
+		symbol, currency = symbol[:i], symbol[i+1:]
+	}
+	if id, ok := coinIDs[strings.ToUpper(symbol)]; ok {
+		return id, strings.ToLower(currency)
+	}
+	return strings.ToLower(symbol), strings.ToLower(currency)
+}
+
+// Quote returns the quotes of the given symbols with one request.
+func (p *Provider) Quote(ctx context.Context, symbols []string) ([]provider.Quote, error) {
+	ids := map[string]bool{}
+	currencies := map[string]bool{}
+	for _, symbol := range symbols {
+		id, currency := p.coin(symbol)
+		ids[id] = true
+		currencies[currency] = true
+	}
+
+	var header http.Header
+	var key string
+	if p.Key != nil {
+		key = p.Key()
+	}
+	if key = provider.Token(ctx, key); key != "" {
+		header = http.Header{"X-Cg-Demo-Api-Key": []string{key}}
+	}
+	if err := p.Limiter.Allow(); err != nil {
+		return nil, err
+	}
+
+	// Maps coin ids to currencies to values (e.g. "usd", "usd_market_cap").
+	var resp map[string]map[string]float64
+	u := fmt.Sprintf(priceURL, url.QueryEscape(join(ids)), url.QueryEscape(join(currencies)))
+	if err := provider.GetJSON(ctx, u, header, &resp); err != nil {
+		return nil, err
+	}
+
+	var ret []provider.Quote
+	for _, symbol := range symbols {
+		id, currency := p.coin(symbol)
+		values, ok := resp[id]
+		if !ok || values[currency] == 0 {
+			continue
+		}
+		q := provider.Quote{
+			Symbol:    symbol,
+			Price:     values[currency],
+			Currency:  strings.ToUpper(currency),
+			Volume:    values[currency+"_24h_vol"],
+			MarketCap: values[currency+"_market_cap"],
+		}
+		if t := values["last_updated_at"]; t != 0 {
+			q.Time = time.Unix(int64(t), 0)
+		}
+		ret = append(ret, q)
+	}
+	return ret, nil
+}
+
+// join returns the keys of a set, comma separated.
+func join(set map[string]bool) string {
+	var ret []string
+	for k := range set {
+		ret = append(ret, k)
+	}
+	return strings.Join(ret, ",")
+}
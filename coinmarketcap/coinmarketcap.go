@@ -0,0 +1,150 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package coinmarketcap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/marcopaganini/quotes-exporter/provider"
+)
+
+const quotesURL = "https://pro-api.coinmarketcap.com/v1/cryptocurrency/quotes/latest?symbol=%s&convert=%s"
+
+// quotesResponse is the response of the latest quotes API.
+type quotesResponse struct {
+	Status struct {
+		ErrorCode    int    `json:"error_code"`
+		ErrorMessage string `json:"error_message"`
+	} `json:"status"`
+	Data map[string]struct {
+		Symbol string `json:"symbol"`
+		Quote  map[string]struct {
+			Price            float64   `json:"price"`
+			Volume24h        float64   `json:"volume_24h"`
+			PercentChange24h float64   `json:"percent_change_24h"`
+			MarketCap        float64   `json:"market_cap"`
+			LastUpdated      time.Time `json:"last_updated"`
+		} `json:"quote"`
+	} `json:"data"`
+}
+
+// Provider is the CoinMarketCap quote provider. Symbols are tickers (BTC),
+// optionally followed by the currency to quote them in (BTC-EUR).
+type Provider struct {
+	// Key returns the API key.
+	Key func() string
+	// Currency is the currency to quote coins in when the symbol has none.
+	Currency string
+	// Limiter limits the requests sent upstream.
+	Limiter *provider.Limiter
+}
+
+// coin returns the ticker and the currency of a symbol.
+func (p *Provider) coin(symbol string) (string, string) {
+	currency := p.Currency
+	if currency == "" {
+		currency = "USD"
+	}
+	if i := strings.LastIndex(symbol, "-"); i > 0 {
+		symbol, currency = symbol[:i], symbol[i+1:]
+	}
+	return strings.ToUpper(symbol), strings.ToUpper(currency)
+}
+
+// Quote returns the quotes of the given symbols, with one request per
+// currency.
+func (p *Provider) Quote(ctx context.Context, symbols []string) ([]provider.Quote, error) {
+	var key string
+	if p.Key != nil {
+		key = p.Key()
+	}
+	key = provider.Token(ctx, key)
+	if key == "" {
+		return nil, errors.New("missing CoinMarketCap API key")
+	}
+	header := http.Header{"X-CMC_PRO_API_KEY": []string{key}}
+
+	// Maps currencies to tickers to the requested symbols.
+	byCurrency := map[string]map[string][]string{}
+	for _, symbol := range symbols {
+		ticker, currency := p.coin(symbol)
+		if byCurrency[currency] == nil {
+			byCurrency[currency] = map[string][]string{}
+		}
+		byCurrency[currency][ticker] = append(byCurrency[currency][ticker], symbol)
+	}
+
+	var (
+		ret      []provider.Quote
+		firstErr error
+	)
+	for currency, tickers := range byCurrency {
+		quotes, err := p.fetch(ctx, currency, tickers, header)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		ret = append(ret, quotes...)
+	}
+	if len(ret) == 0 && firstErr != nil {
+		return nil, firstErr
+	}
+	return ret, nil
+}
+
+// fetch returns the quotes of tickers in a currency.
+func (p *Provider) fetch(ctx context.Context, currency string, tickers map[string][]string, header http.Header) ([]provider.Quote, error) {
+	if err := p.Limiter.Allow(); err != nil {
+		return nil, err
+	}
+
+	var list []string
+	for t := range tickers {
+		list = append(list, t)
+	}
+	var resp quotesResponse
+	u := fmt.Sprintf(quotesURL, url.QueryEscape(strings.Join(list, ",")), url.QueryEscape(currency))
+	if err := provider.GetJSON(ctx, u, header, &resp); err != nil {
+		return nil, err
+	}
+	if resp.Status.ErrorCode != 0 {
+		return nil, fmt.Errorf("upstream error %d: %s", resp.Status.ErrorCode, resp.Status.ErrorMessage)
+	}
+
+	var ret []provider.Quote
+	for ticker, d := range resp.Data {
+		cq, ok := d.Quote[currency]
+		if !ok || cq.Price == 0 {
+			continue
+		}
+		for _, symbol := range tickers[strings.ToUpper(ticker)] {
+			ret = append(ret, provider.Quote{
+				Symbol:        symbol,
+				Price:         cq.Price,
+				Currency:      currency,
+				Time:          cq.LastUpdated,
+				Volume:        cq.Volume24h,
+				MarketCap:     cq.MarketCap,
+				ChangePercent: cq.PercentChange24h,
+			})
+		}
+	}
+	return ret, nil
+}
@@ -12,26 +12,31 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
-	"log"
+	"io"
 	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/kofalt/go-memoize"
 	"github.com/prometheus/client_golang/prometheus"
 
-	"github.com/marcopaganini/quotes-exporter/stonks"
+	"github.com/marcopaganini/quotes-exporter/openfigi"
+	"github.com/marcopaganini/quotes-exporter/provider"
 )
 
 var (
-	// These are metrics for the collector itself
-	queryDuration = prometheus.NewSummary(
-		prometheus.SummaryOpts{
-			Name: "quotes_exporter_query_duration_seconds",
-			Help: "Duration of queries to the upstream API",
-		},
-	)
+	// These are metrics for the collector itself. Histograms are replaced
+	// by setupHistograms once the configuration is loaded.
+	queryDuration    = prometheus.NewHistogram(queryDurationOpts)
+	providerDuration = prometheus.NewHistogramVec(providerDurationOpts, []string{"provider"})
+
 	queryCount = prometheus.NewCounter(
 		prometheus.CounterOpts{
 			Name: "quotes_exporter_queries_total",
@@ -45,16 +50,283 @@ var (
 		},
 	)
 
-	// Cache external API consuming calls for 10 minutes.
+	// Cache external API consuming calls for cache.ttl. Replaced by
+	// setupCache once the configuration is loaded.
 	cache *memoize.Memoizer = memoize.NewMemoizer(10*time.Minute, 20*time.Minute)
+)
 
-	// flags
-	flagPort int
+var (
+	queryDurationOpts = prometheus.HistogramOpts{
+		Name: "quotes_exporter_query_duration_seconds",
+		Help: "Duration of queries to the upstream API",
+	}
+	providerDurationOpts = prometheus.HistogramOpts{
+		Name: "quotes_exporter_provider_request_duration_seconds",
+		Help: "Duration of requests to each upstream provider",
+	}
 )
 
+// setupHistograms creates the latency histograms, as native histograms as
+// well if enabled. Must be called before the histograms are used.
+func setupHistograms() {
+	native := func(opts prometheus.HistogramOpts) prometheus.HistogramOpts {
+		if cfg.Metrics.NativeHistograms {
+			// Buckets at most 10% wide, resetting if they grow too many.
+			opts.NativeHistogramBucketFactor = 1.1
+			opts.NativeHistogramMaxBucketNumber = 160
+			opts.NativeHistogramMinResetDuration = time.Hour
+		}
+		return opts
+	}
+	queryDuration = prometheus.NewHistogram(native(queryDurationOpts))
+	providerDuration = prometheus.NewHistogramVec(native(providerDurationOpts), []string{"provider"})
+}
+
 // collector holds data for a prometheus collector.
 type collector struct {
 	symbols []string
+	// lists holds the watchlists requested, if any.
+	lists []string
+	// providers maps symbols to the provider requested for them, if any.
+	providers map[string]string
+	// labels maps symbols to extra labels for their price. labelNames holds
+	// the sorted union of all extra label names.
+	labels     map[string]map[string]string
+	labelNames []string
+	// traceID is the trace ID of the request, attached as an exemplar to
+	// the query duration and error metrics.
+	traceID string
+	// token is the provider API token passed in the request, if any, for
+	// tokenProvider only.
+	token         string
+	tokenProvider string
+}
+
+// symbolRequest holds one symbol in a JSON request body. Either a plain
+// string ("AMD") or an object ({"symbol": "AMD", "labels": {...}}) is
+// accepted.
+type symbolRequest struct {
+	Symbol   string            `json:"symbol"`
+	Provider string            `json:"provider"`
+	Labels   map[string]string `json:"labels"`
+}
+
+// UnmarshalJSON allows symbols to be given as plain strings.
+func (s *symbolRequest) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &s.Symbol); err == nil {
+		return nil
+	}
+	type plain symbolRequest
+	return json.Unmarshal(data, (*plain)(s))
+}
+
+// labelNameRE matches valid Prometheus label names.
+var labelNameRE = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// reservedLabels holds the label names Collect may add to prices, whatever
+// the configuration, which requests can't use for their own labels.
+var reservedLabels = []string{"symbol", "name", "currency", "provider", "stale"}
+
+// newCollectorFromJSON returns a new collector object with the symbols in a
+// JSON array read from r.
+func newCollectorFromJSON(r io.Reader) (collector, error) {
+	var reqs []symbolRequest
+	if err := json.NewDecoder(r).Decode(&reqs); err != nil {
+		return collector{}, fmt.Errorf("invalid JSON request: %v", err)
+	}
+	if len(reqs) == 0 {
+		return collector{}, fmt.Errorf("missing symbols in request")
+	}
+
+	c := collector{
+		providers: map[string]string{},
+		labels:    map[string]map[string]string{},
+	}
+	names := map[string]bool{}
+	for _, req := range reqs {
+		if req.Symbol == "" {
+			return collector{}, fmt.Errorf("empty symbol in request")
+		}
+		if req.Provider != "" {
+			if !containsFold(providerNames(), req.Provider) {
+				return collector{}, fmt.Errorf("unknown provider %q (valid: %s)", req.Provider, strings.Join(providerNames(), ","))
+			}
+			c.providers[req.Symbol] = strings.ToLower(req.Provider)
+		}
+		for k := range req.Labels {
+			if !labelNameRE.MatchString(k) || strings.HasPrefix(k, "__") {
+				return collector{}, fmt.Errorf("invalid label name %q", k)
+			}
+			for _, r := range reservedLabels {
+				if k == r {
+					return collector{}, fmt.Errorf("label name %q is reserved", k)
+				}
+			}
+			names[k] = true
+		}
+		if len(req.Labels) > 0 {
+			c.labels[req.Symbol] = req.Labels
+		}
+		c.symbols = append(c.symbols, req.Symbol)
+	}
+	for k := range names {
+		c.labelNames = append(c.labelNames, k)
+	}
+	sort.Strings(c.labelNames)
+	return c, nil
+}
+
+// priceLabels returns the label names and values for the price of a symbol,
+// including any extra labels requested for it.
+func (c collector) priceLabels(symbol string) ([]string, []string) {
+	ls := []string{"symbol", "name"}
+	lvs := []string{symbol, displayName(symbol)}
+	for _, k := range c.labelNames {
+		ls = append(ls, k)
+		lvs = append(lvs, c.labels[symbol][k])
+	}
+	return ls, lvs
+}
+
+// withProviderLabels returns a copy of the collector adding the labels of
+// their providers (e.g. currency_pair="EUR/USD") to the price of symbols.
+func (c collector) withProviderLabels() collector {
+	labels := map[string]map[string]string{}
+	names := map[string]bool{}
+	for _, symbol := range c.symbols {
+		p, _ := findProvider(providerFor(symbol, c.providers[symbol]))
+		if p.labels == nil {
+			continue
+		}
+		extra := p.labels(upstreamSymbol(symbol))
+		if len(extra) == 0 {
+			continue
+		}
+		ls := map[string]string{}
+		for k, v := range c.labels[symbol] {
+			ls[k] = v
+		}
+		for k, v := range extra {
+			ls[k] = v
+			names[k] = true
+		}
+		labels[symbol] = ls
+	}
+	if len(names) == 0 {
+		return c
+	}
+
+	for symbol, ls := range c.labels {
+		if _, ok := labels[symbol]; !ok {
+			labels[symbol] = ls
+		}
+	}
+	c.labels = labels
+	c.labelNames = append([]string{}, c.labelNames...)
+	for k := range names {
+		if !containsFold(c.labelNames, k) {
+			c.labelNames = append(c.labelNames, k)
+		}
+	}
+	sort.Strings(c.labelNames)
+	return c
+}
+
+// quote holds a provider quote and when it was fetched.
+type quote struct {
+	provider.Quote
+	// fetched is when the quote was fetched from upstream.
+	fetched time.Time
+	// provider is the name of the provider that served the quote.
+	provider string
+}
+
+// fetchQuote returns the current price of a symbol from a provider, trying
+// the fallback providers in order if it fails. An empty provider selects the
+// best provider for the symbol. A request token in ctx replaces the configured
+// credentials of the provider it is meant for.
+func fetchQuote(ctx context.Context, symbol, pname string) (quote, error) {
+	if b, ok := basket(symbol); ok {
+		// Basket components are cached and shared, so they're always
+		// fetched with the configured credentials.
+		q, err := basketQuote(withProviderToken(ctx, "", ""), b)
+		q.provider = "basket"
+		return q, err
+	}
+	if _, ok := volatilityIndex(upstreamSymbol(symbol)); ok && pname == "" {
+		ctx, cancel := upstreamContext(ctx)
+		defer cancel()
+		q, err := indexQuote(ctx, symbol)
+		q.provider = "yahoo"
+		return q, err
+	}
+
+	var firstErr error
+	for _, name := range healthyChain(providerChain(symbol, pname)) {
+		q, err := fetchProviderQuote(ctx, symbol, name)
+		if err == nil {
+			return q, nil
+		}
+		logger("collector").Warn("Provider failed", "provider", name, "symbol", symbol, "error", err)
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	return quote{}, firstErr
+}
+
+// fetchProviderQuote returns the current price of a symbol from the named
+// provider.
+func fetchProviderQuote(ctx context.Context, symbol, pname string) (quote, error) {
+	p, ok := findProvider(pname)
+	if !ok {
+		return quote{}, fmt.Errorf("unknown provider %q", pname)
+	}
+	psym := providerSymbol(pname, symbol)
+	// Quotes of requests with their own token are kept to the request.
+	shared := tokenFor(ctx, pname) == ""
+	// Serve the last quote, if any, instead of calling a provider with an
+	// open circuit.
+	if err := allowCircuit(pname); err != nil {
+		if q, ok := lastQuote(pname, psym); ok && shared {
+			return q, nil
+		}
+		return quote{}, err
+	}
+	// Rate limit tokens are only taken for calls actually made.
+	if err := allowProvider(pname); err != nil {
+		// Let another call check the circuit, if this was the trial call.
+		recordProviderResult(pname, err)
+		return quote{}, err
+	}
+	ctx, cancel := upstreamContext(providerContext(ctx, pname))
+	defer cancel()
+	start := time.Now()
+	quotes, err := p.provider.Quote(ctx, []string{psym})
+	providerDuration.WithLabelValues(pname).Observe(time.Since(start).Seconds())
+	recordProviderResult(pname, err)
+	recordThrottle(pname, err)
+	if err != nil {
+		return quote{}, err
+	}
+	pq, ok := provider.Find(quotes, psym)
+	if !ok {
+		return quote{}, fmt.Errorf("%w: %s", provider.ErrNotFound, psym)
+	}
+	q := quote{Quote: pq, fetched: time.Now(), provider: pname}
+	if shared {
+		saveLastQuote(pname, psym, q)
+	}
+	return q, nil
+}
+
+// upstreamContext returns a context for upstream requests, canceled after
+// the upstream timeout, if any.
+func upstreamContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if cfg.Upstream.Timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, cfg.Upstream.Timeout)
 }
 
 // newCollector returns a new collector object with parsed data from the URL object.
@@ -62,15 +334,43 @@ func newCollector(myurl *url.URL) (collector, error) {
 	var symbols []string
 
 	// Typical query is formatted as: ?symbols=AAA,BBB...&symbols=CCC,DDD...
-	// We fetch all symbols into a single slice.
-	qvalues, ok := myurl.Query()["symbols"]
-	if !ok {
+	// We fetch all symbols into a single slice. Watchlists can be requested
+	// with ?list=name.
+	query := myurl.Query()
+	for _, qvalue := range query["symbols"] {
+		symbols = append(symbols, strings.Split(qvalue, ",")...)
+	}
+	// ISINs and CUSIPs can also be given with ?isin= and ?cusip=, checking
+	// they are valid.
+	for _, id := range []struct{ param, idType string }{{"isin", openfigi.ISIN}, {"cusip", openfigi.CUSIP}} {
+		ids, err := identifierSymbols(id.idType, query[id.param])
+		if err != nil {
+			return collector{}, fmt.Errorf("%s: %v", id.param, err)
+		}
+		symbols = append(symbols, ids...)
+	}
+	for _, list := range query["list"] {
+		lsymbols, ok := watchlists.get(list)
+		if !ok {
+			return collector{}, fmt.Errorf("unknown watchlist %q", list)
+		}
+		symbols = append(symbols, lsymbols...)
+	}
+	if len(symbols) == 0 {
 		return collector{}, fmt.Errorf("missing symbols in query")
 	}
-	for _, qvalue := range qvalues {
-		symbols = append(symbols, strings.Split(qvalue, ",")...)
+
+	// ?provider=name selects the provider of all symbols in the query.
+	providers := map[string]string{}
+	if pname := query.Get("provider"); pname != "" {
+		if !containsFold(providerNames(), pname) {
+			return collector{}, fmt.Errorf("unknown provider %q (valid: %s)", pname, strings.Join(providerNames(), ","))
+		}
+		for _, symbol := range symbols {
+			providers[symbol] = strings.ToLower(pname)
+		}
 	}
-	return collector{symbols}, nil
+	return collector{symbols: symbols, lists: query["list"], providers: providers}, nil
 }
 
 // Describe outputs description for prometheus timeseries.
@@ -79,49 +379,242 @@ func (c collector) Describe(ch chan<- *prometheus.Desc) {
 	ch <- prometheus.NewDesc("dummy", "dummy", nil, nil)
 }
 
+// quoteFetcher returns a function fetching the quote of a symbol from a
+// provider (empty for the best provider), to be memoized under its quote key.
+func quoteFetcher(ctx context.Context, symbol, pname string) func() (interface{}, error) {
+	key := quoteKey(symbol, pname)
+	// Try not to hit the end point too hard.
+	return func() (interface{}, error) {
+		// Symbols unknown upstream are not looked up again until their
+		// negative cache entry expires.
+		if nerr, ok := cache.Storage.Get(notFoundKey(key)); ok {
+			negativeCacheHits.Inc()
+			return nil, nerr.(error)
+		}
+		atomic.AddInt64(&quoteFetches, 1)
+		q, err := fetchQuote(ctx, symbol, pname)
+		if notFound(err) && cfg.Cache.NegativeTTL > 0 {
+			cache.Storage.Set(notFoundKey(key), err, cfg.Cache.NegativeTTL)
+		}
+		// Quotes served while a circuit is open keep their fetch time.
+		if q.fetched.IsZero() {
+			q.fetched = time.Now()
+		}
+		return q, err
+	}
+}
+
+// uncachedQuote fetches the quote of a symbol from a provider, bypassing
+// the cache.
+func uncachedQuote(ctx context.Context, symbol, pname string) (interface{}, error) {
+	atomic.AddInt64(&quoteFetches, 1)
+	q, err := fetchQuote(ctx, symbol, pname)
+	if q.fetched.IsZero() {
+		q.fetched = time.Now()
+	}
+	return q, err
+}
+
 // Collect retrieves quote data and ouputs prometheus compatible timeseries on
 // the output channel.
 func (c collector) Collect(ch chan<- prometheus.Metric) {
 	queryCount.Inc()
+	c = c.withProviderLabels()
+
+	now := time.Now()
+	if cfg.Metrics.Snapshot {
+		var done func()
+		ch, done = snapshot(ch, now)
+		defer done()
+	}
+
+	if len(cfg.Metrics.Sentiment) > 0 {
+		collectSentiment(ch)
+	}
+
+	if cfg.Metrics.Aggregates {
+		for _, list := range c.lists {
+			collectAggregates(ch, list)
+		}
+	}
 
+	var symbols []string
 	for _, symbol := range c.symbols {
-		// Try not to hit the end point too hard.
-		cachedFetcher := func() (interface{}, error) {
-			return stonks.Quote(symbol)
+		if !symbolLimit.allow(symbol, cfg.Limits.MaxSymbols, cfg.Limits.SymbolWindow) {
+			symbolsDropped.Inc()
+			logger("collector").Warn("Symbol limit reached, dropping symbol", "symbol", symbol, "limit", cfg.Limits.MaxSymbols)
+			continue
 		}
+		symbols = append(symbols, symbol)
+	}
+
+	// Quotes fetched with the request's own provider token are neither
+	// cached nor served from the cache, so they're never shared with other
+	// users and the token is always checked.
+	ctx := withProviderToken(context.Background(), c.tokenProvider, c.token)
+	if c.token == "" {
+		c.prefetch(ctx, symbols)
+	}
 
-		start := time.Now()
-		qret, err, cached := cache.Memoize(symbol, cachedFetcher)
-		queryDuration.Observe(float64(time.Since(start).Seconds()))
+	for _, symbol := range symbols {
+		pname := c.providers[symbol]
+		key := quoteKey(symbol, pname)
+		log := logger("collector").With("provider", providerFor(symbol, pname), "symbol", symbol)
+
+		cachedFetcher := quoteFetcher(ctx, symbol, pname)
+
+		// Expired quotes are served as they are while being refreshed in
+		// the background, so scrapes don't wait on upstream every TTL.
+		var (
+			qret         interface{}
+			err          error
+			cached       bool
+			revalidating bool
+		)
+		if c.token != "" {
+			start := time.Now()
+			qret, err = uncachedQuote(ctx, symbol, pname)
+			observe(queryDuration, float64(time.Since(start).Seconds()), c.traceID)
+		} else if sq, ok := staleQuote(key, symbol); ok {
+			staleServed.Inc()
+			revalidate(key, symbol, cachedFetcher)
+			qret, cached, revalidating = sq, true, true
+		} else {
+			start := time.Now()
+			qret, err, cached = memoizeQuote(key, symbol, cachedFetcher)
+			observe(queryDuration, float64(time.Since(start).Seconds()), c.traceID)
+		}
 
 		if err != nil {
-			errorCount.Inc()
-			log.Printf("Error looking up %s: %v\n", symbol, err)
+			inc(errorCount, c.traceID)
+			log.Error("Error looking up quote", "error", err)
 			return
 		}
 		// Convert to native type as Memoize returns an interface.
-		price, ok := qret.(float64)
+		q, ok := qret.(quote)
 		if !ok {
-			errorCount.Inc()
-			log.Printf("Invalid quote data for %s: %v\n", symbol, qret)
+			inc(errorCount, c.traceID)
+			log.Error("Invalid quote data", "data", qret)
 			return
 		}
+		if !revalidating && c.token == "" {
+			saveServedQuote(key, q)
+		}
 
 		// ls contains the list of labels and lvs the corresponding values.
-		ls := []string{"symbol", "name"}
-		lvs := []string{symbol, symbol}
+		ls, lvs := c.priceLabels(symbol)
+		// Configured names take precedence over names from the provider.
+		if q.Name != "" && lvs[1] == symbol {
+			lvs[1] = q.Name
+		}
+		// With fallbacks, the provider serving a quote may vary.
+		if len(cfg.ProviderFallback) > 0 {
+			ls = append(ls, "provider")
+			lvs = append(lvs, q.provider)
+		}
 
-		c := ""
-		if cached {
-			c = " (cached)"
+		if cfg.Quote.MaxAge > 0 {
+			// Quotes being revalidated are reported as stale, but not
+			// dropped.
+			stale := q.stale(cfg.Quote.MaxAge)
+			switch cfg.Quote.StaleAction {
+			case staleDrop:
+				if stale {
+					log.Warn("Dropping stale quote", "market_time", q.Time)
+					continue
+				}
+			case staleLabel:
+				ls = append(ls, "stale")
+				lvs = append(lvs, strconv.FormatBool(stale || revalidating))
+			case staleGauge:
+				success := 1.0
+				if stale || revalidating {
+					success = 0
+				}
+				ch <- prometheus.MustNewConstMetric(quoteSuccessDesc, prometheus.GaugeValue, success, symbol)
+			}
 		}
-		log.Printf("Retrieved %s%s, price: %f\n", symbol, c, price)
 
-		ch <- prometheus.MustNewConstMetric(
-			prometheus.NewDesc("quotes_exporter_price", "Asset Price.", ls, nil),
-			prometheus.GaugeValue,
-			price,
-			lvs...,
-		)
+		price := roundPrice(symbol, q.Price)
+		log.Info("Retrieved quote", "price", price, "currency", q.Currency, "cached", cached, "stale", revalidating)
+
+		if cfg.Metrics.Snapshot {
+			ch <- prometheus.MustNewConstMetric(quoteAgeDesc, prometheus.GaugeValue, now.Sub(q.fetched).Seconds(), symbol)
+		}
+
+		// Volatility indices carry no volume, so flag them as indices to
+		// allow dashboards to tell them apart from tradeable assets.
+		if _, ok := volatilityIndex(upstreamSymbol(symbol)); ok {
+			ch <- prometheus.MustNewConstMetric(
+				prometheus.NewDesc("quotes_exporter_asset_info", "Asset information.", []string{"symbol", "name", "asset_type"}, nil),
+				prometheus.GaugeValue,
+				1,
+				symbol, displayName(symbol), assetTypeIndex,
+			)
+		}
+
+		if len(cfg.Metrics.Currencies) > 0 {
+			collectCurrencies(ch, symbol, q, ls, lvs)
+		} else {
+			// The currency label is empty (and thus absent) when the
+			// provider doesn't report the currency.
+			ch <- prometheus.MustNewConstMetric(
+				prometheus.NewDesc("quotes_exporter_price", "Asset Price.", append(ls, "currency"), nil),
+				prometheus.GaugeValue,
+				price,
+				append(lvs, q.Currency)...,
+			)
+		}
+		collectDetails(ch, symbol, q, ls, lvs)
+
+		// Baskets are synthetic, so only their level and change are known.
+		if b, ok := basket(symbol); ok {
+			collectBasketChange(ch, symbol, b, q.Price)
+			continue
+		}
+
+		if historyEnabled() {
+			closes, err := history(symbol)
+			if err != nil {
+				inc(errorCount, c.traceID)
+				log.Error("Error fetching history", "history_provider", "yahoo", "error", err)
+				continue
+			}
+			if cfg.History.Closes > 0 {
+				collectCloses(ch, symbol, closes)
+			}
+			if cfg.History.Returns {
+				collectReturns(ch, symbol, price, closes)
+			}
+			if cfg.History.Volatility {
+				collectVolatility(ch, symbol, closes)
+			}
+			if len(cfg.History.SMA) > 0 || len(cfg.History.EMA) > 0 {
+				collectAverages(ch, symbol, closes)
+			}
+			if cfg.History.ZScore > 0 {
+				collectZScore(ch, symbol, price, closes)
+			}
+		}
+
+		if cfg.Metrics.Events {
+			collectEvents(ch, symbol)
+		}
+
+		if cfg.Metrics.Holdings {
+			collectHoldings(ch, symbol)
+		}
+
+		if cfg.Metrics.Staking {
+			collectStaking(ch, symbol)
+		}
+
+		if len(cfg.Metrics.Exchanges) > 0 {
+			collectSpread(ch, symbol)
+		}
+
+		if cfg.Earnings.Interval > 0 {
+			collectEarnings(ch, symbol)
+		}
 	}
 }
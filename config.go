@@ -0,0 +1,703 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/marcopaganini/quotes-exporter/exchange"
+	"github.com/marcopaganini/quotes-exporter/htmlpage"
+	"github.com/marcopaganini/quotes-exporter/jsonapi"
+	"github.com/marcopaganini/quotes-exporter/nasdaqdatalink"
+	"github.com/marcopaganini/quotes-exporter/sentiment"
+)
+
+const (
+	// Prefix for environment variables overriding settings. The setting
+	// "web.port" is read from QUOTES_EXPORTER_WEB_PORT, for example.
+	envPrefix = "QUOTES_EXPORTER_"
+
+	// Flag holding the name of the configuration file.
+	configFileFlag = "config.file"
+)
+
+// config holds the exporter configuration. Scalar settings are bound to
+// command-line flags and can also be set from the environment and the
+// configuration file. Precedence is flags > environment > file > defaults.
+type config struct {
+	// File is the configuration file, if any.
+	File string
+	// Provider is the default quote provider.
+	Provider string
+	// ProviderFallback lists the providers to try, in order, when a
+	// provider fails.
+	ProviderFallback stringList
+	// KeyRotation is how providers with several API keys rotate them.
+	KeyRotation string
+
+	Health struct {
+		UnhealthyAfter int
+		RetryInterval  time.Duration
+	}
+	Breaker struct {
+		Failures int
+		Cooldown time.Duration
+	}
+	Cache struct {
+		TTL                  time.Duration
+		CleanupInterval      time.Duration
+		File                 string
+		SaveInterval         time.Duration
+		NegativeTTL          time.Duration
+		StaleWhileRevalidate time.Duration
+		WarmUp               bool
+		RefreshAhead         time.Duration
+		ClosedTTL            time.Duration
+	}
+	Upstream struct {
+		Timeout            time.Duration
+		UserAgent          string
+		CAFile             string
+		TLSMinVersion      string
+		InsecureSkipVerify bool
+	}
+	Proxy struct {
+		URL string
+	}
+
+	Web struct {
+		Port            int
+		AdminToken      string
+		AdminAddress    string
+		StateFile       string
+		ExternalAddress string
+	}
+	Log struct {
+		Level  string
+		Format string
+	}
+	History struct {
+		Closes     int
+		Returns    bool
+		Volatility bool
+		SMA        intList
+		EMA        intList
+		ZScore     int
+		Dir        string
+	}
+	Secrets struct {
+		RefreshInterval time.Duration
+	}
+	OpenFIGI struct {
+		APIKey string
+	}
+	Earnings struct {
+		Interval time.Duration
+	}
+	AlphaVantage struct {
+		Token             string
+		RequestsPerMinute float64
+	}
+	Finnhub struct {
+		Token             string
+		RequestsPerMinute float64
+	}
+	Polygon struct {
+		Token             string
+		RequestsPerMinute float64
+	}
+	Tiingo struct {
+		Token             string
+		RequestsPerMinute float64
+	}
+	TwelveData struct {
+		Token             string
+		RequestsPerMinute float64
+	}
+	Marketstack struct {
+		AccessKey         string
+		Intraday          bool
+		RequestsPerMinute float64
+	}
+	EODHD struct {
+		Token             string
+		Exchange          string
+		RequestsPerMinute float64
+	}
+	FMP struct {
+		Token             string
+		RequestsPerMinute float64
+	}
+	CoinGecko struct {
+		Token             string
+		VsCurrency        string
+		RequestsPerMinute float64
+	}
+	CoinMarketCap struct {
+		Token             string
+		Convert           string
+		RequestsPerMinute float64
+	}
+	OpenExchangeRates struct {
+		AppID             string
+		Base              string
+		RequestsPerMinute float64
+	}
+	Alpaca struct {
+		KeyID             string
+		SecretKey         string
+		Feed              string
+		RequestsPerMinute float64
+	}
+	Tradier struct {
+		Token             string
+		Sandbox           bool
+		RequestsPerMinute float64
+	}
+	NasdaqDataLink struct {
+		Token             string
+		RequestsPerMinute float64
+	}
+	FRED struct {
+		Token             string
+		RequestsPerMinute float64
+	}
+	Morningstar struct {
+		RequestsPerMinute float64
+	}
+	FTFunds struct {
+		RequestsPerMinute float64
+	}
+	Brapi struct {
+		Token             string
+		RequestsPerMinute float64
+	}
+	NSE struct {
+		RequestsPerMinute float64
+	}
+	TSP struct {
+		RequestsPerMinute float64
+	}
+	Metals struct {
+		Token             string
+		Currency          string
+		RequestsPerMinute float64
+	}
+	Investing struct {
+		RequestsPerMinute float64
+	}
+	Chainlink struct {
+		RPCURL            string
+		RequestsPerMinute float64
+	}
+	Pyth struct {
+		Endpoint          string
+		RequestsPerMinute float64
+	}
+	JSONAPI struct {
+		RequestsPerMinute float64
+	}
+	HTML struct {
+		RequestsPerMinute float64
+	}
+	LocalFile struct {
+		Path            string
+		RefreshInterval time.Duration
+	}
+	Mock struct {
+		RandomWalk bool
+		Volatility float64
+	}
+	StockData struct {
+		Token             string
+		RequestsPerMinute float64
+	}
+	Quote struct {
+		MaxAge      time.Duration
+		StaleAction string
+	}
+	Config struct {
+		WatchInterval time.Duration
+	}
+	Limits struct {
+		MaxSymbols   int
+		SymbolWindow time.Duration
+	}
+	Metrics struct {
+		Currencies       stringList
+		Events           bool
+		Holdings         bool
+		Staking          bool
+		Sentiment        stringList
+		Snapshot         bool
+		Precision        int
+		Aggregates       bool
+		Exchanges        stringList
+		NativeHistograms bool
+	}
+
+	// Structured settings. These can only be set in the configuration file.
+	fileConfig
+}
+
+// fileConfig holds the settings that can only be set in the configuration
+// file, usually because they don't fit in a command-line flag.
+type fileConfig struct {
+	// Watchlists maps list names to symbols.
+	Watchlists map[string][]string `json:"watchlists"`
+	// Aliases maps user chosen symbols to upstream symbols and names.
+	Aliases map[string]aliasConfig `json:"aliases"`
+	// Baskets defines custom baskets, exported as synthetic symbols.
+	Baskets map[string]basketConfig `json:"baskets"`
+	// PrecisionByType maps asset types (e.g. CRYPTOCURRENCY) to the number
+	// of decimals to round their prices to.
+	PrecisionByType map[string]int `json:"precision_by_type"`
+	// CacheTTLByType maps asset types (e.g. MUTUALFUND) to how long to
+	// cache their quotes, as durations (e.g. "6h").
+	CacheTTLByType map[string]string `json:"cache_ttl_by_type"`
+	// cacheTTLs holds the parsed CacheTTLByType, keyed by upper case type.
+	cacheTTLs map[string]time.Duration
+	// MarketHours maps Yahoo symbol suffixes (e.g. ".L", or "" for US
+	// listings) to the trading hours of their exchanges, replacing the
+	// defaults.
+	MarketHours map[string]marketHours `json:"market_hours"`
+	// marketHours holds the default and configured trading hours.
+	marketHours map[string]marketHours
+	// Datasets maps symbols to Nasdaq Data Link datasets and columns.
+	Datasets map[string]nasdaqdatalink.Dataset `json:"nasdaq_datasets"`
+	// ChainlinkFeeds maps pairs to Chainlink feed proxy addresses.
+	ChainlinkFeeds map[string]string `json:"chainlink_feeds"`
+	// JSONAPIConfig describes the API used by the jsonapi provider.
+	JSONAPIConfig jsonapi.Config `json:"json_api"`
+	// APIKeys maps providers to several API keys (or secret references),
+	// used in turn instead of their token flags.
+	APIKeys map[string][]string `json:"api_keys"`
+	// RateLimits sets the rate limits of providers, overriding their
+	// requests-per-minute flags.
+	RateLimits map[string]rateLimitConfig `json:"rate_limits"`
+	// Routes select the provider of symbols matching patterns, in order.
+	Routes []routeConfig `json:"provider_routes"`
+	// HTMLPages maps symbols to the web pages scraped by the html provider.
+	HTMLPages map[string]htmlpage.Page `json:"html_pages"`
+	// UserAgents lists user agents used in turn by upstream requests.
+	UserAgents []string `json:"user_agents"`
+	// UpstreamHeaders holds headers added to all upstream requests.
+	UpstreamHeaders map[string]string `json:"upstream_headers"`
+	// Tokens restricts access to /price to the given bearer tokens.
+	Tokens []tenantConfig `json:"tokens"`
+}
+
+// cfg holds the running configuration.
+var cfg config
+
+// newFlagSet returns a flag set for a command with all settings bound to the
+// fields in c.
+func newFlagSet(name string, c *config) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+
+	fs.StringVar(&c.File, configFileFlag, "", "Configuration file (JSON).")
+
+	fs.StringVar(&c.Provider, "provider", "stonks", "Default quote provider ("+strings.Join(providerNames(), ",")+").")
+	fs.Var(&c.ProviderFallback, "provider.fallback", "Comma separated list of providers to try, in order, when a provider fails (e.g. yahoo,stonks).")
+	fs.StringVar(&c.KeyRotation, "provider.key-rotation", rotateRoundRobin, "How providers with several API keys use them ("+strings.Join(keyRotations, ",")+").")
+	fs.IntVar(&c.Health.UnhealthyAfter, "provider.unhealthy-after", 5, "Consecutive failures after which a provider is skipped in favor of fallback providers (0 = never).")
+	fs.DurationVar(&c.Health.RetryInterval, "provider.health-retry-interval", time.Minute, "How long to skip an unhealthy provider before trying it again.")
+	fs.IntVar(&c.Breaker.Failures, "provider.breaker-failures", 10, "Consecutive failures after which calls to a provider are suspended (0 = never).")
+	fs.DurationVar(&c.Breaker.Cooldown, "provider.breaker-cooldown", 30*time.Second, "How long to suspend calls to a failing provider before a trial call.")
+	fs.DurationVar(&c.Cache.TTL, "cache.ttl", 10*time.Minute, "How long to cache upstream results.")
+	fs.DurationVar(&c.Cache.CleanupInterval, "cache.cleanup-interval", 20*time.Minute, "How often to remove expired entries from the cache.")
+	fs.DurationVar(&c.Cache.NegativeTTL, "cache.negative-ttl", 5*time.Minute, "How long to cache symbols unknown upstream (0 = don't cache).")
+	fs.DurationVar(&c.Cache.StaleWhileRevalidate, "cache.stale-while-revalidate", 0, "How long past expiration to serve cached quotes while refreshing them in the background (0 = disabled).")
+	fs.DurationVar(&c.Cache.ClosedTTL, "cache.closed-ttl", 0, "How long to cache quotes while their market is closed, up to when it opens (0 = use cache.ttl).")
+	fs.DurationVar(&c.Cache.RefreshAhead, "cache.refresh-ahead", 0, "Refresh scraped quotes in the background up to this long before they expire (0 = disabled).")
+	fs.BoolVar(&c.Cache.WarmUp, "cache.warm-up", true, "Fetch the quotes of watchlist symbols on startup.")
+	fs.StringVar(&c.Cache.File, "cache.file", "", "File to persist cached quotes across restarts (empty = don't persist).")
+	fs.DurationVar(&c.Cache.SaveInterval, "cache.save-interval", time.Minute, "How often to save cached quotes to cache.file (0 = only on exit).")
+	fs.DurationVar(&c.Upstream.Timeout, "upstream.timeout", 10*time.Second, "Timeout of each request to upstream providers (0 = no timeout).")
+	fs.StringVar(&c.Upstream.UserAgent, "upstream.user-agent", "", "User-Agent of requests to upstream providers (empty = provider defaults).")
+	fs.StringVar(&c.Upstream.CAFile, "upstream.ca-file", "", "PEM bundle of extra CAs trusted for upstream HTTPS requests.")
+	fs.StringVar(&c.Upstream.TLSMinVersion, "upstream.tls-min-version", "", "Minimum TLS version of upstream requests (1.0, 1.1, 1.2 or 1.3; empty = Go default).")
+	fs.BoolVar(&c.Upstream.InsecureSkipVerify, "upstream.insecure-skip-verify", false, "Don't verify upstream TLS certificates (debugging only).")
+	fs.StringVar(&c.Proxy.URL, "proxy.url", "", "Proxy URL for upstream requests (e.g. http://proxy:3128; empty = use HTTP_PROXY and HTTPS_PROXY).")
+
+	fs.IntVar(&c.Web.Port, "web.port", 9340, "Port to listen for HTTP requests.")
+	fs.IntVar(&c.Web.Port, "port", 9340, "Deprecated: use -web.port.")
+	secretVar(fs, &c.Web.AdminToken, "web.admin-token", "Bearer token (or file:, exec: or env: secret) for the admin API (empty = admin API disabled).")
+	fs.StringVar(&c.Web.AdminAddress, "web.admin-address", "", "Address (host:port) to serve /metrics, /healthz and the admin API on (empty = serve them on -web.port).")
+	fs.StringVar(&c.Web.ExternalAddress, "web.external-address", "", "Address (host:port) Prometheus uses to reach the exporter, for /sd (default: the request Host).")
+	fs.StringVar(&c.Web.StateFile, "web.state-file", "", "File to persist runtime watchlist changes (empty = don't persist).")
+
+	fs.StringVar(&c.Log.Level, "log.level", "info", "Log level (debug, info, warn, error).")
+	fs.StringVar(&c.Log.Format, "log.format", "text", "Log format (text, json).")
+
+	fs.IntVar(&c.History.Closes, "history.closes", 0, "Export the last N daily closes (0 = disabled).")
+	fs.BoolVar(&c.History.Returns, "history.returns", false, "Export year-to-date and trailing 1-year returns.")
+	fs.BoolVar(&c.History.Volatility, "history.volatility", false, "Export the trailing 30-day annualized volatility.")
+	fs.Var(&c.History.SMA, "history.sma", "Comma separated list of simple moving average windows, in days (e.g. 20,50,200).")
+	fs.Var(&c.History.EMA, "history.ema", "Comma separated list of exponential moving average windows, in days (e.g. 12,26).")
+	fs.IntVar(&c.History.ZScore, "history.zscore", 0, "Export the move from the last close as a z-score of the daily returns over N trading days (0 = disabled).")
+	fs.StringVar(&c.History.Dir, "history.dir", "", "Directory of the local history store (empty = disabled).")
+
+	fs.DurationVar(&c.Secrets.RefreshInterval, "secrets.refresh-interval", time.Minute, "How often to re-read file: and exec: secrets (e.g. API keys).")
+
+	secretVar(fs, &c.OpenFIGI.APIKey, "openfigi.api-key", "OpenFIGI API key (or file:, exec: or env: secret) to resolve ISINs and CUSIPs (optional, raises rate limits).")
+
+	secretVar(fs, &c.AlphaVantage.Token, "alphavantage.token", "Alpha Vantage API key (or file:, exec: or env: secret).")
+	fs.Float64Var(&c.AlphaVantage.RequestsPerMinute, "alphavantage.requests-per-minute", 5, "Maximum Alpha Vantage requests per minute (0 = unlimited).")
+	secretVar(fs, &c.Finnhub.Token, "finnhub.token", "Finnhub API key (or file:, exec: or env: secret).")
+	fs.Float64Var(&c.Finnhub.RequestsPerMinute, "finnhub.requests-per-minute", 60, "Maximum Finnhub requests per minute (0 = unlimited).")
+	secretVar(fs, &c.Polygon.Token, "polygon.token", "Polygon.io API key (or file:, exec: or env: secret).")
+	fs.Float64Var(&c.Polygon.RequestsPerMinute, "polygon.requests-per-minute", 5, "Maximum Polygon.io requests per minute (0 = unlimited). Raise it to match paid plans.")
+	secretVar(fs, &c.Tiingo.Token, "tiingo.token", "Tiingo API key (or file:, exec: or env: secret).")
+	fs.Float64Var(&c.Tiingo.RequestsPerMinute, "tiingo.requests-per-minute", 0, "Maximum Tiingo requests per minute (0 = unlimited).")
+	secretVar(fs, &c.TwelveData.Token, "twelvedata.token", "Twelve Data API key (or file:, exec: or env: secret).")
+	fs.Float64Var(&c.TwelveData.RequestsPerMinute, "twelvedata.requests-per-minute", 8, "Maximum Twelve Data requests per minute (0 = unlimited).")
+	secretVar(fs, &c.Marketstack.AccessKey, "marketstack.access-key", "Marketstack API access key (or file:, exec: or env: secret).")
+	fs.BoolVar(&c.Marketstack.Intraday, "marketstack.intraday", false, "Use Marketstack intraday prices (paid plans) instead of end-of-day prices.")
+	fs.Float64Var(&c.Marketstack.RequestsPerMinute, "marketstack.requests-per-minute", 0, "Maximum Marketstack requests per minute (0 = unlimited).")
+	secretVar(fs, &c.EODHD.Token, "eodhd.token", "EOD Historical Data API token (or file:, exec: or env: secret).")
+	fs.StringVar(&c.EODHD.Exchange, "eodhd.exchange", "US", "EOD Historical Data exchange code for symbols without one (e.g. US, LSE, XETRA).")
+	fs.Float64Var(&c.EODHD.RequestsPerMinute, "eodhd.requests-per-minute", 0, "Maximum EOD Historical Data requests per minute (0 = unlimited).")
+	secretVar(fs, &c.FMP.Token, "fmp.token", "Financial Modeling Prep API key (or file:, exec: or env: secret).")
+	fs.Float64Var(&c.FMP.RequestsPerMinute, "fmp.requests-per-minute", 0, "Maximum Financial Modeling Prep requests per minute (0 = unlimited).")
+	secretVar(fs, &c.CoinGecko.Token, "coingecko.token", "CoinGecko demo API key (or file:, exec: or env: secret; optional).")
+	fs.StringVar(&c.CoinGecko.VsCurrency, "coingecko.vs-currency", "usd", "Currency to quote CoinGecko coins in, for symbols without one (e.g. BTC-EUR).")
+	fs.Float64Var(&c.CoinGecko.RequestsPerMinute, "coingecko.requests-per-minute", 30, "Maximum CoinGecko requests per minute (0 = unlimited).")
+	secretVar(fs, &c.CoinMarketCap.Token, "coinmarketcap.token", "CoinMarketCap API key (or file:, exec: or env: secret).")
+	fs.StringVar(&c.CoinMarketCap.Convert, "coinmarketcap.convert", "USD", "Currency to quote CoinMarketCap coins in, for symbols without one (e.g. BTC-EUR).")
+	fs.Float64Var(&c.CoinMarketCap.RequestsPerMinute, "coinmarketcap.requests-per-minute", 30, "Maximum CoinMarketCap requests per minute (0 = unlimited).")
+	secretVar(fs, &c.OpenExchangeRates.AppID, "openexchangerates.app-id", "Open Exchange Rates app ID (or file:, exec: or env: secret).")
+	fs.StringVar(&c.OpenExchangeRates.Base, "openexchangerates.base", "USD", "Open Exchange Rates base currency (free plans only support USD).")
+	fs.Float64Var(&c.OpenExchangeRates.RequestsPerMinute, "openexchangerates.requests-per-minute", 0, "Maximum Open Exchange Rates requests per minute (0 = unlimited).")
+	secretVar(fs, &c.Alpaca.KeyID, "alpaca.key-id", "Alpaca API key ID (or file:, exec: or env: secret).")
+	secretVar(fs, &c.Alpaca.SecretKey, "alpaca.secret-key", "Alpaca API secret key (or file:, exec: or env: secret).")
+	fs.StringVar(&c.Alpaca.Feed, "alpaca.feed", "iex", "Alpaca data feed (iex, or sip with a paid subscription).")
+	fs.Float64Var(&c.Alpaca.RequestsPerMinute, "alpaca.requests-per-minute", 200, "Maximum Alpaca requests per minute (0 = unlimited).")
+	secretVar(fs, &c.Tradier.Token, "tradier.token", "Tradier access token (or file:, exec: or env: secret).")
+	fs.BoolVar(&c.Tradier.Sandbox, "tradier.sandbox", false, "Use the Tradier sandbox API (delayed data) instead of the production API.")
+	fs.Float64Var(&c.Tradier.RequestsPerMinute, "tradier.requests-per-minute", 120, "Maximum Tradier requests per minute (0 = unlimited).")
+	secretVar(fs, &c.NasdaqDataLink.Token, "nasdaqdatalink.token", "Nasdaq Data Link API key (or file:, exec: or env: secret).")
+	fs.Float64Var(&c.NasdaqDataLink.RequestsPerMinute, "nasdaqdatalink.requests-per-minute", 0, "Maximum Nasdaq Data Link requests per minute (0 = unlimited).")
+	secretVar(fs, &c.FRED.Token, "fred.api-key", "FRED API key (or file:, exec: or env: secret).")
+	fs.Float64Var(&c.FRED.RequestsPerMinute, "fred.requests-per-minute", 120, "Maximum FRED requests per minute (0 = unlimited).")
+	fs.Float64Var(&c.Morningstar.RequestsPerMinute, "morningstar.requests-per-minute", 30, "Maximum Morningstar requests per minute (0 = unlimited).")
+	fs.Float64Var(&c.FTFunds.RequestsPerMinute, "ftfunds.requests-per-minute", 30, "Maximum FT funds requests per minute (0 = unlimited).")
+	secretVar(fs, &c.Brapi.Token, "brapi.token", "brapi API token (or file:, exec: or env: secret).")
+	fs.Float64Var(&c.Brapi.RequestsPerMinute, "brapi.requests-per-minute", 60, "Maximum brapi requests per minute (0 = unlimited).")
+	fs.Float64Var(&c.NSE.RequestsPerMinute, "nse.requests-per-minute", 30, "Maximum NSE India requests per minute (0 = unlimited).")
+	fs.Float64Var(&c.TSP.RequestsPerMinute, "tsp.requests-per-minute", 10, "Maximum tsp.gov requests per minute (0 = unlimited).")
+	secretVar(fs, &c.Metals.Token, "metals.token", "Metals-API access key (or file:, exec: or env: secret).")
+	fs.StringVar(&c.Metals.Currency, "metals.currency", "USD", "Currency of precious metal prices, for symbols without one.")
+	fs.Float64Var(&c.Metals.RequestsPerMinute, "metals.requests-per-minute", 10, "Maximum Metals-API requests per minute (0 = unlimited).")
+	fs.Float64Var(&c.Investing.RequestsPerMinute, "investing.requests-per-minute", 10, "Maximum investing.com requests per minute (0 = unlimited).")
+	secretVar(fs, &c.Chainlink.RPCURL, "chainlink.rpc-url", "Ethereum JSON-RPC endpoint URL for Chainlink feeds (or file:, exec: or env: secret).")
+	fs.Float64Var(&c.Chainlink.RequestsPerMinute, "chainlink.requests-per-minute", 120, "Maximum Chainlink JSON-RPC calls per minute (0 = unlimited).")
+	fs.StringVar(&c.Pyth.Endpoint, "pyth.endpoint", "https://hermes.pyth.network", "Pyth Hermes API endpoint URL.")
+	fs.Float64Var(&c.Pyth.RequestsPerMinute, "pyth.requests-per-minute", 60, "Maximum Pyth Hermes requests per minute (0 = unlimited).")
+	fs.Float64Var(&c.JSONAPI.RequestsPerMinute, "jsonapi.requests-per-minute", 60, "Maximum JSON API provider requests per minute (0 = unlimited).")
+	fs.Float64Var(&c.HTML.RequestsPerMinute, "html.requests-per-minute", 30, "Maximum web page scraping requests per minute (0 = unlimited).")
+	fs.StringVar(&c.LocalFile.Path, "localfile.path", "", "CSV or JSON file with prices for the localfile provider.")
+	fs.DurationVar(&c.LocalFile.RefreshInterval, "localfile.refresh-interval", time.Minute, "How often to read the localfile prices file again.")
+	fs.BoolVar(&c.Mock.RandomWalk, "mock.random-walk", false, "Make mock provider prices move on each request.")
+	fs.Float64Var(&c.Mock.Volatility, "mock.volatility", 0.01, "Standard deviation of each mock price move, as a fraction of the price.")
+	secretVar(fs, &c.StockData.Token, "stockdata.token", "StockData.org API token (or file:, exec: or env: secret).")
+	fs.Float64Var(&c.StockData.RequestsPerMinute, "stockdata.requests-per-minute", 10, "Maximum StockData.org requests per minute (0 = unlimited).")
+
+	fs.DurationVar(&c.Earnings.Interval, "earnings.interval", 0, "How often to fetch the next earnings dates of all watchlist symbols (0 = disabled).")
+
+	fs.DurationVar(&c.Quote.MaxAge, "quote.max-age", 0, "Maximum age of the last trade of a quote before it is considered stale (0 = disabled).")
+	fs.StringVar(&c.Quote.StaleAction, "quote.stale-action", staleLabel, "What to do with stale quotes ("+strings.Join(staleActions, ",")+").")
+
+	fs.DurationVar(&c.Config.WatchInterval, "config.watch-interval", 0, "How often to check the configuration file for changes and reload it (0 = only reload on SIGHUP).")
+
+	fs.IntVar(&c.Limits.MaxSymbols, "limits.max-symbols", 0, "Maximum number of distinct symbols exported within -limits.symbol-window (0 = unlimited).")
+	fs.DurationVar(&c.Limits.SymbolWindow, "limits.symbol-window", time.Hour, "Time window for -limits.max-symbols.")
+
+	fs.Var(&c.Metrics.Currencies, "metrics.currencies", "Comma separated list of currencies to export prices in (e.g. native,USD,EUR).")
+	fs.BoolVar(&c.Metrics.Events, "metrics.events", false, "Export split and dividend event metrics.")
+	fs.BoolVar(&c.Metrics.Holdings, "metrics.etf-holdings", false, "Export the weights of the top holdings of ETFs and mutual funds.")
+	fs.BoolVar(&c.Metrics.Staking, "metrics.crypto-staking", false, "Export staking APY for supported proof-of-stake assets.")
+	fs.Var(&c.Metrics.Sentiment, "metrics.sentiment", "Comma separated list of sentiment indices to export ("+strings.Join(sentiment.Names(), ",")+").")
+	fs.BoolVar(&c.Metrics.Aggregates, "metrics.watchlist-aggregates", false, "Export daily change and market cap aggregates for watchlists requested with ?list=.")
+	fs.Var(&c.Metrics.Exchanges, "metrics.crypto-exchanges", "Comma separated list of exchanges to compare crypto pair prices on ("+strings.Join(exchange.Names(), ",")+").")
+	fs.IntVar(&c.Metrics.Precision, "metrics.precision", -1, "Round prices to N decimals (-1 = no rounding).")
+	fs.BoolVar(&c.Metrics.NativeHistograms, "metrics.native-histograms", false, "Also export the latency histograms on /metrics as native histograms (Prometheus 2.40 or newer).")
+	fs.BoolVar(&c.Metrics.Snapshot, "metrics.snapshot", false, "Stamp all series in a scrape with the collection time and export the age of each quote.")
+
+	return fs
+}
+
+// envName returns the environment variable name for a setting.
+func envName(setting string) string {
+	r := strings.NewReplacer(".", "_", "-", "_")
+	return envPrefix + strings.ToUpper(r.Replace(setting))
+}
+
+// parseConfig parses the command-line arguments and loads the configuration
+// file and environment into the settings bound to fs, honoring precedence.
+func parseConfig(fs *flag.FlagSet, c *config, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	// Save the flags set in the command-line, to re-apply them last.
+	explicit := map[string]string{}
+	fs.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = f.Value.String()
+	})
+
+	filename := fs.Lookup(configFileFlag).Value.String()
+	if v, ok := os.LookupEnv(envName(configFileFlag)); ok && explicit[configFileFlag] == "" {
+		filename = v
+	}
+	c.File = filename
+	if filename != "" {
+		if err := loadConfigFile(fs, c, filename); err != nil {
+			return fmt.Errorf("%s: %v", filename, err)
+		}
+	}
+
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		v, ok := os.LookupEnv(envName(f.Name))
+		if !ok || err != nil || f.Name == configFileFlag {
+			return
+		}
+		if e := fs.Set(f.Name, v); e != nil {
+			err = fmt.Errorf("environment variable %s: %v", envName(f.Name), e)
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	for name, v := range explicit {
+		if err := fs.Set(name, v); err != nil {
+			return err
+		}
+	}
+	return c.validate()
+}
+
+// loadConfigFile reads a JSON configuration file. Objects in the file map to
+// the dotted setting names, so {"web": {"port": 80}} sets "web.port".
+func loadConfigFile(fs *flag.FlagSet, c *config, filename string) error {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(data, &c.fileConfig); err != nil {
+		return err
+	}
+
+	var tree map[string]interface{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&tree); err != nil {
+		return err
+	}
+
+	// Remove structured settings, handled above.
+	for _, k := range fileConfigKeys() {
+		delete(tree, k)
+	}
+
+	settings := map[string]string{}
+	if err := flatten("", tree, settings); err != nil {
+		return err
+	}
+	for name, v := range settings {
+		if name == configFileFlag || fs.Lookup(name) == nil {
+			return fmt.Errorf("unknown setting %q", name)
+		}
+		if err := fs.Set(name, v); err != nil {
+			return fmt.Errorf("setting %q: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// fileConfigKeys returns the JSON names of the structured settings.
+func fileConfigKeys() []string {
+	data, _ := json.Marshal(fileConfig{})
+	var m map[string]interface{}
+	json.Unmarshal(data, &m)
+
+	var ret []string
+	for k := range m {
+		ret = append(ret, k)
+	}
+	return ret
+}
+
+// flatten converts a tree of JSON objects into dotted setting names. Lists
+// of scalars are joined by commas.
+func flatten(prefix string, v interface{}, out map[string]string) error {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, val := range t {
+			name := k
+			if prefix != "" {
+				name = prefix + "." + k
+			}
+			if err := flatten(name, val, out); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		var s []string
+		for _, e := range t {
+			switch e.(type) {
+			case map[string]interface{}, []interface{}:
+				return fmt.Errorf("setting %q: lists must contain only scalars", prefix)
+			}
+			s = append(s, fmt.Sprint(e))
+		}
+		out[prefix] = strings.Join(s, ",")
+	case nil:
+	default:
+		out[prefix] = fmt.Sprint(t)
+	}
+	return nil
+}
+
+// validate checks the configuration for inconsistencies.
+func (c *config) validate() error {
+	for _, name := range c.Metrics.Sentiment {
+		if !sentiment.Valid(name) {
+			return fmt.Errorf("unknown sentiment index %q (valid: %s)", name, strings.Join(sentiment.Names(), ","))
+		}
+	}
+	for name, symbols := range c.Watchlists {
+		if len(symbols) == 0 {
+			return fmt.Errorf("watchlist %q is empty", name)
+		}
+	}
+	for i, name := range c.Metrics.Exchanges {
+		if !exchange.Valid(name) {
+			return fmt.Errorf("unknown exchange %q (valid: %s)", name, strings.Join(exchange.Names(), ","))
+		}
+		c.Metrics.Exchanges[i] = strings.ToLower(name)
+	}
+	c.Provider = strings.ToLower(c.Provider)
+	if !containsFold(providerNames(), c.Provider) {
+		return fmt.Errorf("unknown provider %q (valid: %s)", c.Provider, strings.Join(providerNames(), ","))
+	}
+	if c.Cache.TTL <= 0 {
+		return fmt.Errorf("cache TTL must be positive")
+	}
+	if c.Cache.CleanupInterval <= 0 {
+		return fmt.Errorf("cache cleanup interval must be positive")
+	}
+	if c.Cache.RefreshAhead < 0 || (c.Cache.RefreshAhead > 0 && c.Cache.RefreshAhead >= c.Cache.TTL) {
+		return fmt.Errorf("cache refresh ahead must be less than the cache TTL")
+	}
+	c.cacheTTLs = map[string]time.Duration{}
+	for t, v := range c.CacheTTLByType {
+		ttl, err := time.ParseDuration(v)
+		if err != nil || ttl <= 0 {
+			return fmt.Errorf("invalid cache TTL %q for asset type %q", v, t)
+		}
+		c.cacheTTLs[strings.ToUpper(t)] = ttl
+	}
+	var err error
+	if c.marketHours, err = parseMarketHours(c.MarketHours); err != nil {
+		return err
+	}
+	c.KeyRotation = strings.ToLower(c.KeyRotation)
+	if !containsFold(keyRotations, c.KeyRotation) {
+		return fmt.Errorf("unknown key rotation %q (valid: %s)", c.KeyRotation, strings.Join(keyRotations, ","))
+	}
+	for name, rl := range c.RateLimits {
+		if _, ok := findProvider(name); !ok {
+			return fmt.Errorf("unknown provider %q in rate_limits (valid: %s)", name, strings.Join(providerNames(), ","))
+		}
+		if rl.RequestsPerMinute < 0 || rl.Burst < 0 {
+			return fmt.Errorf("negative rate limit for provider %q", name)
+		}
+	}
+	if _, ok := tlsVersions[c.Upstream.TLSMinVersion]; !ok && c.Upstream.TLSMinVersion != "" {
+		return fmt.Errorf("unknown TLS version %q (valid: 1.0,1.1,1.2,1.3)", c.Upstream.TLSMinVersion)
+	}
+	// Proxy URLs may hold credentials, so errors don't show them.
+	if c.Proxy.URL != "" {
+		if u, err := url.Parse(c.Proxy.URL); err != nil || u.Host == "" {
+			return fmt.Errorf("invalid proxy URL (want scheme://[user:password@]host:port)")
+		}
+	}
+	for i, r := range c.Routes {
+		re, err := regexp.Compile(r.Pattern)
+		if err != nil {
+			return fmt.Errorf("invalid pattern of provider route %d: %v", i+1, err)
+		}
+		if !containsFold(providerNames(), r.Provider) {
+			return fmt.Errorf("unknown provider %q in route %q (valid: %s)", r.Provider, r.Pattern, strings.Join(providerNames(), ","))
+		}
+		c.Routes[i].re = re
+		c.Routes[i].Provider = strings.ToLower(r.Provider)
+	}
+	for i, name := range c.ProviderFallback {
+		if !containsFold(providerNames(), name) {
+			return fmt.Errorf("unknown fallback provider %q (valid: %s)", name, strings.Join(providerNames(), ","))
+		}
+		c.ProviderFallback[i] = strings.ToLower(name)
+	}
+	c.Quote.StaleAction = strings.ToLower(c.Quote.StaleAction)
+	if !containsFold(staleActions, c.Quote.StaleAction) {
+		return fmt.Errorf("unknown stale action %q (valid: %s)", c.Quote.StaleAction, strings.Join(staleActions, ","))
+	}
+	for name, ds := range c.Datasets {
+		if ds.Code == "" {
+			return fmt.Errorf("dataset %q has no code", name)
+		}
+	}
+	if c.JSONAPIConfig.URL != "" || c.JSONAPIConfig.Price != "" {
+		if err := c.JSONAPIConfig.Validate(); err != nil {
+			return fmt.Errorf("json_api: %v", err)
+		}
+	}
+	for symbol, page := range c.HTMLPages {
+		if err := page.Validate(); err != nil {
+			return fmt.Errorf("html_pages %q: %v", symbol, err)
+		}
+	}
+	for pair, address := range c.ChainlinkFeeds {
+		if !strings.HasPrefix(address, "0x") || len(address) != 42 {
+			return fmt.Errorf("invalid address %q of Chainlink feed %q", address, pair)
+		}
+	}
+	for name, b := range c.Baskets {
+		if len(b.Symbols) == 0 {
+			return fmt.Errorf("basket %q is empty", name)
+		}
+		for symbol := range b.Symbols {
+			if _, ok := c.Baskets[symbol]; ok {
+				return fmt.Errorf("basket %q contains basket %q", name, symbol)
+			}
+		}
+	}
+	for name, a := range c.Aliases {
+		if a.Symbol == "" && a.Name == "" {
+			return fmt.Errorf("alias %q needs a symbol or a name", name)
+		}
+	}
+	return validateTenants(c.Tokens)
+}
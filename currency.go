@@ -0,0 +1,148 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/marcopaganini/quotes-exporter/yahoo"
+)
+
+// nativeCurrency is the special currency name representing the currency the
+// symbol is quoted in.
+const nativeCurrency = "native"
+
+// minorUnits maps currencies quoted in minor units (e.g. pence) to their major
+// currency and the divisor to convert between them.
+var minorUnits = map[string]struct {
+	currency string
+	divisor  float64
+}{
+	"GBp": {"GBP", 100},
+	"GBX": {"GBP", 100},
+	"ZAc": {"ZAR", 100},
+	"ILA": {"ILS", 100},
+}
+
+// symbolMeta returns descriptive information about a symbol. Results are
+// cached just like quotes.
+func symbolMeta(symbol string) (yahoo.Meta, error) {
+	fetcher := func() (interface{}, error) {
+		ctx, cancel := upstreamContext(context.Background())
+		defer cancel()
+		return yahoo.Quote(ctx, yahooSymbol(symbol))
+	}
+
+	mret, err, _ := memoized("meta:"+symbol, fetcher)
+	if err != nil {
+		return yahoo.Meta{}, err
+	}
+	meta, ok := mret.(yahoo.Meta)
+	if !ok {
+		return yahoo.Meta{}, fmt.Errorf("invalid metadata for %s: %v", symbol, mret)
+	}
+	return meta, nil
+}
+
+// symbolCurrency returns the currency a symbol is quoted in.
+func symbolCurrency(symbol string) (string, error) {
+	meta, err := symbolMeta(symbol)
+	if err != nil {
+		return "", err
+	}
+	if meta.Currency == "" {
+		return "", fmt.Errorf("unknown currency for %s", symbol)
+	}
+	return meta.Currency, nil
+}
+
+// exchangeRate returns the rate to convert an amount in currency "from" into
+// currency "to". Currencies quoted in minor units are handled transparently.
+func exchangeRate(from, to string) (float64, error) {
+	rate := 1.0
+	if mu, ok := minorUnits[from]; ok {
+		from = mu.currency
+		rate /= mu.divisor
+	}
+	if mu, ok := minorUnits[to]; ok {
+		to = mu.currency
+		rate *= mu.divisor
+	}
+	from = strings.ToUpper(from)
+	to = strings.ToUpper(to)
+	if from == to {
+		return rate, nil
+	}
+
+	pair := from + to + "=X"
+	fetcher := func() (interface{}, error) {
+		ctx, cancel := upstreamContext(context.Background())
+		defer cancel()
+		meta, err := yahoo.Quote(ctx, pair)
+		if err != nil {
+			return nil, err
+		}
+		if meta.RegularMarketPrice == 0 {
+			return nil, fmt.Errorf("exchange rate for %s is zero", pair)
+		}
+		return meta.RegularMarketPrice, nil
+	}
+
+	fret, err, _ := memoized("fx:"+pair, fetcher)
+	if err != nil {
+		return 0, err
+	}
+	fx, ok := fret.(float64)
+	if !ok {
+		return 0, fmt.Errorf("invalid exchange rate data for %s: %v", pair, fret)
+	}
+	return rate * fx, nil
+}
+
+// collectCurrencies emits the price of a symbol once for each of the
+// configured currencies, labeled by currency. The price labels and values
+// are given in pls and plvs.
+func collectCurrencies(ch chan<- prometheus.Metric, symbol string, q quote, pls, plvs []string) {
+	price, native := q.Price, q.Currency
+	if native == "" {
+		var err error
+		if native, err = symbolCurrency(symbol); err != nil {
+			errorCount.Inc()
+			logger("currency").Error("Error looking up currency", "provider", "yahoo", "symbol", symbol, "error", err)
+			return
+		}
+	}
+
+	ls := append(append([]string(nil), pls...), "currency")
+	desc := prometheus.NewDesc("quotes_exporter_price", "Asset Price.", ls, nil)
+	lvs := func(currency string) []string {
+		return append(append([]string(nil), plvs...), currency)
+	}
+
+	for _, currency := range cfg.Metrics.Currencies {
+		if currency == nativeCurrency {
+			ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, roundPrice(symbol, price), lvs(nativeCurrency)...)
+			continue
+		}
+		rate, err := exchangeRate(native, currency)
+		if err != nil {
+			errorCount.Inc()
+			logger("currency").Error("Error converting price", "provider", "yahoo", "symbol", symbol, "from", native, "to", currency, "error", err)
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, roundPrice(symbol, price*rate), lvs(currency)...)
+	}
+}
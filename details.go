@@ -0,0 +1,85 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// collectDetails emits the optional figures of a quote (daily range,
+// previous close, volume, etc), when the provider reports them.
+func collectDetails(ch chan<- prometheus.Metric, symbol string, q quote, ls, lvs []string) {
+	var ts float64
+	if !q.Time.IsZero() {
+		ts = float64(q.Time.Unix())
+	}
+	metrics := []struct {
+		name  string
+		help  string
+		value float64
+		// price is true for prices, which are rounded.
+		price bool
+	}{
+		{"quotes_exporter_day_open", "Opening price of the current trading day.", q.Open, true},
+		{"quotes_exporter_day_high", "Highest price of the current trading day.", q.High, true},
+		{"quotes_exporter_day_low", "Lowest price of the current trading day.", q.Low, true},
+		{"quotes_exporter_previous_close", "Closing price of the previous trading day.", q.PreviousClose, true},
+		{"quotes_exporter_volume", "Volume traded in the current trading day.", q.Volume, false},
+		{"quotes_exporter_market_cap", "Market capitalization.", q.MarketCap, false},
+		{"quotes_exporter_pe_ratio", "Price to earnings ratio.", q.PE, false},
+		{"quotes_exporter_price_confidence", "Half width of the confidence interval of the price published by the provider.", q.Confidence, true},
+		{"quotes_exporter_price_timestamp_seconds", "Time of the last trade (or the NAV date of funds), in seconds since the epoch.", ts, false},
+	}
+	for _, m := range metrics {
+		if m.value == 0 {
+			continue
+		}
+		v := m.value
+		if m.price {
+			v = roundPrice(symbol, v)
+		}
+		ch <- prometheus.MustNewConstMetric(
+			prometheus.NewDesc(m.name, m.help, ls, nil),
+			prometheus.GaugeValue,
+			v,
+			lvs...,
+		)
+	}
+	collectChange(ch, symbol, q, ls, lvs)
+}
+
+// collectChange emits the change of a quote from the previous close, computed
+// from the previous close if the provider doesn't report the change. Unlike
+// other figures, a zero change is exported when the previous close is known.
+func collectChange(ch chan<- prometheus.Metric, symbol string, q quote, ls, lvs []string) {
+	pct := q.ChangePercent
+	if q.PreviousClose != 0 {
+		change := q.Price - q.PreviousClose
+		if pct == 0 {
+			pct = change / q.PreviousClose * 100
+		}
+		ch <- prometheus.MustNewConstMetric(
+			prometheus.NewDesc("quotes_exporter_change", "Change from the previous close.", ls, nil),
+			prometheus.GaugeValue,
+			roundPrice(symbol, change),
+			lvs...,
+		)
+	} else if pct == 0 {
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(
+		prometheus.NewDesc("quotes_exporter_change_percent", "Change from the previous close (last 24 hours for crypto), in percent.", ls, nil),
+		prometheus.GaugeValue,
+		pct,
+		lvs...,
+	)
+}
@@ -0,0 +1,131 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// dividendPayment is a dividend in the /dividends response.
+type dividendPayment struct {
+	Date      string  `json:"date"`
+	Timestamp int64   `json:"timestamp"`
+	Amount    float64 `json:"amount"`
+}
+
+// parseRange parses a time range in the format used by Yahoo (e.g. 5d, 3mo,
+// 1y, ytd or max) and returns its start, relative to now.
+func parseRange(s string, now time.Time) (time.Time, error) {
+	switch s {
+	case "max":
+		return time.Time{}, nil
+	case "ytd":
+		return time.Date(now.Year(), 1, 1, 0, 0, 0, 0, now.Location()), nil
+	}
+	for _, u := range []struct {
+		suffix              string
+		years, months, days int
+	}{
+		{"mo", 0, 1, 0},
+		{"wk", 0, 0, 7},
+		{"d", 0, 0, 1},
+		{"y", 1, 0, 0},
+	} {
+		if !strings.HasSuffix(s, u.suffix) {
+			continue
+		}
+		n, err := strconv.Atoi(strings.TrimSuffix(s, u.suffix))
+		if err != nil || n <= 0 {
+			break
+		}
+		return now.AddDate(-n*u.years, -n*u.months, -n*u.days), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid range %q (e.g. 5d, 3mo, 1y, ytd, max)", s)
+}
+
+// dividendsHandler handles the "/dividends" endpoint. It returns the dividends
+// paid by ?symbol= within ?range= (default 5y) as JSON, oldest first.
+func dividendsHandler(w http.ResponseWriter, r *http.Request) {
+	log := logger("web").With("url", redactedURL(r), "remote", r.RemoteAddr)
+
+	symbol := r.URL.Query().Get("symbol")
+	if symbol == "" {
+		http.Error(w, "missing symbol", http.StatusBadRequest)
+		return
+	}
+	rng := r.URL.Query().Get("range")
+	if rng == "" {
+		rng = "5y"
+	}
+	since, err := parseRange(rng, time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !authorizeSymbols(w, r, []string{symbol}) {
+		return
+	}
+
+	ev, err := events(symbol)
+	if err != nil {
+		log.Error("Error fetching corporate events", "provider", "yahoo", "symbol", symbol, "error", err)
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	divs := []dividendPayment{}
+	for _, d := range ev.dividends {
+		if d.Time.Before(since) {
+			continue
+		}
+		divs = append(divs, dividendPayment{
+			Date:      d.Time.UTC().Format("2006-01-02"),
+			Timestamp: d.Time.Unix(),
+			Amount:    d.Amount,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	resp := struct {
+		Symbol    string            `json:"symbol"`
+		Range     string            `json:"range"`
+		Dividends []dividendPayment `json:"dividends"`
+	}{symbol, rng, divs}
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		log.Error("Error encoding JSON response", "error", err)
+	}
+}
+
+// collectDividendTTM emits the sum of the dividends paid by a symbol over the
+// trailing twelve months.
+func collectDividendTTM(ch chan<- prometheus.Metric, symbol string, ev corporateEvents) {
+	since := time.Now().AddDate(-1, 0, 0)
+	var sum float64
+	for _, d := range ev.dividends {
+		if d.Time.After(since) {
+			sum += d.Amount
+		}
+	}
+	ch <- prometheus.MustNewConstMetric(
+		prometheus.NewDesc("quotes_exporter_dividend_ttm_amount", "Sum of dividends paid over the trailing twelve months.", []string{"symbol", "name"}, nil),
+		prometheus.GaugeValue,
+		sum,
+		symbol, displayName(symbol),
+	)
+}
@@ -0,0 +1,109 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"context"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/marcopaganini/quotes-exporter/yahoo"
+)
+
+var (
+	earningsTimestampDesc = prometheus.NewDesc(
+		"quotes_exporter_earnings_timestamp_seconds",
+		"Date of the next earnings announcement.",
+		[]string{"symbol", "name"}, nil,
+	)
+	earningsDaysDesc = prometheus.NewDesc(
+		"quotes_exporter_earnings_days_until",
+		"Number of whole days until the next earnings announcement.",
+		[]string{"symbol", "name"}, nil,
+	)
+
+	// earningsDates holds the next earnings date of watchlist symbols, keyed
+	// by upper case symbol. It is updated by refreshEarnings.
+	earningsMu    sync.RWMutex
+	earningsDates = map[string]time.Time{}
+)
+
+// nextEarnings returns the first earnings date not in the past, if any.
+func nextEarnings(cal yahoo.Calendar, now time.Time) (time.Time, bool) {
+	// Yahoo returns a single date, or a range when the date is not confirmed.
+	for _, t := range cal.EarningsDates {
+		if !t.IsZero() && t.After(now.Add(-24*time.Hour)) {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// updateEarnings fetches the next earnings date of all watchlist symbols.
+func updateEarnings() {
+	// Resolve symbols first, to avoid holding the configuration lock while
+	// fetching.
+	cfgMu.RLock()
+	ysyms := map[string]string{}
+	for _, symbol := range watchlists.symbols() {
+		ysyms[symbol] = yahooSymbol(symbol)
+	}
+	cfgMu.RUnlock()
+
+	dates := map[string]time.Time{}
+	for symbol, ysym := range ysyms {
+		cfgMu.RLock()
+		ctx, cancel := upstreamContext(context.Background())
+		cfgMu.RUnlock()
+		cal, err := yahoo.CalendarEvents(ctx, ysym)
+		cancel()
+		if err != nil {
+			// Symbols without earnings (e.g. ETFs and crypto) fail often.
+			logger("earnings").Debug("Unable to fetch earnings date", "provider", "yahoo", "symbol", symbol, "error", err)
+			continue
+		}
+		if t, ok := nextEarnings(cal, time.Now()); ok {
+			dates[strings.ToUpper(symbol)] = t
+		}
+	}
+
+	earningsMu.Lock()
+	earningsDates = dates
+	earningsMu.Unlock()
+	logger("earnings").Info("Updated earnings dates", "symbols", len(dates))
+}
+
+// refreshEarnings updates the earnings dates now and on every interval.
+func refreshEarnings(interval time.Duration) {
+	updateEarnings()
+	for range time.Tick(interval) {
+		updateEarnings()
+	}
+}
+
+// collectEarnings emits the next earnings date of a symbol, if known.
+func collectEarnings(ch chan<- prometheus.Metric, symbol string) {
+	earningsMu.RLock()
+	t, ok := earningsDates[strings.ToUpper(symbol)]
+	earningsMu.RUnlock()
+	if !ok {
+		return
+	}
+
+	ch <- prometheus.MustNewConstMetric(earningsTimestampDesc, prometheus.GaugeValue, float64(t.Unix()), symbol, displayName(symbol))
+	days := math.Floor(time.Until(t).Hours() / 24)
+	ch <- prometheus.MustNewConstMetric(earningsDaysDesc, prometheus.GaugeValue, days, symbol, displayName(symbol))
+}
@@ -0,0 +1,196 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package eodhd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/marcopaganini/quotes-exporter/provider"
+)
+
+const (
+	realTimeURL = "https://eodhd.com/api/real-time/%s?api_token=%s&fmt=json&s=%s"
+	eodURL      = "https://eodhd.com/api/eod/%s?api_token=%s&fmt=json&order=d&from=%s"
+)
+
+// yahooExchanges maps Yahoo exchange suffixes to EODHD exchange codes, where
+// they differ.
+var yahooExchanges = map[string]string{
+	"L":  "LSE",
+	"DE": "XETRA",
+}
+
+// number is a price. The API returns "NA" for unknown values.
+type number float64
+
+func (n *number) UnmarshalJSON(data []byte) error {
+	var f float64
+	if err := json.Unmarshal(data, &f); err != nil {
+		*n = 0
+		return nil
+	}
+	*n = number(f)
+	return nil
+}
+
+// realTimeQuote is a quote returned by the real-time API.
+type realTimeQuote struct {
+	Code          string `json:"code"`
+	Timestamp     number `json:"timestamp"`
+	Open          number `json:"open"`
+	High          number `json:"high"`
+	Low           number `json:"low"`
+	Close         number `json:"close"`
+	PreviousClose number `json:"previousClose"`
+}
+
+// eodPrice is an entry in the response of the end-of-day API.
+type eodPrice struct {
+	Open  number `json:"open"`
+	High  number `json:"high"`
+	Low   number `json:"low"`
+	Close number `json:"close"`
+}
+
+// Provider is the EOD Historical Data quote provider.
+type Provider struct {
+	// Key returns the API token.
+	Key func() string
+	// Exchange is the exchange code appended to symbols without one.
+	Exchange string
+	// Limiter limits the requests sent upstream.
+	Limiter *provider.Limiter
+}
+
+// ticker returns the EODHD ticker of a symbol: symbols without an exchange
+// get the default exchange, and Yahoo suffixes are translated (VOD.L becomes
+// VOD.LSE).
+func (p *Provider) ticker(symbol string) string {
+	symbol = strings.ToUpper(symbol)
+	i := strings.LastIndex(symbol, ".")
+	if i < 0 {
+		exchange := p.Exchange
+		if exchange == "" {
+			exchange = "US"
+		}
+		return symbol + "." + strings.ToUpper(exchange)
+	}
+	if e, ok := yahooExchanges[symbol[i+1:]]; ok {
+		return symbol[:i+1] + e
+	}
+	return symbol
+}
+
+// Quote returns the quotes of the given symbols with one real-time request.
+// Symbols without a real-time price use their latest end-of-day price.
+func (p *Provider) Quote(ctx context.Context, symbols []string) ([]provider.Quote, error) {
+	var key string
+	if p.Key != nil {
+		key = p.Key()
+	}
+	key = provider.Token(ctx, key)
+	if key == "" {
+		return nil, errors.New("missing EODHD API token")
+	}
+
+	// Maps tickers to the requested symbols.
+	tickers := map[string]string{}
+	var list []string
+	for _, symbol := range symbols {
+		t := p.ticker(symbol)
+		tickers[t] = symbol
+		list = append(list, t)
+	}
+
+	if err := p.Limiter.Allow(); err != nil {
+		return nil, err
+	}
+	var raw json.RawMessage
+	u := fmt.Sprintf(realTimeURL, url.PathEscape(list[0]), url.QueryEscape(key), url.QueryEscape(strings.Join(list[1:], ",")))
+	if err := provider.GetJSON(ctx, u, nil, &raw); err != nil {
+		return nil, err
+	}
+	// One ticker returns an object, several return an array.
+	var resps []realTimeQuote
+	if len(list) == 1 {
+		var r realTimeQuote
+		if err := json.Unmarshal(raw, &r); err != nil {
+			return nil, fmt.Errorf("error decoding response: %v", err)
+		}
+		resps = append(resps, r)
+	} else if err := json.Unmarshal(raw, &resps); err != nil {
+		return nil, fmt.Errorf("error decoding response: %v", err)
+	}
+
+	var ret []provider.Quote
+	for _, r := range resps {
+		symbol, ok := tickers[strings.ToUpper(r.Code)]
+		if !ok || r.Close == 0 {
+			continue
+		}
+		delete(tickers, strings.ToUpper(r.Code))
+		q := provider.Quote{
+			Symbol:        symbol,
+			Price:         float64(r.Close),
+			Open:          float64(r.Open),
+			High:          float64(r.High),
+			Low:           float64(r.Low),
+			PreviousClose: float64(r.PreviousClose),
+		}
+		if r.Timestamp != 0 {
+			q.Time = time.Unix(int64(r.Timestamp), 0)
+		}
+		ret = append(ret, q)
+	}
+
+	for ticker, symbol := range tickers {
+		q, err := p.eod(ctx, ticker, key)
+		if err != nil {
+			continue
+		}
+		q.Symbol = symbol
+		ret = append(ret, q)
+	}
+	if len(ret) == 0 {
+		return nil, fmt.Errorf("no quotes for %s", strings.Join(list, ","))
+	}
+	return ret, nil
+}
+
+// eod returns the latest end-of-day price of a ticker.
+func (p *Provider) eod(ctx context.Context, ticker, key string) (provider.Quote, error) {
+	if err := p.Limiter.Allow(); err != nil {
+		return provider.Quote{}, err
+	}
+
+	var resp []eodPrice
+	from := time.Now().AddDate(0, 0, -10).Format("2006-01-02")
+	u := fmt.Sprintf(eodURL, url.PathEscape(ticker), url.QueryEscape(key), from)
+	if err := provider.GetJSON(ctx, u, nil, &resp); err != nil {
+		return provider.Quote{}, err
+	}
+	if len(resp) == 0 || resp[0].Close == 0 {
+		return provider.Quote{}, fmt.Errorf("%w: %s", provider.ErrNotFound, ticker)
+	}
+	return provider.Quote{
+		Price: float64(resp[0].Close),
+		Open:  float64(resp[0].Open),
+		High:  float64(resp[0].High),
+		Low:   float64(resp[0].Low),
+	}, nil
+}
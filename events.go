@@ -0,0 +1,122 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/marcopaganini/quotes-exporter/yahoo"
+)
+
+// corporateEvents holds the corporate actions of a symbol.
+type corporateEvents struct {
+	dividends []yahoo.Dividend
+	splits    []yahoo.Split
+	calendar  yahoo.Calendar
+}
+
+// events returns the past and upcoming corporate actions of a symbol. Results
+// are cached just like quotes.
+func events(symbol string) (corporateEvents, error) {
+	fetcher := func() (interface{}, error) {
+		ctx, cancel := upstreamContext(context.Background())
+		defer cancel()
+		divs, splits, err := yahoo.Events(ctx, yahooSymbol(symbol))
+		if err != nil {
+			return nil, err
+		}
+		ev := corporateEvents{dividends: divs, splits: splits}
+
+		// Not all symbols have calendar events (e.g. indices), so a failure
+		// here should not prevent exporting past events.
+		cal, err := yahoo.CalendarEvents(ctx, yahooSymbol(symbol))
+		if err != nil {
+			logger("events").Warn("Unable to fetch calendar events", "provider", "yahoo", "symbol", symbol, "error", err)
+		}
+		ev.calendar = cal
+		return ev, nil
+	}
+
+	eret, err, _ := memoized("events:"+symbol, fetcher)
+	if err != nil {
+		return corporateEvents{}, err
+	}
+	ev, ok := eret.(corporateEvents)
+	if !ok {
+		return corporateEvents{}, fmt.Errorf("invalid event data for %s: %v", symbol, eret)
+	}
+	return ev, nil
+}
+
+// collectEvents emits the most recent split and dividend of a symbol, as well
+// as the timestamps of upcoming dividend events, when known.
+func collectEvents(ch chan<- prometheus.Metric, symbol string) {
+	ev, err := events(symbol)
+	if err != nil {
+		errorCount.Inc()
+		logger("events").Error("Error fetching corporate events", "provider", "yahoo", "symbol", symbol, "error", err)
+		return
+	}
+
+	if len(ev.splits) > 0 {
+		split := ev.splits[len(ev.splits)-1]
+		ratio := split.Ratio
+		if ratio == "" {
+			ratio = strconv.FormatFloat(split.Numerator, 'f', -1, 64) + ":" + strconv.FormatFloat(split.Denominator, 'f', -1, 64)
+		}
+		ch <- prometheus.MustNewConstMetric(
+			prometheus.NewDesc("quotes_exporter_split_info", "Most recent stock split.", []string{"symbol", "name", "ratio"}, nil),
+			prometheus.GaugeValue,
+			1,
+			symbol, displayName(symbol), ratio,
+		)
+		if split.Denominator != 0 {
+			ch <- prometheus.MustNewConstMetric(
+				prometheus.NewDesc("quotes_exporter_split_ratio", "Ratio of the most recent stock split (new shares per old share).", []string{"symbol", "name"}, nil),
+				prometheus.GaugeValue,
+				split.Numerator/split.Denominator,
+				symbol, displayName(symbol),
+			)
+		}
+		ch <- prometheus.MustNewConstMetric(
+			prometheus.NewDesc("quotes_exporter_split_timestamp_seconds", "Date of the most recent stock split.", []string{"symbol", "name"}, nil),
+			prometheus.GaugeValue,
+			float64(split.Time.Unix()),
+			symbol, displayName(symbol),
+		)
+	}
+
+	ls := []string{"symbol", "name", "event"}
+	tsDesc := prometheus.NewDesc("quotes_exporter_dividend_timestamp_seconds", "Date of dividend events.", ls, nil)
+
+	if len(ev.dividends) > 0 {
+		div := ev.dividends[len(ev.dividends)-1]
+		ch <- prometheus.MustNewConstMetric(
+			prometheus.NewDesc("quotes_exporter_dividend_amount", "Amount of the most recent dividend.", []string{"symbol", "name"}, nil),
+			prometheus.GaugeValue,
+			div.Amount,
+			symbol, displayName(symbol),
+		)
+		ch <- prometheus.MustNewConstMetric(tsDesc, prometheus.GaugeValue, float64(div.Time.Unix()), symbol, displayName(symbol), "last")
+		collectDividendTTM(ch, symbol, ev)
+	}
+	if !ev.calendar.ExDividendDate.IsZero() {
+		ch <- prometheus.MustNewConstMetric(tsDesc, prometheus.GaugeValue, float64(ev.calendar.ExDividendDate.Unix()), symbol, displayName(symbol), "ex_dividend")
+	}
+	if !ev.calendar.DividendDate.IsZero() {
+		ch <- prometheus.MustNewConstMetric(tsDesc, prometheus.GaugeValue, float64(ev.calendar.DividendDate.Unix()), symbol, displayName(symbol), "payment")
+	}
+}
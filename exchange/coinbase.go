@@ -0,0 +1,86 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package exchange
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/marcopaganini/quotes-exporter/provider"
+)
+
+// coinbaseURL takes the pair and the price type (spot, buy or sell).
+const coinbaseURL = "https://api.coinbase.com/v2/prices/%s-%s/%s"
+
+// Coinbase price types.
+const (
+	CoinbaseSpot = "spot"
+	CoinbaseBuy  = "buy"
+	CoinbaseSell = "sell"
+)
+
+// coinbasePrice returns the price of a pair on Coinbase. Price is one of
+// the Coinbase price types.
+func coinbasePrice(ctx context.Context, base, quote, price string) (float64, error) {
+	var r struct {
+		Data struct {
+			Amount string `json:"amount"`
+		} `json:"data"`
+	}
+	if err := getJSON(ctx, fmt.Sprintf(coinbaseURL, base, quote, price), &r); err != nil {
+		return 0, err
+	}
+	return strconv.ParseFloat(r.Data.Amount, 64)
+}
+
+// coinbase returns the spot price of a pair on Coinbase.
+func coinbase(ctx context.Context, base, quote string) (float64, error) {
+	return coinbasePrice(ctx, base, quote, CoinbaseSpot)
+}
+
+// Coinbase is the Coinbase quote provider. Symbols are crypto-fiat pairs
+// (BTC-USD).
+type Coinbase struct {
+	// Price is the price type to fetch: spot (the default), buy or sell.
+	Price string
+}
+
+// Quote returns the quotes of the given pairs, one request per pair.
+func (c Coinbase) Quote(ctx context.Context, symbols []string) ([]provider.Quote, error) {
+	price := c.Price
+	if price == "" {
+		price = CoinbaseSpot
+	}
+	return provider.Each(ctx, symbols, func(ctx context.Context, symbol string) (provider.Quote, error) {
+		base, quote, ok := Pair(symbol)
+		if !ok {
+			return provider.Quote{}, fmt.Errorf("%s is not a crypto pair (e.g. BTC-USD)", symbol)
+		}
+		var r struct {
+			Data struct {
+				Amount   string `json:"amount"`
+				Currency string `json:"currency"`
+			} `json:"data"`
+		}
+		u := fmt.Sprintf(coinbaseURL, base, quote, price)
+		if err := provider.GetJSON(ctx, u, nil, &r); err != nil {
+			return provider.Quote{}, err
+		}
+		v := parseFloat(r.Data.Amount)
+		if v == 0 {
+			return provider.Quote{}, fmt.Errorf("no %s price for %s", price, symbol)
+		}
+		return provider.Quote{Symbol: symbol, Price: v, Currency: strings.ToUpper(r.Data.Currency)}, nil
+	})
+}
@@ -0,0 +1,105 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package exchange
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/marcopaganini/quotes-exporter/provider"
+)
+
+const bitstampURL = "https://www.bitstamp.net/api/v2/ticker/%s%s/"
+
+// exchanges maps exchange names to functions returning the last price of a
+// pair (e.g. "BTC", "USD").
+var exchanges = map[string]func(ctx context.Context, base, quote string) (float64, error){
+	"bitstamp": bitstamp,
+	"coinbase": coinbase,
+	"kraken":   kraken,
+}
+
+// Names returns the names of the supported exchanges.
+func Names() []string {
+	var names []string
+	for name := range exchanges {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Valid returns true if name is a supported exchange.
+func Valid(name string) bool {
+	_, ok := exchanges[strings.ToLower(name)]
+	return ok
+}
+
+// Pair returns the base and quote currencies of a crypto symbol ("BTC-USD"
+// -> "BTC", "USD") and whether the symbol looks like a crypto pair at all.
+func Pair(symbol string) (string, string, bool) {
+	parts := strings.Split(strings.ToUpper(symbol), "-")
+	if len(parts) != 2 || len(parts[0]) < 2 || len(parts[1]) != 3 {
+		return "", "", false
+	}
+	return parts[0], parts[1], true
+}
+
+// Price returns the last price of a pair on an exchange.
+func Price(ctx context.Context, exchange, base, quote string) (float64, error) {
+	fetch, ok := exchanges[strings.ToLower(exchange)]
+	if !ok {
+		return 0, fmt.Errorf("unknown exchange %q", exchange)
+	}
+	price, err := fetch(ctx, strings.ToUpper(base), strings.ToUpper(quote))
+	if err != nil {
+		return 0, fmt.Errorf("%s: %v", exchange, err)
+	}
+	if price == 0 {
+		return 0, fmt.Errorf("%s: price=0 for %s-%s", exchange, base, quote)
+	}
+	return price, nil
+}
+
+// bitstamp returns the last price of a pair on Bitstamp.
+func bitstamp(ctx context.Context, base, quote string) (float64, error) {
+	var r struct {
+		Last string `json:"last"`
+	}
+	if err := getJSON(ctx, fmt.Sprintf(bitstampURL, strings.ToLower(base), strings.ToLower(quote)), &r); err != nil {
+		return 0, err
+	}
+	return strconv.ParseFloat(r.Last, 64)
+}
+
+// getJSON fetches a URL and decodes the JSON response into v.
+func getJSON(ctx context.Context, u string, v interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := provider.Client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("upstream returned HTTP %d", resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
@@ -0,0 +1,170 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package exchange
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/marcopaganini/quotes-exporter/provider"
+)
+
+const krakenURL = "https://api.kraken.com/0/public/Ticker?pair=%s"
+
+// krakenAssets maps Kraken asset names to common tickers, where they differ.
+var krakenAssets = map[string]string{
+	"XBT": "BTC",
+	"XDG": "DOGE",
+}
+
+// krakenTicker is the ticker of a pair, as returned by Kraken. Numbers are
+// strings, and pairs of values hold today's and the last 24 hours' values.
+type krakenTicker struct {
+	// Last trade: price, volume.
+	C []string `json:"c"`
+	O string   `json:"o"`
+	H []string `json:"h"`
+	L []string `json:"l"`
+	V []string `json:"v"`
+}
+
+// krakenResponse is the response of the Kraken Ticker API.
+type krakenResponse struct {
+	Error []string `json:"error"`
+	// Result is keyed by Kraken's own pair name (e.g. XXBTZUSD).
+	Result map[string]krakenTicker `json:"result"`
+}
+
+// krakenName returns the Kraken name of an asset (BTC -> XBT).
+func krakenName(asset string) string {
+	for k, v := range krakenAssets {
+		if v == asset {
+			return k
+		}
+	}
+	return asset
+}
+
+// krakenPair returns the base and quote currencies of a pair, either in the
+// usual form (BTC-USD) or in Kraken's (XBTUSD, XXBTZUSD).
+func krakenPair(name string) (string, string, bool) {
+	if base, quote, ok := Pair(name); ok {
+		return base, quote, true
+	}
+	name = strings.ToUpper(name)
+	var base, quote string
+	switch {
+	// Legacy names: X prefixes crypto and Z prefixes fiat (XXBTZUSD).
+	case len(name) == 8 && strings.ContainsRune("XZ", rune(name[0])) && strings.ContainsRune("XZ", rune(name[4])):
+		base, quote = name[1:4], name[5:]
+	case len(name) >= 6:
+		base, quote = name[:len(name)-3], name[len(name)-3:]
+	default:
+		return "", "", false
+	}
+	if a, ok := krakenAssets[base]; ok {
+		base = a
+	}
+	if a, ok := krakenAssets[quote]; ok {
+		quote = a
+	}
+	return base, quote, true
+}
+
+// kraken returns the last trade price of a pair on Kraken.
+func kraken(ctx context.Context, base, quote string) (float64, error) {
+	var r krakenResponse
+	if err := getJSON(ctx, fmt.Sprintf(krakenURL, krakenName(base)+quote), &r); err != nil {
+		return 0, err
+	}
+	if len(r.Error) > 0 {
+		return 0, fmt.Errorf("upstream error: %s", strings.Join(r.Error, ", "))
+	}
+	for _, t := range r.Result {
+		if len(t.C) > 0 {
+			return strconv.ParseFloat(t.C[0], 64)
+		}
+	}
+	return 0, fmt.Errorf("missing price in response")
+}
+
+// Kraken is the Kraken quote provider. Symbols are crypto pairs, either in
+// the usual form (BTC-USD) or in Kraken's (XBTUSD, XXBTZUSD).
+type Kraken struct{}
+
+// Quote returns the quotes of the given pairs with one request.
+func (Kraken) Quote(ctx context.Context, symbols []string) ([]provider.Quote, error) {
+	// Maps pairs (BTC-USD) to the requested symbols.
+	pairs := map[string][]string{}
+	var names []string
+	for _, symbol := range symbols {
+		base, quote, ok := krakenPair(symbol)
+		if !ok {
+			continue
+		}
+		pair := base + "-" + quote
+		if _, ok := pairs[pair]; !ok {
+			names = append(names, krakenName(base)+quote)
+		}
+		pairs[pair] = append(pairs[pair], symbol)
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no valid pairs in %s", strings.Join(symbols, ","))
+	}
+
+	var r krakenResponse
+	if err := provider.GetJSON(ctx, fmt.Sprintf(krakenURL, url.QueryEscape(strings.Join(names, ","))), nil, &r); err != nil {
+		return nil, err
+	}
+	// Kraken fails the whole request if any pair is unknown.
+	if len(r.Error) > 0 {
+		return nil, fmt.Errorf("upstream error: %s", strings.Join(r.Error, ", "))
+	}
+
+	var ret []provider.Quote
+	for name, t := range r.Result {
+		base, quote, ok := krakenPair(name)
+		if !ok || len(t.C) == 0 {
+			continue
+		}
+		price := parseFloat(t.C[0])
+		if price == 0 {
+			continue
+		}
+		for _, symbol := range pairs[base+"-"+quote] {
+			q := provider.Quote{Symbol: symbol, Price: price, Currency: quote, Open: parseFloat(t.O)}
+			if len(t.H) > 0 {
+				q.High = parseFloat(t.H[0])
+			}
+			if len(t.L) > 0 {
+				q.Low = parseFloat(t.L[0])
+			}
+			if len(t.V) > 0 {
+				q.Volume = parseFloat(t.V[0])
+			}
+			ret = append(ret, q)
+		}
+	}
+	return ret, nil
+}
+
+// parseFloat parses a number, returning zero (unknown) on errors.
+func parseFloat(s string) float64 {
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0
+	}
+	return v
+}
@@ -0,0 +1,88 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package finnhub
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/marcopaganini/quotes-exporter/provider"
+)
+
+const quoteURL = "https://finnhub.io/api/v1/quote?symbol=%s"
+
+// quoteResponse is the response of the quote API.
+type quoteResponse struct {
+	Current       float64 `json:"c"`
+	High          float64 `json:"h"`
+	Low           float64 `json:"l"`
+	Open          float64 `json:"o"`
+	PreviousClose float64 `json:"pc"`
+	// Time of the last trade, in seconds since the epoch.
+	Time int64 `json:"t"`
+}
+
+// Provider is the Finnhub quote provider.
+type Provider struct {
+	// Key returns the API key.
+	Key func() string
+	// Limiter limits the requests sent upstream (the free tier allows 60
+	// requests per minute).
+	Limiter *provider.Limiter
+}
+
+// Quote returns the quotes of the given symbols, one request per symbol.
+func (p *Provider) Quote(ctx context.Context, symbols []string) ([]provider.Quote, error) {
+	var key string
+	if p.Key != nil {
+		key = p.Key()
+	}
+	key = provider.Token(ctx, key)
+	if key == "" {
+		return nil, errors.New("missing Finnhub API key")
+	}
+	header := http.Header{"X-Finnhub-Token": []string{key}}
+
+	return provider.Each(ctx, symbols, func(ctx context.Context, symbol string) (provider.Quote, error) {
+		if err := p.Limiter.Allow(); err != nil {
+			return provider.Quote{}, fmt.Errorf("%s: %w", symbol, err)
+		}
+
+		var resp quoteResponse
+		u := fmt.Sprintf(quoteURL, url.QueryEscape(strings.ToUpper(symbol)))
+		if err := provider.GetJSON(ctx, u, header, &resp); err != nil {
+			return provider.Quote{}, err
+		}
+		// Unknown symbols return all zeros.
+		if resp.Current == 0 {
+			return provider.Quote{}, fmt.Errorf("%w: %s", provider.ErrNotFound, symbol)
+		}
+
+		q := provider.Quote{
+			Symbol:        symbol,
+			Price:         resp.Current,
+			Open:          resp.Open,
+			High:          resp.High,
+			Low:           resp.Low,
+			PreviousClose: resp.PreviousClose,
+		}
+		if resp.Time != 0 {
+			q.Time = time.Unix(resp.Time, 0)
+		}
+		return q, nil
+	})
+}
@@ -0,0 +1,70 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"flag"
+	"net/url"
+	"strings"
+)
+
+// stringList is a flag.Value holding a comma separated list of strings.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = nil
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			*l = append(*l, v)
+		}
+	}
+	return nil
+}
+
+// secretString is a flag.Value holding a credential (or a secret reference),
+// redacted when printing the configuration.
+type secretString string
+
+func (s *secretString) String() string {
+	return string(*s)
+}
+
+func (s *secretString) Set(value string) error {
+	*s = secretString(value)
+	return nil
+}
+
+// secretVar defines a string flag holding a credential.
+func secretVar(fs *flag.FlagSet, p *string, name, usage string) {
+	fs.Var((*secretString)(p), name, usage)
+}
+
+// redactedValue returns the value of a flag safe to print: credentials are
+// redacted, and so are user names and passwords in URLs.
+func redactedValue(f *flag.Flag) string {
+	v := f.Value.String()
+	if _, ok := f.Value.(*secretString); ok {
+		if v == "" {
+			return ""
+		}
+		return "<redacted>"
+	}
+	if u, err := url.Parse(v); err == nil && u.User != nil {
+		u.User = nil
+		return u.String()
+	}
+	return v
+}
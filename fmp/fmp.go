@@ -0,0 +1,92 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package fmp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/marcopaganini/quotes-exporter/provider"
+)
+
+const quoteURL = "https://financialmodelingprep.com/api/v3/quote/%s?apikey=%s"
+
+// quoteResponse is an entry in the response of the quote API.
+type quoteResponse struct {
+	Symbol        string  `json:"symbol"`
+	Price         float64 `json:"price"`
+	Open          float64 `json:"open"`
+	DayHigh       float64 `json:"dayHigh"`
+	DayLow        float64 `json:"dayLow"`
+	PreviousClose float64 `json:"previousClose"`
+	Volume        float64 `json:"volume"`
+	MarketCap     float64 `json:"marketCap"`
+	PE            float64 `json:"pe"`
+	// Timestamp is the time of the quote, in seconds since the epoch.
+	Timestamp int64 `json:"timestamp"`
+}
+
+// Provider is the Financial Modeling Prep quote provider.
+type Provider struct {
+	// Key returns the API key.
+	Key func() string
+	// Limiter limits the requests sent upstream.
+	Limiter *provider.Limiter
+}
+
+// Quote returns the quotes of the given symbols with one request.
+func (p *Provider) Quote(ctx context.Context, symbols []string) ([]provider.Quote, error) {
+	var key string
+	if p.Key != nil {
+		key = p.Key()
+	}
+	key = provider.Token(ctx, key)
+	if key == "" {
+		return nil, errors.New("missing Financial Modeling Prep API key")
+	}
+	if err := p.Limiter.Allow(); err != nil {
+		return nil, err
+	}
+
+	var resp []quoteResponse
+	u := fmt.Sprintf(quoteURL, url.PathEscape(strings.ToUpper(strings.Join(symbols, ","))), url.QueryEscape(key))
+	if err := provider.GetJSON(ctx, u, nil, &resp); err != nil {
+		return nil, err
+	}
+
+	var ret []provider.Quote
+	for _, r := range resp {
+		if r.Price == 0 {
+			continue
+		}
+		q := provider.Quote{
+			Symbol:        r.Symbol,
+			Price:         r.Price,
+			Open:          r.Open,
+			High:          r.DayHigh,
+			Low:           r.DayLow,
+			PreviousClose: r.PreviousClose,
+			Volume:        r.Volume,
+			MarketCap:     r.MarketCap,
+			PE:            r.PE,
+		}
+		if r.Timestamp != 0 {
+			q.Time = time.Unix(r.Timestamp, 0)
+		}
+		ret = append(ret, q)
+	}
+	return ret, nil
+}
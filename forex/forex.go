@@ -0,0 +1,127 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package forex
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/marcopaganini/quotes-exporter/provider"
+)
+
+const frankfurterURL = "https://api.frankfurter.app/latest?from=%s&to=%s"
+
+// Pair returns the base and quote currencies of a currency pair symbol. The
+// forms EUR/USD, EUR-USD, EURUSD and EURUSD=X (as used by Yahoo) are accepted.
+func Pair(symbol string) (string, string, bool) {
+	s := strings.TrimSuffix(strings.ToUpper(symbol), "=X")
+	s = strings.NewReplacer("/", "", "-", "").Replace(s)
+	if len(s) != 6 {
+		return "", "", false
+	}
+	for _, r := range s {
+		if r < 'A' || r > 'Z' {
+			return "", "", false
+		}
+	}
+	return s[:3], s[3:], true
+}
+
+// PairLabel returns the currency pair of a symbol in the EUR/USD form, or an
+// empty string if the symbol isn't a currency pair.
+func PairLabel(symbol string) string {
+	base, quote, ok := Pair(symbol)
+	if !ok {
+		return ""
+	}
+	return base + "/" + quote
+}
+
+// quotes returns the quotes of symbols (keyed by quote currency) from the
+// rates of a base currency (keyed by quote currency).
+func quotes(symbols map[string][]string, rates map[string]float64) []provider.Quote {
+	var ret []provider.Quote
+	for cur, rate := range rates {
+		if rate == 0 {
+			continue
+		}
+		for _, symbol := range symbols[strings.ToUpper(cur)] {
+			ret = append(ret, provider.Quote{Symbol: symbol, Price: rate, Currency: strings.ToUpper(cur)})
+		}
+	}
+	return ret
+}
+
+// byBase groups symbols by base currency and then by quote currency.
+func byBase(symbols []string) (map[string]map[string][]string, error) {
+	ret := map[string]map[string][]string{}
+	for _, symbol := range symbols {
+		base, quote, ok := Pair(symbol)
+		if !ok {
+			continue
+		}
+		if ret[base] == nil {
+			ret[base] = map[string][]string{}
+		}
+		ret[base][quote] = append(ret[base][quote], symbol)
+	}
+	if len(ret) == 0 {
+		return nil, fmt.Errorf("no currency pairs in %s", strings.Join(symbols, ","))
+	}
+	return ret, nil
+}
+
+// keys returns the keys of a map, comma separated.
+func keys(m map[string][]string) string {
+	var ret []string
+	for k := range m {
+		ret = append(ret, k)
+	}
+	return strings.Join(ret, ",")
+}
+
+// Frankfurter is a forex provider using the ECB reference rates published
+// by frankfurter.app. Rates are updated once a day.
+type Frankfurter struct{}
+
+// Quote returns the rates of the given currency pairs, with one request per
+// base currency.
+func (Frankfurter) Quote(ctx context.Context, symbols []string) ([]provider.Quote, error) {
+	bases, err := byBase(symbols)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		ret      []provider.Quote
+		firstErr error
+	)
+	for base, quoteSymbols := range bases {
+		var resp struct {
+			Rates map[string]float64 `json:"rates"`
+		}
+		u := fmt.Sprintf(frankfurterURL, url.QueryEscape(base), url.QueryEscape(keys(quoteSymbols)))
+		if err := provider.GetJSON(ctx, u, nil, &resp); err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		ret = append(ret, quotes(quoteSymbols, resp.Rates)...)
+	}
+	if len(ret) == 0 && firstErr != nil {
+		return nil, firstErr
+	}
+	return ret, nil
+}
@@ -0,0 +1,81 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package forex
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/marcopaganini/quotes-exporter/provider"
+)
+
+const oxrURL = "https://openexchangerates.org/api/latest.json?app_id=%s&base=%s"
+
+// OpenExchangeRates is a forex provider using openexchangerates.org.
+type OpenExchangeRates struct {
+	// Key returns the app ID.
+	Key func() string
+	// Base is the base currency of the requests (free plans only support
+	// USD). Pairs with other base currencies are cross rates.
+	Base string
+	// Limiter limits the requests sent upstream.
+	Limiter *provider.Limiter
+}
+
+// Quote returns the rates of the given currency pairs with one request.
+func (o *OpenExchangeRates) Quote(ctx context.Context, symbols []string) ([]provider.Quote, error) {
+	var key string
+	if o.Key != nil {
+		key = o.Key()
+	}
+	key = provider.Token(ctx, key)
+	if key == "" {
+		return nil, errors.New("missing Open Exchange Rates app ID")
+	}
+	base := strings.ToUpper(o.Base)
+	if base == "" {
+		base = "USD"
+	}
+	if err := o.Limiter.Allow(); err != nil {
+		return nil, err
+	}
+
+	var resp struct {
+		Timestamp int64              `json:"timestamp"`
+		Rates     map[string]float64 `json:"rates"`
+	}
+	if err := provider.GetJSON(ctx, fmt.Sprintf(oxrURL, url.QueryEscape(key), url.QueryEscape(base)), nil, &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.Rates) == 0 {
+		return nil, errors.New("empty rates from upstream")
+	}
+	resp.Rates[base] = 1
+
+	var ret []provider.Quote
+	for _, symbol := range symbols {
+		from, to, ok := Pair(symbol)
+		if !ok || resp.Rates[from] == 0 || resp.Rates[to] == 0 {
+			continue
+		}
+		q := provider.Quote{Symbol: symbol, Price: resp.Rates[to] / resp.Rates[from], Currency: to}
+		if resp.Timestamp != 0 {
+			q.Time = time.Unix(resp.Timestamp, 0)
+		}
+		ret = append(ret, q)
+	}
+	return ret, nil
+}
@@ -0,0 +1,81 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package fred
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/marcopaganini/quotes-exporter/provider"
+)
+
+const observationsURL = "https://api.stlouisfed.org/fred/series/observations?series_id=%s&api_key=%s&file_type=json&sort_order=desc&limit=10"
+
+// observationsResponse is the response of the series observations API.
+// Missing values are reported as ".".
+type observationsResponse struct {
+	Observations []struct {
+		Date  string `json:"date"`
+		Value string `json:"value"`
+	} `json:"observations"`
+}
+
+// Provider is the FRED (St. Louis Fed) provider. Symbols are series IDs
+// (e.g. DGS10, SOFR, CPIAUCSL).
+type Provider struct {
+	// Key returns the API key.
+	Key func() string
+	// Limiter limits the requests sent upstream.
+	Limiter *provider.Limiter
+}
+
+// Quote returns the latest observation of the given series, one request per
+// series.
+func (p *Provider) Quote(ctx context.Context, symbols []string) ([]provider.Quote, error) {
+	var key string
+	if p.Key != nil {
+		key = p.Key()
+	}
+	key = provider.Token(ctx, key)
+	if key == "" {
+		return nil, errors.New("missing FRED API key")
+	}
+
+	return provider.Each(ctx, symbols, func(ctx context.Context, symbol string) (provider.Quote, error) {
+		if err := p.Limiter.Allow(); err != nil {
+			return provider.Quote{}, fmt.Errorf("%s: %w", symbol, err)
+		}
+
+		var resp observationsResponse
+		u := fmt.Sprintf(observationsURL, url.QueryEscape(strings.ToUpper(symbol)), url.QueryEscape(key))
+		if err := provider.GetJSON(ctx, u, nil, &resp); err != nil {
+			return provider.Quote{}, err
+		}
+		// Observations are newest first; skip missing values (e.g. holidays).
+		for _, o := range resp.Observations {
+			v, err := strconv.ParseFloat(o.Value, 64)
+			if err == nil {
+				return provider.Quote{Symbol: symbol, Price: v}, nil
+			}
+		}
+		return provider.Quote{}, fmt.Errorf("no recent observations for %s", symbol)
+	})
+}
+
+// Labels returns the series label of a symbol.
+func Labels(symbol string) map[string]string {
+	return map[string]string{"series": strings.ToUpper(symbol)}
+}
@@ -0,0 +1,104 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package ftfunds
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/marcopaganini/quotes-exporter/provider"
+	"github.com/marcopaganini/quotes-exporter/scrape"
+)
+
+const (
+	searchURL    = "https://markets.ft.com/data/searchapi/searchsecurities?query=%s"
+	tearsheetURL = "https://markets.ft.com/data/funds/tearsheet/summary?s=%s"
+
+	// FT rejects requests carrying the default Go User-Agent.
+	userAgent = "Mozilla/5.0 (X11; Linux x86_64; rv:109.0) Gecko/20100101 Firefox/115.0"
+)
+
+var (
+	// priceRE matches the price on a tearsheet, the first data list value
+	// labelled "Price (CUR)".
+	priceRE = regexp.MustCompile(`Price \(([A-Z]{3})\)</span><span class="mod-ui-data-list__value">([^<]+)<`)
+
+	header = http.Header{"User-Agent": []string{userAgent}}
+)
+
+// searchResponse is the response of the security search API.
+type searchResponse struct {
+	Data struct {
+		Security []struct {
+			Symbol     string `json:"symbol"`
+			AssetClass string `json:"assetClass"`
+		} `json:"security"`
+	} `json:"data"`
+}
+
+// Provider is the FT funds provider, for UK and European funds. Symbols are
+// ISINs, optionally followed by the currency of the share class
+// (GB00B3X7QG63:GBP).
+type Provider struct {
+	// Limiter limits the requests sent upstream.
+	Limiter *provider.Limiter
+}
+
+// Quote returns the prices of the given funds, one request (two for ISINs
+// without a currency) per fund.
+func (p *Provider) Quote(ctx context.Context, symbols []string) ([]provider.Quote, error) {
+	return provider.Each(ctx, symbols, func(ctx context.Context, symbol string) (provider.Quote, error) {
+		if err := p.Limiter.Allow(); err != nil {
+			return provider.Quote{}, fmt.Errorf("%s: %w", symbol, err)
+		}
+		ftSymbol := strings.ToUpper(symbol)
+		if !strings.Contains(ftSymbol, ":") {
+			var err error
+			if ftSymbol, err = search(ctx, ftSymbol); err != nil {
+				return provider.Quote{}, err
+			}
+		}
+
+		body, err := provider.Get(ctx, fmt.Sprintf(tearsheetURL, url.QueryEscape(ftSymbol)), header)
+		if err != nil {
+			return provider.Quote{}, err
+		}
+		m := priceRE.FindSubmatch(body)
+		if m == nil {
+			return provider.Quote{}, fmt.Errorf("no price on the FT page of %s (unknown fund?)", ftSymbol)
+		}
+		price, _, err := scrape.ParsePrice(html.UnescapeString(string(m[2])))
+		if err != nil {
+			return provider.Quote{}, fmt.Errorf("invalid price for %s: %v", ftSymbol, err)
+		}
+		return provider.Quote{Symbol: symbol, Price: price, Currency: string(m[1])}, nil
+	})
+}
+
+// search returns the FT symbol (ISIN:CUR) of a fund ISIN.
+func search(ctx context.Context, isin string) (string, error) {
+	var resp searchResponse
+	if err := provider.GetJSON(ctx, fmt.Sprintf(searchURL, url.QueryEscape(isin)), header, &resp); err != nil {
+		return "", err
+	}
+	for _, s := range resp.Data.Security {
+		if strings.EqualFold(s.AssetClass, "fund") && strings.HasPrefix(strings.ToUpper(s.Symbol), isin+":") {
+			return s.Symbol, nil
+		}
+	}
+	return "", fmt.Errorf("%w: fund %s on FT", provider.ErrNotFound, isin)
+}
@@ -0,0 +1,159 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/marcopaganini/quotes-exporter/provider"
+)
+
+var providerHealthyDesc = prometheus.NewDesc(
+	"quotes_exporter_provider_healthy",
+	"Whether a provider is healthy (1) or skipped after repeated failures (0).",
+	[]string{"provider"}, nil,
+)
+
+// providerState holds the health of a provider. Consecutive failures mark a
+// provider unhealthy, so it's skipped in favor of fallbacks, and past
+// breaker.failures open its circuit, so it's not called at all.
+type providerState struct {
+	// failures is the number of consecutive failed calls.
+	failures int
+	// lastFailure is the time of the last failed call.
+	lastFailure time.Time
+	// open is true while calls to the provider are suspended.
+	open bool
+	// openedAt is when the circuit last opened.
+	openedAt time.Time
+	// probing is true while a single trial call checks if an open circuit
+	// can close again.
+	probing bool
+}
+
+// providerHealth tracks the health and circuit breakers of the providers
+// used so far.
+var providerHealth = struct {
+	sync.Mutex
+	states map[string]*providerState
+}{states: map[string]*providerState{}}
+
+// requestError returns true if err is caused by the request, like an unknown
+// symbol, rather than by the provider. The provider answered, so these count
+// as successful calls.
+func requestError(err error) bool {
+	switch provider.StatusCode(err) {
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		return true
+	}
+	return notFound(err)
+}
+
+// recordProviderResult records the result of a call to a provider, marking
+// it unhealthy or opening its circuit after too many consecutive failures.
+func recordProviderResult(name string, err error) {
+	providerHealth.Lock()
+	defer providerHealth.Unlock()
+
+	s, ok := providerHealth.states[name]
+	if !ok {
+		s = &providerState{}
+		providerHealth.states[name] = s
+	}
+	if err == nil || requestError(err) {
+		if s.open {
+			logger("breaker").Info("Provider circuit closed", "provider", name)
+		}
+		*s = providerState{}
+		return
+	}
+	// Running out of our own rate limit, or upstream asking us to slow
+	// down, says nothing about the provider, so a trial call must be
+	// retried.
+	if errors.Is(err, provider.ErrRateLimited) || !provider.RetryAfter(err).IsZero() {
+		s.probing = false
+		return
+	}
+	s.failures++
+	s.lastFailure = time.Now()
+	if s.failures == cfg.Health.UnhealthyAfter {
+		logger("health").Warn("Provider marked unhealthy", "provider", name, "failures", s.failures, "error", err)
+	}
+	if cfg.Breaker.Failures > 0 && (s.probing || (!s.open && s.failures >= cfg.Breaker.Failures)) {
+		if !s.open {
+			logger("breaker").Warn("Provider circuit opened", "provider", name, "failures", s.failures, "error", err)
+		}
+		s.open = true
+		s.openedAt = time.Now()
+		s.probing = false
+	}
+}
+
+// unhealthy returns true if a provider failed too many times in a row. An
+// unhealthy provider counts as healthy again once the retry interval has
+// passed since its last failure, to let one call check if it recovered.
+func unhealthy(name string) bool {
+	if cfg.Health.UnhealthyAfter <= 0 {
+		return false
+	}
+	providerHealth.Lock()
+	defer providerHealth.Unlock()
+
+	s, ok := providerHealth.states[name]
+	return ok && s.failures >= cfg.Health.UnhealthyAfter && time.Since(s.lastFailure) < cfg.Health.RetryInterval
+}
+
+// healthyChain returns the healthy providers in a chain, or the whole chain
+// if none is healthy.
+func healthyChain(chain []string) []string {
+	var ret []string
+	for _, name := range chain {
+		if !unhealthy(name) {
+			ret = append(ret, name)
+		}
+	}
+	if len(ret) == 0 {
+		return chain
+	}
+	return ret
+}
+
+// healthCollector exports the health of the providers used so far.
+type healthCollector struct{}
+
+// Describe outputs description for prometheus timeseries.
+func (healthCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- providerHealthyDesc
+}
+
+// Collect outputs the provider health gauges.
+func (healthCollector) Collect(ch chan<- prometheus.Metric) {
+	providerHealth.Lock()
+	var names []string
+	for name := range providerHealth.states {
+		names = append(names, name)
+	}
+	providerHealth.Unlock()
+
+	for _, name := range names {
+		v := 1.0
+		if unhealthy(name) {
+			v = 0
+		}
+		ch <- prometheus.MustNewConstMetric(providerHealthyDesc, prometheus.GaugeValue, v, name)
+	}
+}
@@ -0,0 +1,318 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/marcopaganini/quotes-exporter/yahoo"
+)
+
+const (
+	// Number of trading days used to compute the realized volatility.
+	volatilityDays = 30
+
+	// Trading days in a year, used to annualize daily figures.
+	tradingDaysPerYear = 252
+)
+
+// historyDays returns the number of calendar days of history needed to
+// satisfy all enabled history based metrics.
+func historyDays() int {
+	// Leave room for weekends and holidays.
+	days := cfg.History.Closes*7/5 + 10
+	if cfg.History.Returns && days < 376 {
+		days = 376
+	}
+	if cfg.History.Volatility && days < volatilityDays*7/5+10 {
+		days = volatilityDays*7/5 + 10
+	}
+	for _, n := range cfg.History.SMA {
+		if days < n*7/5+10 {
+			days = n*7/5 + 10
+		}
+	}
+	if days < cfg.History.ZScore*7/5+10 {
+		days = cfg.History.ZScore*7/5 + 10
+	}
+	// EMAs need some extra history to converge.
+	for _, n := range cfg.History.EMA {
+		if days < 3*n*7/5+10 {
+			days = 3*n*7/5 + 10
+		}
+	}
+	return days
+}
+
+// historyEnabled returns true if any history based metric is enabled.
+func historyEnabled() bool {
+	return cfg.History.Closes > 0 || cfg.History.Returns || cfg.History.Volatility || len(cfg.History.SMA) > 0 || len(cfg.History.EMA) > 0 || cfg.History.ZScore > 0
+}
+
+// intList is a flag.Value holding a comma separated list of positive integers.
+type intList []int
+
+func (l *intList) String() string {
+	var s []string
+	for _, n := range *l {
+		s = append(s, strconv.Itoa(n))
+	}
+	return strings.Join(s, ",")
+}
+
+func (l *intList) Set(value string) error {
+	*l = nil
+	for _, v := range strings.Split(value, ",") {
+		if v == "" {
+			continue
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return fmt.Errorf("invalid positive number: %q", v)
+		}
+		*l = append(*l, n)
+	}
+	return nil
+}
+
+// history returns the daily closes for a symbol, oldest first, excluding the
+// current trading session. Results are cached just like quotes. If the local
+// history store is enabled, fetched closes are saved to it, and it is used
+// as a fallback when the provider fails.
+func history(symbol string) ([]yahoo.Close, error) {
+	fetcher := func() (interface{}, error) {
+		ctx, cancel := upstreamContext(context.Background())
+		defer cancel()
+		since := time.Now().AddDate(0, 0, -historyDays())
+		closes, err := yahoo.History(ctx, yahooSymbol(symbol), since)
+		if err != nil {
+			return nil, err
+		}
+		if len(closes) < 2 {
+			return nil, fmt.Errorf("not enough history for %s", symbol)
+		}
+		// The last entry is the current session.
+		closes = closes[:len(closes)-1]
+
+		if cfg.History.Dir != "" {
+			if err := saveHistory(symbol, closes); err != nil {
+				logger("history").Warn("Unable to update local history store", "symbol", symbol, "error", err)
+			}
+		}
+		return closes, nil
+	}
+
+	hret, err, _ := memoized("history:"+symbol, fetcher)
+	if err != nil {
+		if cfg.History.Dir == "" {
+			return nil, err
+		}
+		closes, serr := loadHistory(symbol)
+		if serr != nil || len(closes) == 0 {
+			return nil, err
+		}
+		logger("history").Warn("Using local history store", "provider", "yahoo", "symbol", symbol, "error", err)
+		return closes, nil
+	}
+	closes, ok := hret.([]yahoo.Close)
+	if !ok {
+		return nil, fmt.Errorf("invalid history data for %s: %v", symbol, hret)
+	}
+	return closes, nil
+}
+
+// collectCloses emits the last daily closes of a symbol, as configured in
+// history.closes, labeled by their offset in trading days from the current
+// session.
+func collectCloses(ch chan<- prometheus.Metric, symbol string, closes []yahoo.Close) {
+	ls := []string{"symbol", "name", "offset"}
+	desc := prometheus.NewDesc("quotes_exporter_close", "Daily close price, N trading days ago.", ls, nil)
+
+	for i := 1; i <= cfg.History.Closes && i <= len(closes); i++ {
+		ch <- prometheus.MustNewConstMetric(
+			desc,
+			prometheus.GaugeValue,
+			roundPrice(symbol, closes[len(closes)-i].Price),
+			symbol, displayName(symbol), fmt.Sprintf("%dd", i),
+		)
+	}
+}
+
+// closeAt returns the last close at or before the given time.
+func closeAt(closes []yahoo.Close, t time.Time) (yahoo.Close, bool) {
+	for i := len(closes) - 1; i >= 0; i-- {
+		if !closes[i].Time.After(t) {
+			return closes[i], true
+		}
+	}
+	return yahoo.Close{}, false
+}
+
+// collectReturns emits the year-to-date and trailing one year returns of a
+// symbol, in percent, based on its current price.
+func collectReturns(ch chan<- prometheus.Metric, symbol string, price float64, closes []yahoo.Close) {
+	ls := []string{"symbol", "name", "period"}
+	desc := prometheus.NewDesc("quotes_exporter_return_percent", "Price return over a period, in percent.", ls, nil)
+
+	now := time.Now()
+	periods := []struct {
+		name string
+		ref  time.Time
+	}{
+		// YTD returns are relative to the last close of the previous year.
+		{"ytd", time.Date(now.Year(), 1, 1, 0, 0, 0, 0, now.Location()).Add(-time.Nanosecond)},
+		{"1y", now.AddDate(-1, 0, 0)},
+	}
+
+	for _, p := range periods {
+		ref, ok := closeAt(closes, p.ref)
+		if !ok || ref.Price == 0 {
+			logger("history").Warn("Not enough history to compute return", "symbol", symbol, "period", p.name)
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(
+			desc,
+			prometheus.GaugeValue,
+			(price-ref.Price)/ref.Price*100,
+			symbol, displayName(symbol), p.name,
+		)
+	}
+}
+
+// collectVolatility emits the annualized standard deviation of the daily log
+// returns of a symbol over the last volatilityDays trading days.
+func collectVolatility(ch chan<- prometheus.Metric, symbol string, closes []yahoo.Close) {
+	if len(closes) < volatilityDays+1 {
+		logger("history").Warn("Not enough history to compute volatility", "symbol", symbol)
+		return
+	}
+	closes = closes[len(closes)-volatilityDays-1:]
+
+	rets := logReturns(closes)
+	if len(rets) < 2 {
+		return
+	}
+	_, stddev := meanStddev(rets)
+
+	ls := []string{"symbol", "name", "window"}
+	ch <- prometheus.MustNewConstMetric(
+		prometheus.NewDesc("quotes_exporter_volatility", "Annualized realized volatility of daily returns.", ls, nil),
+		prometheus.GaugeValue,
+		stddev*math.Sqrt(tradingDaysPerYear),
+		symbol, displayName(symbol), fmt.Sprintf("%dd", volatilityDays),
+	)
+}
+
+// collectAverages emits the simple and exponential moving averages of the
+// daily closes of a symbol, for each configured window.
+func collectAverages(ch chan<- prometheus.Metric, symbol string, closes []yahoo.Close) {
+	ls := []string{"symbol", "name", "window"}
+	smaDesc := prometheus.NewDesc("quotes_exporter_sma", "Simple moving average of daily closes.", ls, nil)
+	emaDesc := prometheus.NewDesc("quotes_exporter_ema", "Exponential moving average of daily closes.", ls, nil)
+
+	for _, n := range cfg.History.SMA {
+		if len(closes) < n {
+			logger("history").Warn("Not enough history to compute SMA", "symbol", symbol, "window", n)
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(smaDesc, prometheus.GaugeValue, roundPrice(symbol, sma(closes[len(closes)-n:])), symbol, displayName(symbol), fmt.Sprintf("%dd", n))
+	}
+
+	for _, n := range cfg.History.EMA {
+		if len(closes) < n {
+			logger("history").Warn("Not enough history to compute EMA", "symbol", symbol, "window", n)
+			continue
+		}
+		// Seed with the SMA of the oldest n closes, then smooth forward.
+		k := 2 / float64(n+1)
+		ema := sma(closes[:n])
+		for _, c := range closes[n:] {
+			ema = c.Price*k + ema*(1-k)
+		}
+		ch <- prometheus.MustNewConstMetric(emaDesc, prometheus.GaugeValue, roundPrice(symbol, ema), symbol, displayName(symbol), fmt.Sprintf("%dd", n))
+	}
+}
+
+// sma returns the simple average of the prices in closes.
+func sma(closes []yahoo.Close) float64 {
+	var sum float64
+	for _, c := range closes {
+		sum += c.Price
+	}
+	return sum / float64(len(closes))
+}
+
+// logReturns returns the daily log returns of closes, skipping days without
+// a valid price.
+func logReturns(closes []yahoo.Close) []float64 {
+	var rets []float64
+	for i := 1; i < len(closes); i++ {
+		if closes[i-1].Price <= 0 || closes[i].Price <= 0 {
+			continue
+		}
+		rets = append(rets, math.Log(closes[i].Price/closes[i-1].Price))
+	}
+	return rets
+}
+
+// meanStddev returns the mean and sample standard deviation of xs, which must
+// hold at least two values.
+func meanStddev(xs []float64) (float64, float64) {
+	var mean float64
+	for _, x := range xs {
+		mean += x
+	}
+	mean /= float64(len(xs))
+
+	var variance float64
+	for _, x := range xs {
+		variance += (x - mean) * (x - mean)
+	}
+	return mean, math.Sqrt(variance / float64(len(xs)-1))
+}
+
+// collectZScore emits the move of the current price from the last close as a
+// z-score: the number of standard deviations away from the mean daily log
+// return over the last history.zscore trading days.
+func collectZScore(ch chan<- prometheus.Metric, symbol string, price float64, closes []yahoo.Close) {
+	n := cfg.History.ZScore
+	if len(closes) < n+1 || price <= 0 {
+		logger("history").Warn("Not enough history to compute z-score", "symbol", symbol)
+		return
+	}
+	closes = closes[len(closes)-n-1:]
+
+	rets := logReturns(closes)
+	if len(rets) < 2 {
+		return
+	}
+	mean, stddev := meanStddev(rets)
+	last := closes[len(closes)-1].Price
+	if stddev == 0 || last <= 0 {
+		return
+	}
+
+	ls := []string{"symbol", "name", "window"}
+	ch <- prometheus.MustNewConstMetric(
+		prometheus.NewDesc("quotes_exporter_move_zscore", "Move of the price from the last close, in standard deviations of daily returns.", ls, nil),
+		prometheus.GaugeValue,
+		(math.Log(price/last)-mean)/stddev,
+		symbol, displayName(symbol), fmt.Sprintf("%dd", n),
+	)
+}
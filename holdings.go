@@ -0,0 +1,73 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/marcopaganini/quotes-exporter/yahoo"
+)
+
+// holdings returns the top holdings of a fund. Results are cached just like
+// quotes.
+func holdings(symbol string) ([]yahoo.Holding, error) {
+	fetcher := func() (interface{}, error) {
+		ctx, cancel := upstreamContext(context.Background())
+		defer cancel()
+		return yahoo.TopHoldings(ctx, yahooSymbol(symbol))
+	}
+
+	hret, err, _ := memoized("holdings:"+symbol, fetcher)
+	if err != nil {
+		return nil, err
+	}
+	h, ok := hret.([]yahoo.Holding)
+	if !ok {
+		return nil, fmt.Errorf("invalid holdings data for %s: %v", symbol, hret)
+	}
+	return h, nil
+}
+
+// collectHoldings emits the weight of each of the top holdings of a fund.
+// Symbols that are not ETFs or mutual funds are silently ignored.
+func collectHoldings(ch chan<- prometheus.Metric, symbol string) {
+	meta, err := symbolMeta(symbol)
+	if err != nil {
+		errorCount.Inc()
+		logger("holdings").Error("Error looking up asset type", "provider", "yahoo", "symbol", symbol, "error", err)
+		return
+	}
+	if meta.InstrumentType != "ETF" && meta.InstrumentType != "MUTUALFUND" {
+		return
+	}
+
+	h, err := holdings(symbol)
+	if err != nil {
+		errorCount.Inc()
+		logger("holdings").Error("Error fetching holdings", "provider", "yahoo", "symbol", symbol, "error", err)
+		return
+	}
+
+	ls := []string{"etf", "holding", "holding_name"}
+	desc := prometheus.NewDesc("quotes_exporter_etf_holding_weight", "Weight of a top holding in a fund (0-1).", ls, nil)
+	for _, holding := range h {
+		// Some holdings (e.g. cash, bonds) have no symbol.
+		hsym := holding.Symbol
+		if hsym == "" {
+			hsym = holding.Name
+		}
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, holding.Weight, symbol, hsym, holding.Name)
+	}
+}
@@ -0,0 +1,128 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package htmlpage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/marcopaganini/quotes-exporter/provider"
+	"github.com/marcopaganini/quotes-exporter/scrape"
+)
+
+const (
+	// DefaultPage is the key of the page used for symbols without one.
+	DefaultPage = "*"
+
+	userAgent = "Mozilla/5.0 (X11; Linux x86_64; rv:109.0) Gecko/20100101 Firefox/115.0"
+)
+
+// Page describes where to find the price of a symbol on a web page.
+type Page struct {
+	// URL is the page URL. {symbol} is replaced by the symbol.
+	URL string `json:"url"`
+	// Selector is the CSS selector of the element holding the price.
+	Selector string `json:"selector"`
+	// Attribute, if set, names the attribute holding the price, instead of
+	// the element text (e.g. "content" in <meta itemprop="price">).
+	Attribute string `json:"attribute"`
+	// Currency is the currency of the price, if the page doesn't show it.
+	Currency string `json:"currency"`
+	// Decimal is the decimal separator of the price ("." or ","), guessed
+	// from the text if empty.
+	Decimal string `json:"decimal"`
+	// Headers are added to requests.
+	Headers map[string]string `json:"headers"`
+}
+
+// Validate checks the page description.
+func (p Page) Validate() error {
+	if p.URL == "" {
+		return errors.New("missing URL")
+	}
+	if p.Selector == "" {
+		return errors.New("missing selector")
+	}
+	if p.Decimal != "" && p.Decimal != "." && p.Decimal != "," {
+		return fmt.Errorf("invalid decimal separator %q", p.Decimal)
+	}
+	_, err := scrape.ParseSelector(p.Selector)
+	return err
+}
+
+// Provider is the web page scraping provider, reading prices from pages
+// described in its configuration, such as fund NAVs on bank websites.
+type Provider struct {
+	// Pages maps symbols to their pages. The DefaultPage key applies to
+	// symbols not listed.
+	Pages map[string]Page
+	// Limiter limits the requests sent upstream.
+	Limiter *provider.Limiter
+}
+
+// Quote returns the quotes of the given symbols, one request per symbol.
+func (p *Provider) Quote(ctx context.Context, symbols []string) ([]provider.Quote, error) {
+	return provider.Each(ctx, symbols, func(ctx context.Context, symbol string) (provider.Quote, error) {
+		page, ok := p.page(symbol)
+		if !ok {
+			return provider.Quote{}, fmt.Errorf("no page configured for %s", symbol)
+		}
+		sel, err := scrape.ParseSelector(page.Selector)
+		if err != nil {
+			return provider.Quote{}, err
+		}
+		header := http.Header{"User-Agent": []string{userAgent}}
+		for k, v := range page.Headers {
+			header.Set(k, v)
+		}
+
+		if err := p.Limiter.Allow(); err != nil {
+			return provider.Quote{}, fmt.Errorf("%s: %w", symbol, err)
+		}
+		u := strings.Replace(page.URL, "{symbol}", url.PathEscape(symbol), -1)
+		body, err := provider.Get(ctx, u, header)
+		if err != nil {
+			return provider.Quote{}, err
+		}
+
+		text, attrs, ok := sel.Find(string(body))
+		if !ok {
+			return provider.Quote{}, fmt.Errorf("no element matching %q on the page of %s", page.Selector, symbol)
+		}
+		if page.Attribute != "" {
+			text = attrs[strings.ToLower(page.Attribute)]
+		}
+		price, currency, err := scrape.ParsePriceDecimal(text, page.Decimal)
+		if err != nil {
+			return provider.Quote{}, fmt.Errorf("invalid price for %s: %v", symbol, err)
+		}
+		if page.Currency != "" {
+			currency = strings.ToUpper(page.Currency)
+		}
+		return provider.Quote{Symbol: symbol, Price: price, Currency: currency}, nil
+	})
+}
+
+// page returns the page of a symbol.
+func (p *Provider) page(symbol string) (Page, bool) {
+	for s, page := range p.Pages {
+		if strings.EqualFold(s, symbol) {
+			return page, true
+		}
+	}
+	page, ok := p.Pages[DefaultPage]
+	return page, ok
+}
@@ -0,0 +1,175 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/marcopaganini/quotes-exporter/openfigi"
+)
+
+// figiKey holds the OpenFIGI API key, if configured.
+var figiKey *secret
+
+// figiCacheTTL is how long identifier mappings are cached. Listings rarely
+// change, and OpenFIGI allows few requests without a key.
+const figiCacheTTL = 24 * time.Hour
+
+// exchangeSuffixes maps OpenFIGI exchange codes to Yahoo symbol suffixes, in
+// order of preference.
+var exchangeSuffixes = []struct {
+	code   string
+	suffix string
+}{
+	{"US", ""},
+	{"LN", ".L"},
+	{"GY", ".DE"},
+	{"GR", ".F"},
+	{"FP", ".PA"},
+	{"NA", ".AS"},
+	{"IM", ".MI"},
+	{"SM", ".MC"},
+	{"SW", ".SW"},
+	{"BB", ".BR"},
+	{"ID", ".IR"},
+	{"CN", ".TO"},
+	{"AU", ".AX"},
+	{"JP", ".T"},
+	{"HK", ".HK"},
+}
+
+// identifierType returns the OpenFIGI identifier type of a symbol if it is a
+// valid ISIN or CUSIP (check digit included), or an empty string otherwise.
+func identifierType(symbol string) string {
+	s := strings.ToUpper(symbol)
+	switch {
+	case len(s) == 12 && isLetter(s[0]) && isLetter(s[1]) && checkDigit(s[:11], false) == s[11]:
+		return openfigi.ISIN
+	case len(s) == 9 && checkDigit(s[:8], true) == s[8]:
+		return openfigi.CUSIP
+	}
+	return ""
+}
+
+// checkDigit returns the check digit of an ISIN or CUSIP without its last
+// character. Both use the Luhn algorithm over the digits of each character
+// (letters count as 10-35), but CUSIP doubles every second character while
+// ISIN doubles every second digit from the right.
+func checkDigit(s string, cusip bool) byte {
+	var digits []int
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		var v int
+		switch {
+		case c >= '0' && c <= '9':
+			v = int(c - '0')
+		case isLetter(c):
+			v = int(c-'A') + 10
+		case cusip && c == '*':
+			v = 36
+		case cusip && c == '@':
+			v = 37
+		case cusip && c == '#':
+			v = 38
+		default:
+			return 0
+		}
+		if cusip {
+			if i%2 == 1 {
+				v *= 2
+			}
+			digits = append(digits, v/10, v%10)
+			continue
+		}
+		if v >= 10 {
+			digits = append(digits, v/10)
+		}
+		digits = append(digits, v%10)
+	}
+
+	var sum int
+	for i := range digits {
+		d := digits[len(digits)-1-i]
+		if !cusip && i%2 == 0 {
+			d *= 2
+			d = d/10 + d%10
+		}
+		sum += d
+	}
+	return byte('0' + (10-sum%10)%10)
+}
+
+// identifierSymbols returns the identifiers in comma separated lists,
+// checking they are valid identifiers of the given type.
+func identifierSymbols(idType string, values []string) ([]string, error) {
+	var ret []string
+	for _, v := range values {
+		for _, id := range strings.Split(v, ",") {
+			if id = strings.TrimSpace(id); id == "" {
+				continue
+			}
+			if identifierType(id) != idType {
+				return nil, fmt.Errorf("invalid identifier %q", id)
+			}
+			ret = append(ret, strings.ToUpper(id))
+		}
+	}
+	return ret, nil
+}
+
+// isLetter returns true for upper case ASCII letters.
+func isLetter(c byte) bool {
+	return c >= 'A' && c <= 'Z'
+}
+
+// resolveIdentifier returns the ticker for an ISIN or CUSIP, with the Yahoo
+// suffix of its preferred exchange. Results are cached for figiCacheTTL.
+func resolveIdentifier(idType, id string) (string, error) {
+	fetcher := func() (interface{}, error) {
+		key := ""
+		if figiKey != nil {
+			key = figiKey.Get()
+		}
+		ctx, cancel := upstreamContext(context.Background())
+		defer cancel()
+		listings, err := openfigi.Map(ctx, idType, strings.ToUpper(id), key)
+		if err != nil {
+			return nil, err
+		}
+		for _, es := range exchangeSuffixes {
+			for _, l := range listings {
+				if l.ExchangeCode == es.code && l.Ticker != "" {
+					// Yahoo uses dashes for share classes (BRK/B -> BRK-B).
+					return strings.Replace(l.Ticker, "/", "-", -1) + es.suffix, nil
+				}
+			}
+		}
+		return nil, fmt.Errorf("no listing of %s on a supported exchange", id)
+	}
+
+	key := "figi:" + strings.ToUpper(id)
+	tret, err, cached := memoized(key, fetcher)
+	if err != nil {
+		return "", err
+	}
+	ticker, ok := tret.(string)
+	if !ok {
+		return "", fmt.Errorf("invalid mapping data for %s: %v", id, tret)
+	}
+	if !cached {
+		cache.Storage.Set(key, ticker, figiCacheTTL)
+	}
+	return ticker, nil
+}
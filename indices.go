@@ -0,0 +1,83 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/marcopaganini/quotes-exporter/provider"
+	"github.com/marcopaganini/quotes-exporter/yahoo"
+)
+
+// assetTypeIndex is the asset type reported for market indices.
+const assetTypeIndex = "INDEX"
+
+// volatilityIndices contains the volatility indices known to the exporter.
+// These are not tradeable (and have no volume), so most quote sources can't
+// resolve them. They are always fetched from Yahoo, using the caret prefix.
+var volatilityIndices = map[string]bool{
+	"VIX":   true, // CBOE S&P 500 Volatility
+	"VIX9D": true, // CBOE S&P 500 9-day Volatility
+	"VIX3M": true, // CBOE S&P 500 3-month Volatility
+	"VIX6M": true, // CBOE S&P 500 6-month Volatility
+	"VVIX":  true, // CBOE VIX Volatility
+	"VXN":   true, // CBOE Nasdaq-100 Volatility
+	"VXD":   true, // CBOE DJIA Volatility
+	"RVX":   true, // CBOE Russell 2000 Volatility
+	"OVX":   true, // CBOE Crude Oil Volatility
+	"GVZ":   true, // CBOE Gold Volatility
+	"MOVE":  true, // ICE BofAML MOVE (Treasury volatility)
+}
+
+// volatilityIndex returns the Yahoo symbol for a volatility index, and
+// whether the symbol is a known volatility index at all. Both "VIX" and
+// "^VIX" are accepted.
+func volatilityIndex(symbol string) (string, bool) {
+	name := strings.TrimPrefix(strings.ToUpper(symbol), "^")
+	if !volatilityIndices[name] {
+		return "", false
+	}
+	return "^" + name, true
+}
+
+// yahooSymbol returns the symbol to use when querying Yahoo.
+func yahooSymbol(symbol string) string {
+	symbol = upstreamSymbol(symbol)
+	if ysym, ok := volatilityIndex(symbol); ok {
+		return ysym
+	}
+	return symbol
+}
+
+// indexQuote returns the current value of a volatility index.
+func indexQuote(ctx context.Context, symbol string) (quote, error) {
+	ysym := yahooSymbol(symbol)
+	meta, err := yahoo.Quote(ctx, ysym)
+	if err != nil {
+		return quote{}, err
+	}
+	if meta.InstrumentType != assetTypeIndex {
+		return quote{}, fmt.Errorf("%s is not an index (type %q)", ysym, meta.InstrumentType)
+	}
+	if meta.RegularMarketPrice == 0 {
+		return quote{}, fmt.Errorf("query returned price=0 for %s", ysym)
+	}
+	q := provider.Quote{
+		Symbol:        ysym,
+		Price:         meta.RegularMarketPrice,
+		Time:          marketTime(meta),
+		PreviousClose: meta.PreviousClose,
+	}
+	return quote{Quote: q}, nil
+}
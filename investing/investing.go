@@ -0,0 +1,96 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package investing
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/marcopaganini/quotes-exporter/provider"
+	"github.com/marcopaganini/quotes-exporter/scrape"
+)
+
+const (
+	baseURL = "https://www.investing.com/"
+
+	// investing.com blocks requests that don't look like they come from a
+	// browser.
+	userAgent = "Mozilla/5.0 (X11; Linux x86_64; rv:109.0) Gecko/20100101 Firefox/115.0"
+)
+
+var (
+	priceRE    = regexp.MustCompile(`data-test="instrument-price-last"[^>]*>([^<]+)<`)
+	changeRE   = regexp.MustCompile(`data-test="instrument-price-change-percent"[^>]*>(.*?)</`)
+	currencyRE = regexp.MustCompile(`Currency in\s*(?:<[^>]*>\s*)*([A-Z]{3})\b`)
+	tagRE      = regexp.MustCompile(`<[^>]*>`)
+
+	header = http.Header{
+		"User-Agent":      []string{userAgent},
+		"Accept":          []string{"text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8"},
+		"Accept-Language": []string{"en-US,en;q=0.5"},
+	}
+)
+
+// Provider is the investing.com scraping provider, a best effort fallback for
+// instruments not covered by the API providers. Symbols are the paths of
+// instrument pages (equities/apple-computer-inc, certificates/some-cert) or
+// their full URLs.
+type Provider struct {
+	// Limiter limits the requests sent upstream.
+	Limiter *provider.Limiter
+}
+
+// Quote returns the quotes of the given symbols, one request per symbol.
+func (p *Provider) Quote(ctx context.Context, symbols []string) ([]provider.Quote, error) {
+	return provider.Each(ctx, symbols, func(ctx context.Context, symbol string) (provider.Quote, error) {
+		if err := p.Limiter.Allow(); err != nil {
+			return provider.Quote{}, fmt.Errorf("%s: %w", symbol, err)
+		}
+		body, err := provider.Get(ctx, pageURL(symbol), header)
+		if err != nil {
+			return provider.Quote{}, err
+		}
+		page := string(body)
+
+		m := priceRE.FindStringSubmatch(page)
+		if m == nil {
+			return provider.Quote{}, fmt.Errorf("no price on the investing.com page of %s (invalid symbol or page layout changed?)", symbol)
+		}
+		price, currency, err := scrape.ParsePrice(html.UnescapeString(m[1]))
+		if err != nil {
+			return provider.Quote{}, fmt.Errorf("invalid price for %s: %v", symbol, err)
+		}
+		q := provider.Quote{Symbol: symbol, Price: price, Currency: currency}
+		if m := currencyRE.FindStringSubmatch(page); m != nil {
+			q.Currency = m[1]
+		}
+		if m := changeRE.FindStringSubmatch(page); m != nil {
+			s := strings.Trim(tagRE.ReplaceAllString(html.UnescapeString(m[1]), ""), "()% ")
+			if v, _, err := scrape.ParsePrice(s); err == nil {
+				q.ChangePercent = v
+			}
+		}
+		return q, nil
+	})
+}
+
+// pageURL returns the URL of the page of a symbol.
+func pageURL(symbol string) string {
+	if strings.HasPrefix(symbol, "https://") || strings.HasPrefix(symbol, "http://") {
+		return symbol
+	}
+	return baseURL + strings.TrimPrefix(symbol, "/")
+}
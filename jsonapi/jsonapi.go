@@ -0,0 +1,155 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package jsonapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/marcopaganini/quotes-exporter/provider"
+	"github.com/marcopaganini/quotes-exporter/scrape"
+)
+
+// Config describes a JSON API.
+type Config struct {
+	// URL is the API URL. {symbol} is replaced by the symbol.
+	URL string `json:"url"`
+	// Headers are added to requests (e.g. Authorization).
+	Headers map[string]string `json:"headers"`
+	// Price is the JSONPath of the price.
+	Price string `json:"price"`
+	// Currency is the JSONPath of the currency, or a fixed ISO 4217 code.
+	Currency string `json:"currency"`
+	// Name is the JSONPath of the asset name.
+	Name string `json:"name"`
+}
+
+// Validate checks the configuration.
+func (c Config) Validate() error {
+	if c.URL == "" {
+		return errors.New("missing URL")
+	}
+	if c.Price == "" {
+		return errors.New("missing price path")
+	}
+	for _, expr := range []string{c.Price, c.Name, c.currencyPath()} {
+		if expr == "" {
+			continue
+		}
+		if _, err := ParsePath(expr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// currencyPath returns the JSONPath of the currency, if it is not fixed.
+func (c Config) currencyPath() string {
+	if strings.HasPrefix(c.Currency, "$") {
+		return c.Currency
+	}
+	return ""
+}
+
+// Provider is the JSON API provider, reading quotes from any API returning
+// JSON, as described in its configuration.
+type Provider struct {
+	Config Config
+	// Limiter limits the requests sent upstream.
+	Limiter *provider.Limiter
+}
+
+// Quote returns the quotes of the given symbols, one request per symbol.
+func (p *Provider) Quote(ctx context.Context, symbols []string) ([]provider.Quote, error) {
+	c := p.Config
+	if c.URL == "" {
+		return nil, errors.New("JSON API provider not configured")
+	}
+	pricePath, err := ParsePath(c.Price)
+	if err != nil {
+		return nil, err
+	}
+	header := http.Header{}
+	for k, v := range c.Headers {
+		header.Set(k, v)
+	}
+
+	return provider.Each(ctx, symbols, func(ctx context.Context, symbol string) (provider.Quote, error) {
+		if err := p.Limiter.Allow(); err != nil {
+			return provider.Quote{}, fmt.Errorf("%s: %w", symbol, err)
+		}
+		u := strings.Replace(c.URL, "{symbol}", url.PathEscape(symbol), -1)
+		var doc interface{}
+		if err := provider.GetJSON(ctx, u, header, &doc); err != nil {
+			return provider.Quote{}, err
+		}
+
+		v, ok := pricePath.Lookup(doc)
+		if !ok {
+			return provider.Quote{}, fmt.Errorf("no price at %s in the response for %s", c.Price, symbol)
+		}
+		price, currency, err := number(v)
+		if err != nil {
+			return provider.Quote{}, fmt.Errorf("invalid price for %s: %v", symbol, err)
+		}
+
+		q := provider.Quote{Symbol: symbol, Price: price, Currency: currency}
+		if c.Currency != "" && c.currencyPath() == "" {
+			q.Currency = strings.ToUpper(c.Currency)
+		} else if s, ok := lookupString(doc, c.currencyPath()); ok {
+			q.Currency = strings.ToUpper(s)
+		}
+		if s, ok := lookupString(doc, c.Name); ok {
+			q.Name = s
+		}
+		return q, nil
+	})
+}
+
+// number returns the value of a JSON number, or of a string holding a price
+// (and the currency found in it).
+func number(v interface{}) (float64, string, error) {
+	switch n := v.(type) {
+	case float64:
+		return n, "", nil
+	case string:
+		return scrape.ParsePrice(n)
+	}
+	return 0, "", fmt.Errorf("unexpected value %v", v)
+}
+
+// lookupString returns the value at a path as a string.
+func lookupString(doc interface{}, expr string) (string, bool) {
+	if expr == "" {
+		return "", false
+	}
+	path, err := ParsePath(expr)
+	if err != nil {
+		return "", false
+	}
+	v, ok := path.Lookup(doc)
+	if !ok || v == nil {
+		return "", false
+	}
+	switch s := v.(type) {
+	case string:
+		return s, s != ""
+	case float64:
+		return strconv.FormatFloat(s, 'f', -1, 64), true
+	}
+	return "", false
+}
@@ -0,0 +1,108 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package jsonapi
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// step is a step of a path: an object member or an array index.
+type step struct {
+	key   string
+	index int
+	// isIndex is true for array indexes.
+	isIndex bool
+}
+
+// Path is a parsed JSONPath expression. Only the child operators are
+// supported: $.data.quote[0].price, $['last price'].
+type Path []step
+
+// ParsePath parses a JSONPath expression.
+func ParsePath(expr string) (Path, error) {
+	s := strings.TrimSpace(expr)
+	s = strings.TrimPrefix(s, "$")
+
+	var path Path
+	for s != "" {
+		switch s[0] {
+		case '.':
+			s = s[1:]
+			n := strings.IndexAny(s, ".[")
+			if n < 0 {
+				n = len(s)
+			}
+			if n == 0 {
+				return nil, fmt.Errorf("empty member name in %q", expr)
+			}
+			path = append(path, step{key: s[:n]})
+			s = s[n:]
+
+		case '[':
+			end := strings.Index(s, "]")
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated [ in %q", expr)
+			}
+			inner := strings.TrimSpace(s[1:end])
+			s = s[end+1:]
+			if len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0] {
+				path = append(path, step{key: inner[1 : len(inner)-1]})
+				continue
+			}
+			i, err := strconv.Atoi(inner)
+			if err != nil {
+				return nil, fmt.Errorf("invalid index %q in %q", inner, expr)
+			}
+			path = append(path, step{index: i, isIndex: true})
+
+		default:
+			// A leading member name without a dot ("data.price").
+			if len(path) > 0 {
+				return nil, fmt.Errorf("unexpected %q in %q", s[0], expr)
+			}
+			s = "." + s
+		}
+	}
+	return path, nil
+}
+
+// Lookup returns the value at the path in a decoded JSON document.
+func (p Path) Lookup(v interface{}) (interface{}, bool) {
+	for _, st := range p {
+		if st.isIndex {
+			a, ok := v.([]interface{})
+			if !ok {
+				return nil, false
+			}
+			i := st.index
+			// Negative indexes count from the end.
+			if i < 0 {
+				i += len(a)
+			}
+			if i < 0 || i >= len(a) {
+				return nil, false
+			}
+			v = a[i]
+			continue
+		}
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if v, ok = m[st.key]; !ok {
+			return nil, false
+		}
+	}
+	return v, true
+}
@@ -0,0 +1,71 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// jsonSample is a single sample in the JSON output of /price.
+type jsonSample struct {
+	Metric string            `json:"metric"`
+	Labels map[string]string `json:"labels"`
+	Value  float64           `json:"value"`
+}
+
+// wantsJSON returns true if the client asked for JSON output, either with
+// ?format=json or an Accept header.
+func wantsJSON(r *http.Request) bool {
+	if f := r.URL.Query().Get("format"); f != "" {
+		return f == "json"
+	}
+	return strings.Contains(r.Header.Get("Accept"), "application/json")
+}
+
+// writeJSON gathers the metrics in a registry and writes the gauge and
+// counter samples as a JSON array.
+func writeJSON(w http.ResponseWriter, g prometheus.Gatherer) {
+	mfs, err := g.Gather()
+	if err != nil {
+		logger("web").Error("Error gathering metrics", "error", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	samples := []jsonSample{}
+	for _, mf := range mfs {
+		for _, m := range mf.GetMetric() {
+			s := jsonSample{Metric: mf.GetName(), Labels: map[string]string{}}
+			for _, lp := range m.GetLabel() {
+				s.Labels[lp.GetName()] = lp.GetValue()
+			}
+			switch {
+			case m.GetGauge() != nil:
+				s.Value = m.GetGauge().GetValue()
+			case m.GetCounter() != nil:
+				s.Value = m.GetCounter().GetValue()
+			default:
+				continue
+			}
+			samples = append(samples, s)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(samples); err != nil {
+		logger("web").Error("Error encoding JSON response", "error", err)
+	}
+}
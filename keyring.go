@@ -0,0 +1,88 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"sync"
+)
+
+// Key rotation strategies.
+const (
+	rotateRoundRobin = "round-robin"
+	rotateOnLimit    = "on-429"
+)
+
+// keyRotations holds the valid values of provider.key-rotation.
+var keyRotations = []string{rotateRoundRobin, rotateOnLimit}
+
+// keyRing holds several API keys of a provider, used in turn.
+type keyRing struct {
+	sync.Mutex
+	keys []*secret
+	next int
+	// onLimit keeps using a key until upstream rate limits it, instead of
+	// moving to the next key on every request.
+	onLimit bool
+}
+
+// Get returns the API key to use for the next request.
+func (r *keyRing) Get() string {
+	r.Lock()
+	defer r.Unlock()
+	k := r.keys[r.next%len(r.keys)]
+	if !r.onLimit {
+		r.next++
+	}
+	return k.Get()
+}
+
+// keyRings holds the key rings of providers with several API keys.
+var keyRings = struct {
+	sync.Mutex
+	rings map[string]*keyRing
+}{rings: map[string]*keyRing{}}
+
+// newKeyRing returns a key ring for the given secret specs.
+func newKeyRing(specs []string) (*keyRing, error) {
+	r := &keyRing{onLimit: cfg.KeyRotation == rotateOnLimit}
+	for _, spec := range specs {
+		s, err := newSecret(spec)
+		if err != nil {
+			return nil, err
+		}
+		r.keys = append(r.keys, s)
+	}
+	return r, nil
+}
+
+// setKeyRings replaces the key rings of all providers.
+func setKeyRings(rings map[string]*keyRing) {
+	keyRings.Lock()
+	defer keyRings.Unlock()
+	keyRings.rings = rings
+}
+
+// rotateKey moves a provider to its next API key after upstream rate limited
+// the current one. It returns false if the provider has a single key.
+func rotateKey(name string) bool {
+	keyRings.Lock()
+	r, ok := keyRings.rings[name]
+	keyRings.Unlock()
+	if !ok || len(r.keys) < 2 {
+		return false
+	}
+	r.Lock()
+	defer r.Unlock()
+	r.next++
+	logger("secrets").Info("Provider rate limited, rotating API key", "provider", name)
+	return true
+}
@@ -0,0 +1,199 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package localfile
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/marcopaganini/quotes-exporter/provider"
+	"github.com/marcopaganini/quotes-exporter/scrape"
+)
+
+// columns are the CSV columns of files without a header.
+var columns = []string{"symbol", "price", "name", "currency"}
+
+// entry is an entry of a JSON file. Prices may be numbers or strings.
+type entry struct {
+	Symbol   string      `json:"symbol"`
+	Price    interface{} `json:"price"`
+	Name     string      `json:"name"`
+	Currency string      `json:"currency"`
+}
+
+// Provider is the local file provider, for private assets (employer stock
+// plans, real estate estimates). It reads prices from a CSV file with
+// symbol, price, name and currency columns (with or without a header), or a
+// JSON array of objects with the same fields.
+type Provider struct {
+	// Path is the path of the file. Files ending in .json are read as JSON,
+	// others as CSV.
+	Path string
+	// Interval is how often to read the file again.
+	Interval time.Duration
+
+	mu     sync.Mutex
+	quotes map[string]provider.Quote
+	loaded time.Time
+	// path is the file the quotes were loaded from.
+	path string
+}
+
+// Quote returns the quotes of the given symbols found in the file.
+func (p *Provider) Quote(ctx context.Context, symbols []string) ([]provider.Quote, error) {
+	quotes, err := p.load()
+	if err != nil {
+		return nil, err
+	}
+	var ret []provider.Quote
+	for _, symbol := range symbols {
+		if q, ok := quotes[strings.ToUpper(symbol)]; ok {
+			q.Symbol = symbol
+			ret = append(ret, q)
+		}
+	}
+	return ret, nil
+}
+
+// load returns the quotes in the file, reading it again if the refresh
+// interval has passed. The previous quotes are kept if the file can't be
+// read.
+func (p *Provider) load() (map[string]provider.Quote, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.Path == "" {
+		return nil, errors.New("missing quotes file path")
+	}
+	if p.quotes != nil && p.path == p.Path && time.Since(p.loaded) < p.Interval {
+		return p.quotes, nil
+	}
+	quotes, err := read(p.Path)
+	if err != nil {
+		if p.quotes != nil && p.path == p.Path {
+			return p.quotes, nil
+		}
+		return nil, err
+	}
+	p.quotes, p.loaded, p.path = quotes, time.Now(), p.Path
+	return quotes, nil
+}
+
+// read reads a quotes file.
+func read(path string) (map[string]provider.Quote, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	st, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []entry
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		if err := json.Unmarshal(data, &entries); err != nil {
+			return nil, fmt.Errorf("error parsing %s: %v", path, err)
+		}
+	} else if entries, err = readCSV(data); err != nil {
+		return nil, fmt.Errorf("error parsing %s: %v", path, err)
+	}
+
+	quotes := map[string]provider.Quote{}
+	for i, e := range entries {
+		if e.Symbol == "" {
+			return nil, fmt.Errorf("%s: entry %d has no symbol", path, i+1)
+		}
+		var (
+			price    float64
+			currency string
+		)
+		switch v := e.Price.(type) {
+		case float64:
+			price = v
+		case string:
+			if price, currency, err = scrape.ParsePrice(v); err != nil {
+				return nil, fmt.Errorf("%s: invalid price of %s: %v", path, e.Symbol, err)
+			}
+		default:
+			return nil, fmt.Errorf("%s: invalid price of %s: %v", path, e.Symbol, e.Price)
+		}
+		if e.Currency != "" {
+			currency = strings.ToUpper(e.Currency)
+		}
+		quotes[strings.ToUpper(e.Symbol)] = provider.Quote{
+			Symbol:   e.Symbol,
+			Price:    price,
+			Currency: currency,
+			Name:     e.Name,
+			// The best guess of the time of the price.
+			Time: st.ModTime(),
+		}
+	}
+	return quotes, nil
+}
+
+// readCSV reads the entries of a CSV file. A first row without a valid
+// price is a header naming the columns.
+func readCSV(data []byte) ([]entry, error) {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = -1
+	r.Comment = '#'
+	r.TrimLeadingSpace = true
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	cols := columns
+	if len(records) > 0 && len(records[0]) > 1 {
+		if _, _, err := scrape.ParsePrice(records[0][1]); err != nil {
+			cols = nil
+			for _, c := range records[0] {
+				cols = append(cols, strings.ToLower(strings.TrimSpace(c)))
+			}
+			records = records[1:]
+		}
+	}
+
+	var entries []entry
+	for _, rec := range records {
+		var e entry
+		for i, v := range rec {
+			if i >= len(cols) {
+				break
+			}
+			v = strings.TrimSpace(v)
+			switch cols[i] {
+			case "symbol":
+				e.Symbol = v
+			case "price":
+				e.Price = v
+			case "name":
+				e.Name = v
+			case "currency":
+				e.Currency = v
+			}
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
@@ -0,0 +1,54 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// setupLogging installs the default slog logger according to the log level
+// and format flags.
+func setupLogging(level, format string) error {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return fmt.Errorf("invalid log level %q: %v", level, err)
+	}
+	opts := &slog.HandlerOptions{Level: lvl}
+
+	var h slog.Handler
+	switch strings.ToLower(format) {
+	case "text":
+		h = slog.NewTextHandler(os.Stderr, opts)
+	case "json":
+		h = slog.NewJSONHandler(os.Stderr, opts)
+	default:
+		return fmt.Errorf("invalid log format %q (valid: text,json)", format)
+	}
+	slog.SetDefault(slog.New(h))
+	return nil
+}
+
+// logger returns a logger tagging all messages with a component name. It
+// derives from the default logger at call time, so it always honors the
+// configuration installed by setupLogging.
+func logger(component string) *slog.Logger {
+	return slog.Default().With("component", component)
+}
+
+// fatal logs an error message and exits the program.
+func fatal(msg string, args ...interface{}) {
+	logger("main").Error(msg, args...)
+	os.Exit(1)
+}
@@ -12,35 +12,83 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"flag"
 	"fmt"
-	"log"
 	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/collectors"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/marcopaganini/quotes-exporter/provider"
+	"github.com/marcopaganini/quotes-exporter/yahoo"
 )
 
+// maxRequestBody is the maximum size of a POST request to /price.
+const maxRequestBody = 1 << 20
+
+// backfillDays is the number of days to backfill (backfill command only).
+var backfillDays int
+
 // priceHandler handles the "/price" endpoint. It creates a new collector with
 // the URL and a new prometheus registry to use that collector.
 func priceHandler(w http.ResponseWriter, r *http.Request) {
-	log.Printf("URL: %s\n", r.RequestURI)
+	log := logger("web").With("url", redactedURL(r), "remote", r.RemoteAddr)
+	log.Info("Received request")
 
-	collector, err := newCollector(r.URL)
+	var (
+		collector collector
+		err       error
+	)
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		collector, err = newCollector(r.URL)
+		if err != nil {
+			log.Error("Invalid request", "error", err)
+			return
+		}
+	case http.MethodPost:
+		collector, err = newCollectorFromJSON(http.MaxBytesReader(w, r.Body, maxRequestBody))
+		if err != nil {
+			log.Error("Invalid request", "error", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !authorizeSymbols(w, r, collector.symbols) {
+		return
+	}
+	collector.traceID = traceID(r)
+	collector.tokenProvider, collector.token, err = providerToken(r)
 	if err != nil {
-		log.Print(err)
+		log.Error("Invalid request", "error", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
+	// Quote metrics only. Exporter metrics are served on /metrics.
 	registry := prometheus.NewRegistry()
+	registry.MustRegister(collector)
 
-	// These will be collected every time the /stock or /fund endpoint is reached.
-	registry.MustRegister(
-		collector,
-		queryCount,
-		queryDuration,
-		errorCount)
+	if wantsJSON(r) {
+		writeJSON(w, registry)
+		return
+	}
 
 	// Delegate http serving to Promethues client library, which will call collector.Collect.
 	h := promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
@@ -51,7 +99,7 @@ func priceHandler(w http.ResponseWriter, r *http.Request) {
 func help(w http.ResponseWriter, r *http.Request) {
 	fmt.Fprintf(w, "<h1>Prometheus Quotes Exporter</h1>")
 	fmt.Fprintf(w, "<p>To fetch quotes, your URL must be formatted as:</p>")
-	fmt.Fprintf(w, "http://localhost:%d/price?symbols=AAAA,BBBB,CCCC", flagPort)
+	fmt.Fprintf(w, "http://localhost:%d/price?symbols=AAAA,BBBB,CCCC", cfg.Web.Port)
 	fmt.Fprintf(w, "<p><b>Examples:</b></p>")
 	fmt.Fprintf(w, "<ul>")
 
@@ -61,31 +109,445 @@ func help(w http.ResponseWriter, r *http.Request) {
 	}
 
 	for _, s := range symbols {
-		fmt.Fprintf(w, "<li><a href=\"http://localhost:%d/price?symbols=%s\">", flagPort, s)
-		fmt.Fprintf(w, "http://localhost:%d/price?symbols=%s</a></li>", flagPort, s)
+		fmt.Fprintf(w, "<li><a href=\"http://localhost:%d/price?symbols=%s\">", cfg.Web.Port, s)
+		fmt.Fprintf(w, "http://localhost:%d/price?symbols=%s</a></li>", cfg.Web.Port, s)
 	}
 }
 
-func main() {
-	flag.IntVar(&flagPort, "port", 9340, "Port to listen for HTTP requests.")
-	flag.Parse()
+// command holds a subcommand of the exporter.
+type command struct {
+	usage string
+	help  string
+	run   func(fs *flag.FlagSet) error
+}
 
-	reg := prometheus.NewRegistry()
+// commands maps subcommand names to their implementations. Running the
+// program without a subcommand is the same as running "serve".
+var commands = map[string]command{
+	"serve":        {"", "Serve quotes over HTTP (default).", serveCmd},
+	"get":          {"[SYMBOL...]", "Fetch quotes and print them in the Prometheus format.", getCmd},
+	"check-config": {"", "Validate the configuration and print the effective settings.", checkConfigCmd},
+	"bench":        {"[SYMBOL...]", "Scrape symbols (default: all watchlists) repeatedly and report latencies and upstream calls.", benchCmd},
+	"backfill":     {"[SYMBOL...]", "Save daily history for symbols (default: all watchlists) to the history store.", backfillCmd},
+	"providers":    {"", "List the quote providers and what they support.", providersCmd},
+}
 
-	// Add standard process and Go metrics.
+// serveCmd runs the HTTP server.
+func serveCmd(fs *flag.FlagSet) error {
+	// Exporter telemetry, served on /metrics. Quote metrics are served from
+	// per-request registries on /price.
+	reg := prometheus.NewRegistry()
 	reg.MustRegister(
 		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
 		collectors.NewGoCollector(),
+		queryCount,
+		queryDuration,
+		providerDuration,
+		errorCount,
+		cacheCollector{},
+		healthCollector{},
+		breakerCollector{},
+		throttleCollector{},
+		symbolsDropped,
 	)
 
+	// Internal endpoints (telemetry and admin) go on a separate listener if
+	// requested, so they're not exposed wherever quotes are.
+	mux := http.NewServeMux()
+	internal := mux
+	if cfg.Web.AdminAddress != "" {
+		internal = http.NewServeMux()
+	}
+
 	// Add handlers.
-	http.HandleFunc("/", help)
-	http.Handle("/metrics", promhttp.Handler())
+	mux.HandleFunc("/", help)
+	mux.HandleFunc("/price", priceHandler)
+	mux.HandleFunc("/sd", sdHandler)
+	mux.HandleFunc("/dividends", dividendsHandler)
+	mux.HandleFunc("/spark", sparkHandler)
+
+	// OpenMetrics is needed to expose exemplars.
+	internal.Handle("/metrics", promhttp.InstrumentMetricHandler(reg, promhttp.HandlerFor(reg, promhttp.HandlerOpts{EnableOpenMetrics: true})))
+	internal.HandleFunc("/healthz", healthHandler)
 
-	http.HandleFunc("/price", func(w http.ResponseWriter, r *http.Request) {
-		priceHandler(w, r)
+	if cfg.Web.AdminToken != "" {
+		var err error
+		if adminToken, err = newSecret(cfg.Web.AdminToken); err != nil {
+			return fmt.Errorf("admin token: %v", err)
+		}
+		setSecrets("admin", []*secret{adminToken})
+		internal.HandleFunc(watchlistPath, adminOnly(watchlistHandler))
+		internal.HandleFunc(watchlistPath+"/", adminOnly(watchlistHandler))
+		internal.HandleFunc(cachePath, adminOnly(cacheHandler))
+		internal.HandleFunc(cachePath+"/", adminOnly(cacheHandler))
+	}
+
+	if err := loadTenants(cfg.Tokens); err != nil {
+		return err
+	}
+
+	// Reloads replace cfg, so background tasks and listeners get their
+	// settings from a copy taken before reloads start, and read anything
+	// else under cfgMu.
+	c := cfg
+	if c.Secrets.RefreshInterval > 0 {
+		go refreshSecrets(c.Secrets.RefreshInterval)
+	}
+	if c.Earnings.Interval > 0 {
+		go refreshEarnings(c.Earnings.Interval)
+	}
+
+	errc := make(chan error, 3)
+	if c.Cache.File != "" {
+		if err := loadCacheFile(c.Cache.File); err != nil {
+			logger("cache").Error("Error loading cache, starting empty", "file", c.Cache.File, "error", err)
+		}
+		stop := make(chan os.Signal, 1)
+		signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+		go persistCache(c.Cache.File, c.Cache.SaveInterval, stop, errc)
+	}
+	if c.Cache.WarmUp {
+		go warmCache()
+	}
+	if c.Cache.RefreshAhead > 0 {
+		go refreshAhead(c.Cache.RefreshAhead)
+	}
+	if internal != mux {
+		go func() {
+			logger("main").Info("Listening for internal endpoints", "address", c.Web.AdminAddress)
+			errc <- http.ListenAndServe(c.Web.AdminAddress, withConfig(internal))
+		}()
+	}
+	go func() {
+		logger("main").Info("Listening", "port", c.Web.Port)
+		errc <- http.ListenAndServe(fmt.Sprintf(":%d", c.Web.Port), withConfig(mux))
+	}()
+	go handleReloads(c.Config.WatchInterval)
+	return <-errc
+}
+
+// healthHandler reports that the exporter is up.
+func healthHandler(w http.ResponseWriter, r *http.Request) {
+	fmt.Fprintln(w, "OK")
+}
+
+// getCmd fetches the symbols given as arguments and prints them to stdout.
+func getCmd(fs *flag.FlagSet) error {
+	symbols := fs.Args()
+	if len(symbols) == 0 {
+		return fmt.Errorf("no symbols to fetch")
+	}
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(collector{symbols: symbols})
+
+	// Use the same formatting as the HTTP endpoint.
+	req := httptest.NewRequest(http.MethodGet, "/price", nil)
+	rec := httptest.NewRecorder()
+	promhttp.HandlerFor(registry, promhttp.HandlerOpts{}).ServeHTTP(rec, req)
+
+	_, err := os.Stdout.Write(rec.Body.Bytes())
+	return err
+}
+
+// checkConfigCmd prints the effective configuration. Configuration errors are
+// detected before any command runs.
+func checkConfigCmd(fs *flag.FlagSet) error {
+	fs.VisitAll(func(f *flag.Flag) {
+		if f.Name != "port" {
+			fmt.Printf("%s=%s\n", f.Name, redactedValue(f))
+		}
 	})
+	var names []string
+	for name := range cfg.Watchlists {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Printf("watchlists.%s=%s\n", name, strings.Join(cfg.Watchlists[name], ","))
+	}
+	return nil
+}
 
-	log.Print("Listening on port ", flagPort)
-	log.Fatal(http.ListenAndServe(fmt.Sprintf(":%d", flagPort), nil))
+// backfillCmd fetches the daily history of the symbols given as arguments
+// (or all watchlist symbols) and saves it to the local history store.
+func backfillCmd(fs *flag.FlagSet) error {
+	if cfg.History.Dir == "" {
+		return fmt.Errorf("backfill requires a history store (-history.dir)")
+	}
+	symbols := fs.Args()
+	if len(symbols) == 0 {
+		symbols = watchlists.symbols()
+	}
+	if len(symbols) == 0 {
+		return fmt.Errorf("no symbols to backfill")
+	}
+
+	since := time.Now().AddDate(0, 0, -backfillDays)
+	var failed int
+	for _, symbol := range symbols {
+		log := logger("backfill").With("provider", "yahoo", "symbol", symbol)
+
+		ctx, cancel := upstreamContext(context.Background())
+		closes, err := yahoo.History(ctx, yahooSymbol(symbol), since)
+		cancel()
+		if err == nil && len(closes) < 2 {
+			err = fmt.Errorf("not enough history")
+		}
+		if err == nil {
+			// Skip the current (possibly ongoing) session.
+			err = saveHistory(symbol, closes[:len(closes)-1])
+		}
+		if err != nil {
+			failed++
+			log.Error("Error backfilling history", "error", err)
+			continue
+		}
+		log.Info("Backfilled history", "days", len(closes)-1)
+	}
+	if failed > 0 {
+		return fmt.Errorf("failed to backfill %d of %d symbols", failed, len(symbols))
+	}
+	return nil
+}
+
+// loadProviderSecrets resolves the configured provider credentials and
+// configures the providers using them.
+func loadProviderSecrets() error {
+	var list []*secret
+	figiKey = nil
+	if cfg.OpenFIGI.APIKey != "" {
+		var err error
+		if figiKey, err = newSecret(cfg.OpenFIGI.APIKey); err != nil {
+			return fmt.Errorf("OpenFIGI API key: %v", err)
+		}
+		list = append(list, figiKey)
+	}
+
+	// Providers with a name can rotate among several keys, set in the
+	// configuration file.
+	keys := []struct {
+		name     string
+		provider string
+		spec     string
+		key      *func() string
+	}{
+		{"Alpha Vantage", "alphavantage", cfg.AlphaVantage.Token, &alphaVantageProvider.Key},
+		{"Finnhub", "finnhub", cfg.Finnhub.Token, &finnhubProvider.Key},
+		{"Polygon.io", "polygon", cfg.Polygon.Token, &polygonProvider.Key},
+		{"Tiingo", "tiingo", cfg.Tiingo.Token, &tiingoProvider.Key},
+		{"Twelve Data", "twelvedata", cfg.TwelveData.Token, &twelveDataProvider.Key},
+		{"Marketstack", "marketstack", cfg.Marketstack.AccessKey, &marketstackProvider.Key},
+		{"EOD Historical Data", "eodhd", cfg.EODHD.Token, &eodhdProvider.Key},
+		{"Financial Modeling Prep", "fmp", cfg.FMP.Token, &fmpProvider.Key},
+		{"CoinGecko", "coingecko", cfg.CoinGecko.Token, &coinGeckoProvider.Key},
+		{"CoinMarketCap", "coinmarketcap", cfg.CoinMarketCap.Token, &coinMarketCapProvider.Key},
+		{"Open Exchange Rates", "openexchangerates", cfg.OpenExchangeRates.AppID, &openExchangeRatesProvider.Key},
+		{"Alpaca key ID", "", cfg.Alpaca.KeyID, &alpacaProvider.Key},
+		{"Alpaca secret key", "", cfg.Alpaca.SecretKey, &alpacaProvider.Secret},
+		{"Tradier", "tradier", cfg.Tradier.Token, &tradierProvider.Key},
+		{"Nasdaq Data Link", "nasdaqdatalink", cfg.NasdaqDataLink.Token, &nasdaqDataLinkProvider.Key},
+		{"FRED", "fred", cfg.FRED.Token, &fredProvider.Key},
+		{"brapi", "brapi", cfg.Brapi.Token, &brapiProvider.Key},
+		{"Metals-API", "metals", cfg.Metals.Token, &metalsProvider.Key},
+		{"Chainlink JSON-RPC URL", "", cfg.Chainlink.RPCURL, &chainlinkProvider.URL},
+		{"StockData.org", "stockdata", cfg.StockData.Token, &stockDataProvider.Key},
+	}
+	rings := map[string]*keyRing{}
+	for _, k := range keys {
+		if specs := cfg.APIKeys[k.provider]; k.provider != "" && len(specs) > 0 {
+			r, err := newKeyRing(specs)
+			if err != nil {
+				return fmt.Errorf("%s API keys: %v", k.name, err)
+			}
+			rings[k.provider] = r
+			list = append(list, r.keys...)
+			*k.key = r.Get
+			continue
+		}
+		if k.spec == "" {
+			*k.key = nil
+			continue
+		}
+		s, err := newSecret(k.spec)
+		if err != nil {
+			return fmt.Errorf("%s API key: %v", k.name, err)
+		}
+		list = append(list, s)
+		*k.key = s.Get
+	}
+	for name := range cfg.APIKeys {
+		if _, ok := rings[name]; !ok {
+			return fmt.Errorf("provider %q in api_keys doesn't rotate API keys", name)
+		}
+	}
+	setKeyRings(rings)
+	setSecrets("providers", list)
+
+	alphaVantageProvider.Limiter = providerLimiter("alphavantage", cfg.AlphaVantage.RequestsPerMinute)
+	finnhubProvider.Limiter = providerLimiter("finnhub", cfg.Finnhub.RequestsPerMinute)
+	polygonProvider.Limiter = providerLimiter("polygon", cfg.Polygon.RequestsPerMinute)
+	tiingoProvider.Limiter = providerLimiter("tiingo", cfg.Tiingo.RequestsPerMinute)
+	twelveDataProvider.Limiter = providerLimiter("twelvedata", cfg.TwelveData.RequestsPerMinute)
+	marketstackProvider.Limiter = providerLimiter("marketstack", cfg.Marketstack.RequestsPerMinute)
+	marketstackProvider.Intraday = cfg.Marketstack.Intraday
+	eodhdProvider.Limiter = providerLimiter("eodhd", cfg.EODHD.RequestsPerMinute)
+	eodhdProvider.Exchange = cfg.EODHD.Exchange
+	fmpProvider.Limiter = providerLimiter("fmp", cfg.FMP.RequestsPerMinute)
+	coinGeckoProvider.Limiter = providerLimiter("coingecko", cfg.CoinGecko.RequestsPerMinute)
+	coinGeckoProvider.Currency = cfg.CoinGecko.VsCurrency
+	coinMarketCapProvider.Limiter = providerLimiter("coinmarketcap", cfg.CoinMarketCap.RequestsPerMinute)
+	coinMarketCapProvider.Currency = cfg.CoinMarketCap.Convert
+	openExchangeRatesProvider.Limiter = providerLimiter("openexchangerates", cfg.OpenExchangeRates.RequestsPerMinute)
+	openExchangeRatesProvider.Base = cfg.OpenExchangeRates.Base
+	alpacaProvider.Limiter = providerLimiter("alpaca", cfg.Alpaca.RequestsPerMinute)
+	alpacaProvider.Feed = cfg.Alpaca.Feed
+	tradierProvider.Limiter = providerLimiter("tradier", cfg.Tradier.RequestsPerMinute)
+	tradierProvider.Sandbox = cfg.Tradier.Sandbox
+	nasdaqDataLinkProvider.Limiter = providerLimiter("nasdaqdatalink", cfg.NasdaqDataLink.RequestsPerMinute)
+	nasdaqDataLinkProvider.Datasets = cfg.Datasets
+	fredProvider.Limiter = providerLimiter("fred", cfg.FRED.RequestsPerMinute)
+	morningstarProvider.Limiter = providerLimiter("morningstar", cfg.Morningstar.RequestsPerMinute)
+	ftFundsProvider.Limiter = providerLimiter("ftfunds", cfg.FTFunds.RequestsPerMinute)
+	brapiProvider.Limiter = providerLimiter("brapi", cfg.Brapi.RequestsPerMinute)
+	nseProvider.Limiter = providerLimiter("nse", cfg.NSE.RequestsPerMinute)
+	tspProvider.Limiter = providerLimiter("tsp", cfg.TSP.RequestsPerMinute)
+	metalsProvider.Limiter = providerLimiter("metals", cfg.Metals.RequestsPerMinute)
+	metalsProvider.Currency = cfg.Metals.Currency
+	investingProvider.Limiter = providerLimiter("investing", cfg.Investing.RequestsPerMinute)
+	chainlinkProvider.Limiter = providerLimiter("chainlink", cfg.Chainlink.RequestsPerMinute)
+	chainlinkProvider.Feeds = cfg.ChainlinkFeeds
+	pythProvider.Limiter = providerLimiter("pyth", cfg.Pyth.RequestsPerMinute)
+	pythProvider.Endpoint = cfg.Pyth.Endpoint
+	jsonAPIProvider.Limiter = providerLimiter("jsonapi", cfg.JSONAPI.RequestsPerMinute)
+	jsonAPIProvider.Config = cfg.JSONAPIConfig
+	htmlProvider.Limiter = providerLimiter("html", cfg.HTML.RequestsPerMinute)
+	htmlProvider.Pages = cfg.HTMLPages
+	localFileProvider.Path = cfg.LocalFile.Path
+	localFileProvider.Interval = cfg.LocalFile.RefreshInterval
+	mockProvider.RandomWalk = cfg.Mock.RandomWalk
+	mockProvider.Volatility = cfg.Mock.Volatility
+	stockDataProvider.Limiter = providerLimiter("stockdata", cfg.StockData.RequestsPerMinute)
+	limitOtherProviders()
+
+	userAgents := cfg.UserAgents
+	if cfg.Upstream.UserAgent != "" {
+		userAgents = []string{cfg.Upstream.UserAgent}
+	}
+	header := http.Header{}
+	for k, v := range cfg.UpstreamHeaders {
+		header.Set(k, v)
+	}
+	provider.SetHeaders(userAgents, header)
+
+	var proxy *url.URL
+	if cfg.Proxy.URL != "" {
+		// Already checked by validate.
+		proxy, _ = url.Parse(cfg.Proxy.URL)
+	}
+	tlsConfig, err := upstreamTLSConfig()
+	if err != nil {
+		return err
+	}
+	provider.SetTransport(proxy, tlsConfig)
+	return nil
+}
+
+// tlsVersions maps the values of upstream.tls-min-version to TLS versions.
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// upstreamTLSConfig returns the TLS configuration of upstream requests, or
+// nil to use the defaults.
+func upstreamTLSConfig() (*tls.Config, error) {
+	u := cfg.Upstream
+	if u.CAFile == "" && u.TLSMinVersion == "" && !u.InsecureSkipVerify {
+		return nil, nil
+	}
+	c := &tls.Config{MinVersion: tlsVersions[u.TLSMinVersion]}
+	if u.CAFile != "" {
+		pem, err := os.ReadFile(u.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("CA bundle: %v", err)
+		}
+		// Add to the system CAs, so public upstreams keep working.
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("CA bundle: no certificates found in %s", u.CAFile)
+		}
+		c.RootCAs = pool
+	}
+	if u.InsecureSkipVerify {
+		logger("main").Warn("Upstream TLS certificate verification disabled; use for debugging only")
+		c.InsecureSkipVerify = true
+	}
+	return c, nil
+}
+
+// usage prints the program usage to stderr.
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s [COMMAND] [FLAGS] [ARGS]\n\nCommands:\n", filepath.Base(os.Args[0]))
+	var names []string
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-14s %s\n", name, commands[name].help)
+	}
+	fmt.Fprintf(os.Stderr, "\nRun '%s COMMAND -help' for the flags of each command.\n", filepath.Base(os.Args[0]))
+	fmt.Fprintf(os.Stderr, "Flags can also be set in the environment (e.g. %s) or the config file.\n", envName("web.port"))
+}
+
+func main() {
+	name, args := "serve", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+	cmd, ok := commands[name]
+	if !ok {
+		usage()
+		os.Exit(2)
+	}
+
+	fs := newFlagSet(name, &cfg)
+	if name == "backfill" {
+		fs.IntVar(&backfillDays, "days", 5*365, "Number of calendar days to backfill.")
+	}
+	if name == "bench" {
+		fs.IntVar(&benchConcurrency, "concurrency", 4, "Number of concurrent scrapes.")
+		fs.IntVar(&benchRequests, "requests", 100, "Total number of scrapes.")
+	}
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s %s [FLAGS] %s\n\n%s\n\nFlags:\n", filepath.Base(os.Args[0]), name, cmd.usage, cmd.help)
+		fs.PrintDefaults()
+	}
+
+	cmdArgs = args
+	if err := parseConfig(fs, &cfg, args); err != nil {
+		fatal("Invalid configuration", "error", err)
+	}
+	if err := setupLogging(cfg.Log.Level, cfg.Log.Format); err != nil {
+		fatal("Invalid logging configuration", "error", err)
+	}
+	setupCache()
+	setupHistograms()
+	if err := watchlists.load(cfg.Watchlists, cfg.Web.StateFile); err != nil {
+		fatal("Error loading watchlist state", "file", cfg.Web.StateFile, "error", err)
+	}
+
+	if err := loadProviderSecrets(); err != nil {
+		fatal("Error loading provider credentials", "error", err)
+	}
+
+	if err := cmd.run(fs); err != nil {
+		fatal("Error running command", "command", name, "error", err)
+	}
 }
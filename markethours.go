@@ -0,0 +1,144 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	// Trading hours must work without the system time zone database.
+	_ "time/tzdata"
+)
+
+// marketHours holds the regular trading hours of an exchange, on weekdays.
+type marketHours struct {
+	Timezone string `json:"timezone"`
+	Open     string `json:"open"`
+	Close    string `json:"close"`
+
+	loc         *time.Location
+	open, close time.Duration // Since midnight.
+}
+
+// defaultMarketHours maps Yahoo symbol suffixes (empty for US listings) to
+// the trading hours of their exchanges. Holidays are not taken into account.
+var defaultMarketHours = map[string]marketHours{
+	"":    {Timezone: "America/New_York", Open: "09:30", Close: "16:00"},
+	".L":  {Timezone: "Europe/London", Open: "08:00", Close: "16:30"},
+	".DE": {Timezone: "Europe/Berlin", Open: "09:00", Close: "17:30"},
+	".F":  {Timezone: "Europe/Berlin", Open: "08:00", Close: "22:00"},
+	".PA": {Timezone: "Europe/Paris", Open: "09:00", Close: "17:30"},
+	".AS": {Timezone: "Europe/Amsterdam", Open: "09:00", Close: "17:30"},
+	".MI": {Timezone: "Europe/Rome", Open: "09:00", Close: "17:30"},
+	".MC": {Timezone: "Europe/Madrid", Open: "09:00", Close: "17:30"},
+	".SW": {Timezone: "Europe/Zurich", Open: "09:00", Close: "17:30"},
+	".BR": {Timezone: "Europe/Brussels", Open: "09:00", Close: "17:30"},
+	".IR": {Timezone: "Europe/Dublin", Open: "08:00", Close: "16:30"},
+	".TO": {Timezone: "America/Toronto", Open: "09:30", Close: "16:00"},
+	".AX": {Timezone: "Australia/Sydney", Open: "10:00", Close: "16:00"},
+	".T":  {Timezone: "Asia/Tokyo", Open: "09:00", Close: "15:30"},
+	".HK": {Timezone: "Asia/Hong_Kong", Open: "09:30", Close: "16:00"},
+}
+
+// parse checks the trading hours and fills in the parsed fields.
+func (m *marketHours) parse() error {
+	var err error
+	if m.loc, err = time.LoadLocation(m.Timezone); err != nil {
+		return err
+	}
+	for _, t := range []struct {
+		s string
+		d *time.Duration
+	}{{m.Open, &m.open}, {m.Close, &m.close}} {
+		hm, err := time.Parse("15:04", t.s)
+		if err != nil {
+			return fmt.Errorf("invalid time %q (use HH:MM)", t.s)
+		}
+		*t.d = time.Duration(hm.Hour())*time.Hour + time.Duration(hm.Minute())*time.Minute
+	}
+	if m.open >= m.close {
+		return fmt.Errorf("market opens (%s) after it closes (%s)", m.Open, m.Close)
+	}
+	return nil
+}
+
+// parseMarketHours returns the default trading hours, replaced by those set
+// in the configuration file, keyed by symbol suffix.
+func parseMarketHours(hours map[string]marketHours) (map[string]marketHours, error) {
+	ret := map[string]marketHours{}
+	for _, mhs := range []map[string]marketHours{defaultMarketHours, hours} {
+		for suffix, m := range mhs {
+			if err := m.parse(); err != nil {
+				return nil, fmt.Errorf("market hours of %q: %v", suffix, err)
+			}
+			ret[strings.ToUpper(suffix)] = m
+		}
+	}
+	return ret, nil
+}
+
+// nextOpen returns when the market next opens after t, or the zero time if
+// it is open at t.
+func (m marketHours) nextOpen(t time.Time) time.Time {
+	t = t.In(m.loc)
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, m.loc)
+	for i := 0; i < 8; i++ {
+		d := day.AddDate(0, 0, i)
+		if d.Weekday() == time.Saturday || d.Weekday() == time.Sunday {
+			continue
+		}
+		open, close := d.Add(m.open), d.Add(m.close)
+		if i == 0 && !t.Before(open) && t.Before(close) {
+			return time.Time{}
+		}
+		if open.After(t) {
+			return open
+		}
+	}
+	return time.Time{}
+}
+
+// symbolMarket returns the trading hours of the exchange of a symbol, from
+// its Yahoo suffix, if known.
+func symbolMarket(symbol string) (marketHours, bool) {
+	symbol = strings.ToUpper(upstreamSymbol(symbol))
+	suffix := ""
+	if i := strings.LastIndex(symbol, "."); i > 0 {
+		suffix = symbol[i:]
+	}
+	m, ok := cfg.marketHours[suffix]
+	return m, ok
+}
+
+// closedMarketTTL returns how long to cache a quote of a symbol while its
+// market is closed: cache.closed-ttl, but no longer than until the market
+// opens again. Quotes that traded within the cache TTL (e.g. crypto or
+// currencies, trading around the clock) are not affected.
+func closedMarketTTL(symbol string, q quote) (time.Duration, bool) {
+	if cfg.Cache.ClosedTTL <= 0 || q.Time.IsZero() || q.fetched.Sub(q.Time) < cfg.Cache.TTL {
+		return 0, false
+	}
+	m, ok := symbolMarket(symbol)
+	if !ok {
+		return 0, false
+	}
+	now := time.Now()
+	open := m.nextOpen(now)
+	if open.IsZero() {
+		return 0, false
+	}
+	ttl := open.Sub(now)
+	if ttl > cfg.Cache.ClosedTTL {
+		ttl = cfg.Cache.ClosedTTL
+	}
+	return ttl, true
+}
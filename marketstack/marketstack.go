@@ -0,0 +1,140 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package marketstack
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/marcopaganini/quotes-exporter/provider"
+)
+
+const (
+	eodURL      = "https://api.marketstack.com/v1/eod/latest?access_key=%s&symbols=%s&limit=%d&offset=%d"
+	intradayURL = "https://api.marketstack.com/v1/intraday/latest?access_key=%s&symbols=%s&limit=%d&offset=%d"
+
+	// maxBatch is the maximum number of symbols (and results) per request.
+	maxBatch = 100
+
+	// timeFormat is the format of the dates returned by the API.
+	timeFormat = "2006-01-02T15:04:05-0700"
+)
+
+// response is the response of the end-of-day and intraday APIs.
+type response struct {
+	Pagination struct {
+		Offset int `json:"offset"`
+		Count  int `json:"count"`
+		Total  int `json:"total"`
+	} `json:"pagination"`
+	Data []struct {
+		Symbol string  `json:"symbol"`
+		Open   float64 `json:"open"`
+		High   float64 `json:"high"`
+		Low    float64 `json:"low"`
+		Close  float64 `json:"close"`
+		// Last is only returned by the intraday API.
+		Last float64 `json:"last"`
+		Date string  `json:"date"`
+	} `json:"data"`
+	Error *struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// Provider is the Marketstack quote provider.
+type Provider struct {
+	// Key returns the API access key.
+	Key func() string
+	// Intraday selects the intraday API (paid plans) instead of the latest
+	// end-of-day prices.
+	Intraday bool
+	// Limiter limits the requests sent upstream.
+	Limiter *provider.Limiter
+}
+
+// Quote returns the quotes of the given symbols, in batches of up to 100
+// symbols. Results spanning more than one page are fetched page by page.
+func (p *Provider) Quote(ctx context.Context, symbols []string) ([]provider.Quote, error) {
+	var key string
+	if p.Key != nil {
+		key = p.Key()
+	}
+	key = provider.Token(ctx, key)
+	if key == "" {
+		return nil, errors.New("missing Marketstack access key")
+	}
+
+	var ret []provider.Quote
+	for len(symbols) > 0 {
+		n := len(symbols)
+		if n > maxBatch {
+			n = maxBatch
+		}
+		quotes, err := p.batch(ctx, symbols[:n], key)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, quotes...)
+		symbols = symbols[n:]
+	}
+	return ret, nil
+}
+
+// batch returns the quotes of a batch of symbols, following pagination.
+func (p *Provider) batch(ctx context.Context, symbols []string, key string) ([]provider.Quote, error) {
+	base := eodURL
+	if p.Intraday {
+		base = intradayURL
+	}
+	syms := url.QueryEscape(strings.ToUpper(strings.Join(symbols, ",")))
+
+	var ret []provider.Quote
+	for offset := 0; ; {
+		if err := p.Limiter.Allow(); err != nil {
+			return nil, err
+		}
+		var resp response
+		if err := provider.GetJSON(ctx, fmt.Sprintf(base, url.QueryEscape(key), syms, maxBatch, offset), nil, &resp); err != nil {
+			return nil, err
+		}
+		if resp.Error != nil {
+			return nil, fmt.Errorf("upstream error: %s: %s", resp.Error.Code, resp.Error.Message)
+		}
+
+		for _, d := range resp.Data {
+			price := d.Close
+			if p.Intraday && d.Last != 0 {
+				price = d.Last
+			}
+			if price == 0 {
+				continue
+			}
+			q := provider.Quote{Symbol: d.Symbol, Price: price, Open: d.Open, High: d.High, Low: d.Low}
+			// End-of-day dates are midnight, not the time of the trade.
+			if p.Intraday {
+				q.Time, _ = time.Parse(timeFormat, d.Date)
+			}
+			ret = append(ret, q)
+		}
+
+		offset = resp.Pagination.Offset + resp.Pagination.Count
+		if resp.Pagination.Count == 0 || offset >= resp.Pagination.Total {
+			return ret, nil
+		}
+	}
+}
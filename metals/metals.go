@@ -0,0 +1,162 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package metals
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/marcopaganini/quotes-exporter/provider"
+)
+
+const (
+	latestURL = "https://metals-api.com/api/latest?access_key=%s&base=%s&symbols=%s"
+)
+
+// metals maps metal codes to their names.
+var metals = map[string]string{
+	"XAU": "gold",
+	"XAG": "silver",
+	"XPT": "platinum",
+	"XPD": "palladium",
+}
+
+// latestResponse is the response of the latest rates API.
+type latestResponse struct {
+	Success   bool               `json:"success"`
+	Timestamp int64              `json:"timestamp"`
+	Rates     map[string]float64 `json:"rates"`
+	Error     struct {
+		Info string `json:"info"`
+	} `json:"error"`
+}
+
+// Provider is the Metals-API precious metals provider. Symbols are metal codes
+// or names (XAU, gold), optionally followed by the currency to quote them in
+// (XAU/EUR, XAGEUR, gold/GBP). Prices are per troy ounce.
+type Provider struct {
+	// Key returns the access key.
+	Key func() string
+	// Currency is the currency of symbols without one.
+	Currency string
+	// Limiter limits the requests sent upstream.
+	Limiter *provider.Limiter
+}
+
+// Quote returns the spot prices of the given symbols, with one request per
+// currency.
+func (p *Provider) Quote(ctx context.Context, symbols []string) ([]provider.Quote, error) {
+	var key string
+	if p.Key != nil {
+		key = p.Key()
+	}
+	key = provider.Token(ctx, key)
+	if key == "" {
+		return nil, errors.New("missing Metals-API access key")
+	}
+
+	// Group the metals by currency.
+	byCurrency := map[string][]string{}
+	var currencies []string
+	for _, symbol := range symbols {
+		code, currency, ok := parse(symbol)
+		if !ok {
+			continue
+		}
+		if currency == "" {
+			currency = p.currency()
+		}
+		if _, ok := byCurrency[currency]; !ok {
+			currencies = append(currencies, currency)
+		}
+		byCurrency[currency] = append(byCurrency[currency], code)
+	}
+
+	rates := map[string]latestResponse{}
+	for _, currency := range currencies {
+		if err := p.Limiter.Allow(); err != nil {
+			return nil, err
+		}
+		var resp latestResponse
+		u := fmt.Sprintf(latestURL, url.QueryEscape(key), url.QueryEscape(currency), url.QueryEscape(strings.Join(byCurrency[currency], ",")))
+		if err := provider.GetJSON(ctx, u, nil, &resp); err != nil {
+			return nil, err
+		}
+		if !resp.Success {
+			return nil, fmt.Errorf("error from Metals-API: %s", resp.Error.Info)
+		}
+		rates[currency] = resp
+	}
+
+	var ret []provider.Quote
+	for _, symbol := range symbols {
+		code, currency, ok := parse(symbol)
+		if !ok {
+			continue
+		}
+		if currency == "" {
+			currency = p.currency()
+		}
+		resp := rates[currency]
+		// Rates are in ounces of metal per unit of currency.
+		rate := resp.Rates[code]
+		if rate == 0 {
+			continue
+		}
+		ret = append(ret, provider.Quote{
+			Symbol:   symbol,
+			Price:    1 / rate,
+			Currency: currency,
+			Time:     time.Unix(resp.Timestamp, 0),
+		})
+	}
+	return ret, nil
+}
+
+// currency returns the default currency.
+func (p *Provider) currency() string {
+	if p.Currency == "" {
+		return "USD"
+	}
+	return strings.ToUpper(p.Currency)
+}
+
+// parse returns the metal code and currency (empty if not given) of a symbol.
+func parse(symbol string) (code, currency string, ok bool) {
+	s := strings.ToUpper(strings.TrimSpace(symbol))
+	if i := strings.IndexAny(s, "/:"); i >= 0 {
+		s, currency = s[:i], s[i+1:]
+	} else if len(s) == 6 {
+		if _, ok := metals[s[:3]]; ok {
+			s, currency = s[:3], s[3:]
+		}
+	}
+	for c, name := range metals {
+		if s == c || s == strings.ToUpper(name) {
+			return c, currency, true
+		}
+	}
+	return "", "", false
+}
+
+// Labels returns the metal label of a symbol.
+func Labels(symbol string) map[string]string {
+	code, _, ok := parse(symbol)
+	if !ok {
+		return nil
+	}
+	return map[string]string{"metal": metals[code]}
+}
@@ -0,0 +1,100 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package mock
+
+import (
+	"context"
+	"hash/fnv"
+	"math"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/marcopaganini/quotes-exporter/provider"
+)
+
+// walk is the random walk of the price of a symbol.
+type walk struct {
+	rand  *rand.Rand
+	price float64
+}
+
+// Provider is the mock provider, returning synthetic prices for testing
+// dashboards and alerts without upstream requests. The price of a symbol is
+// derived from its name, so it is the same across runs. With RandomWalk set,
+// prices move on each request, following the same path on every run.
+type Provider struct {
+	// RandomWalk makes prices move on each request.
+	RandomWalk bool
+	// Volatility is the standard deviation of each move, as a fraction of
+	// the price (e.g. 0.01 for 1%).
+	Volatility float64
+
+	mu    sync.Mutex
+	walks map[string]*walk
+}
+
+// Quote returns synthetic quotes of the given symbols.
+func (p *Provider) Quote(ctx context.Context, symbols []string) ([]provider.Quote, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	var ret []provider.Quote
+	for _, symbol := range symbols {
+		seed := symbolSeed(symbol)
+		base := basePrice(seed)
+		price := base
+		if p.RandomWalk {
+			price = p.step(symbol, seed, base)
+		}
+		ret = append(ret, provider.Quote{
+			Symbol:        symbol,
+			Price:         price,
+			Currency:      "USD",
+			Time:          now,
+			PreviousClose: base,
+			ChangePercent: (price - base) / base * 100,
+		})
+	}
+	return ret, nil
+}
+
+// step moves the price of a symbol one step and returns it. Must be called
+// with the lock held.
+func (p *Provider) step(symbol string, seed int64, base float64) float64 {
+	key := strings.ToUpper(symbol)
+	if p.walks == nil {
+		p.walks = map[string]*walk{}
+	}
+	w, ok := p.walks[key]
+	if !ok {
+		w = &walk{rand: rand.New(rand.NewSource(seed)), price: base}
+		p.walks[key] = w
+	}
+	w.price *= math.Exp(w.rand.NormFloat64() * p.Volatility)
+	return w.price
+}
+
+// symbolSeed returns the seed of the prices of a symbol.
+func symbolSeed(symbol string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(strings.ToUpper(symbol)))
+	return int64(h.Sum64() >> 1)
+}
+
+// basePrice returns a price between 10 and 1000, spread evenly on a log scale.
+func basePrice(seed int64) float64 {
+	f := float64(seed%10000) / 10000
+	return math.Round(math.Pow(10, 1+2*f)*100) / 100
+}
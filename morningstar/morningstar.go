@@ -0,0 +1,75 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package morningstar
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/marcopaganini/quotes-exporter/provider"
+)
+
+// screenerURL is the public fund screener used by Morningstar's own web
+// widgets. It searches by ticker, ISIN or name.
+const screenerURL = "https://lt.morningstar.com/api/rest.svc/klr5zyak8x/security/screener?page=1&pageSize=5&outputType=json&universeIds=FOALL%%24%%24ALL&securityDataPoints=SecId%%7CName%%7CTicker%%7Cisin%%7CClosePrice%%7CClosePriceDate%%7CPriceCurrency&term=%s"
+
+// screenerResponse is the response of the screener API.
+type screenerResponse struct {
+	Rows []struct {
+		Name           string  `json:"Name"`
+		Ticker         string  `json:"Ticker"`
+		ISIN           string  `json:"isin"`
+		ClosePrice     float64 `json:"ClosePrice"`
+		ClosePriceDate string  `json:"ClosePriceDate"`
+		PriceCurrency  string  `json:"PriceCurrency"`
+	} `json:"rows"`
+}
+
+// Provider is the Morningstar mutual fund NAV provider. Symbols are fund
+// tickers (VTIAX) or ISINs.
+type Provider struct {
+	// Limiter limits the requests sent upstream.
+	Limiter *provider.Limiter
+}
+
+// Quote returns the latest NAV of the given funds, one request per fund. The
+// quote time is the NAV date.
+func (p *Provider) Quote(ctx context.Context, symbols []string) ([]provider.Quote, error) {
+	return provider.Each(ctx, symbols, func(ctx context.Context, symbol string) (provider.Quote, error) {
+		if err := p.Limiter.Allow(); err != nil {
+			return provider.Quote{}, fmt.Errorf("%s: %w", symbol, err)
+		}
+
+		var resp screenerResponse
+		if err := provider.GetJSON(ctx, fmt.Sprintf(screenerURL, url.QueryEscape(symbol)), nil, &resp); err != nil {
+			return provider.Quote{}, err
+		}
+		// The search is fuzzy, so only accept exact ticker or ISIN matches.
+		for _, r := range resp.Rows {
+			if !strings.EqualFold(r.Ticker, symbol) && !strings.EqualFold(r.ISIN, symbol) {
+				continue
+			}
+			if r.ClosePrice == 0 {
+				break
+			}
+			q := provider.Quote{Symbol: symbol, Price: r.ClosePrice, Currency: r.PriceCurrency}
+			if t, err := time.Parse("2006-01-02T15:04:05", r.ClosePriceDate); err == nil {
+				q.Time = t
+			}
+			return q, nil
+		}
+		return provider.Quote{}, fmt.Errorf("no NAV for %s (unknown fund?)", symbol)
+	})
+}
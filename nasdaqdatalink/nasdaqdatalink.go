@@ -0,0 +1,117 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package nasdaqdatalink
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/marcopaganini/quotes-exporter/provider"
+)
+
+const datasetURL = "https://data.nasdaq.com/api/v3/datasets/%s.json?rows=1&api_key=%s"
+
+// Dataset maps a symbol to a column of a Nasdaq Data Link time series.
+type Dataset struct {
+	// Code is the dataset code (e.g. LBMA/GOLD).
+	Code string `json:"code"`
+	// Column is the column holding the value (default: the first column
+	// after the date).
+	Column string `json:"column"`
+	// Currency of the value, if any.
+	Currency string `json:"currency"`
+}
+
+// datasetResponse is the response of the datasets API.
+type datasetResponse struct {
+	Dataset struct {
+		ColumnNames []string        `json:"column_names"`
+		Data        [][]interface{} `json:"data"`
+	} `json:"dataset"`
+}
+
+// Provider is the Nasdaq Data Link (formerly Quandl) provider. Symbols are
+// the names of configured datasets, or dataset codes (LBMA/GOLD).
+type Provider struct {
+	// Key returns the API key.
+	Key func() string
+	// Datasets maps symbols to datasets.
+	Datasets map[string]Dataset
+	// Limiter limits the requests sent upstream.
+	Limiter *provider.Limiter
+}
+
+// Quote returns the latest value of the given datasets, one request per
+// dataset.
+func (p *Provider) Quote(ctx context.Context, symbols []string) ([]provider.Quote, error) {
+	var key string
+	if p.Key != nil {
+		key = p.Key()
+	}
+	key = provider.Token(ctx, key)
+	if key == "" {
+		return nil, errors.New("missing Nasdaq Data Link API key")
+	}
+
+	return provider.Each(ctx, symbols, func(ctx context.Context, symbol string) (provider.Quote, error) {
+		ds, ok := p.Datasets[symbol]
+		if !ok {
+			ds = Dataset{Code: strings.ToUpper(symbol)}
+		}
+		if err := p.Limiter.Allow(); err != nil {
+			return provider.Quote{}, fmt.Errorf("%s: %w", symbol, err)
+		}
+
+		var resp datasetResponse
+		u := fmt.Sprintf(datasetURL, ds.Code, url.QueryEscape(key))
+		if err := provider.GetJSON(ctx, u, nil, &resp); err != nil {
+			return provider.Quote{}, err
+		}
+		v, err := value(resp, ds.Column)
+		if err != nil {
+			return provider.Quote{}, fmt.Errorf("dataset %s: %v", ds.Code, err)
+		}
+		return provider.Quote{Symbol: symbol, Price: v, Currency: ds.Currency}, nil
+	})
+}
+
+// value returns the value of a column in the latest row of a dataset.
+func value(resp datasetResponse, column string) (float64, error) {
+	d := resp.Dataset
+	if len(d.Data) == 0 {
+		return 0, errors.New("no data")
+	}
+	idx := 1
+	if column != "" {
+		idx = -1
+		for i, name := range d.ColumnNames {
+			if strings.EqualFold(name, column) {
+				idx = i
+			}
+		}
+		if idx < 0 {
+			return 0, fmt.Errorf("unknown column %q (valid: %s)", column, strings.Join(d.ColumnNames, ","))
+		}
+	}
+	row := d.Data[0]
+	if idx >= len(row) {
+		return 0, fmt.Errorf("missing column %d", idx)
+	}
+	v, ok := row[idx].(float64)
+	if !ok || v == 0 {
+		return 0, fmt.Errorf("no value in column %d", idx)
+	}
+	return v, nil
+}
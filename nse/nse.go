@@ -0,0 +1,147 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package nse
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/marcopaganini/quotes-exporter/provider"
+)
+
+const (
+	// Visiting the home page sets the cookies required by the API.
+	homeURL  = "https://www.nseindia.com/"
+	quoteURL = "https://www.nseindia.com/api/quote-equity?symbol=%s"
+
+	// NSE rejects requests without a browser User-Agent.
+	userAgent = "Mozilla/5.0 (X11; Linux x86_64; rv:109.0) Gecko/20100101 Firefox/115.0"
+)
+
+// ist is the time zone of the timestamps returned by NSE.
+var ist = time.FixedZone("IST", 5*3600+1800)
+
+// quoteResponse is the response of the equity quote API.
+type quoteResponse struct {
+	Info struct {
+		Symbol string `json:"symbol"`
+	} `json:"info"`
+	Metadata struct {
+		LastUpdateTime string `json:"lastUpdateTime"`
+	} `json:"metadata"`
+	PriceInfo struct {
+		LastPrice       float64 `json:"lastPrice"`
+		Open            float64 `json:"open"`
+		PreviousClose   float64 `json:"previousClose"`
+		PChange         float64 `json:"pChange"`
+		IntraDayHighLow struct {
+			Min float64 `json:"min"`
+			Max float64 `json:"max"`
+		} `json:"intraDayHighLow"`
+	} `json:"priceInfo"`
+}
+
+// Provider is the NSE (National Stock Exchange of India) provider. Symbols
+// are NSE tickers (RELIANCE), with or without the Yahoo ".NS" suffix.
+type Provider struct {
+	// Limiter limits the requests sent upstream.
+	Limiter *provider.Limiter
+
+	mu     sync.Mutex
+	client *http.Client
+}
+
+// Quote returns the quotes of the given symbols, one request per symbol.
+func (p *Provider) Quote(ctx context.Context, symbols []string) ([]provider.Quote, error) {
+	return provider.Each(ctx, symbols, func(ctx context.Context, symbol string) (provider.Quote, error) {
+		if err := p.Limiter.Allow(); err != nil {
+			return provider.Quote{}, fmt.Errorf("%s: %w", symbol, err)
+		}
+		ticker := strings.TrimSuffix(strings.ToUpper(symbol), ".NS")
+
+		var resp quoteResponse
+		if err := p.getJSON(ctx, fmt.Sprintf(quoteURL, url.QueryEscape(ticker)), &resp); err != nil {
+			return provider.Quote{}, err
+		}
+		pi := resp.PriceInfo
+		if pi.LastPrice == 0 {
+			return provider.Quote{}, fmt.Errorf("%w: %s", provider.ErrNotFound, ticker)
+		}
+
+		q := provider.Quote{
+			Symbol:        symbol,
+			Price:         pi.LastPrice,
+			Currency:      "INR",
+			Open:          pi.Open,
+			High:          pi.IntraDayHighLow.Max,
+			Low:           pi.IntraDayHighLow.Min,
+			PreviousClose: pi.PreviousClose,
+			ChangePercent: pi.PChange,
+		}
+		if t, err := time.ParseInLocation("02-Jan-2006 15:04:05", resp.Metadata.LastUpdateTime, ist); err == nil {
+			q.Time = t
+		}
+		return q, nil
+	})
+}
+
+// getJSON fetches an API URL with the session cookies, starting a new session
+// when there is none or the current one is rejected.
+func (p *Provider) getJSON(ctx context.Context, u string, v interface{}) error {
+	header := http.Header{
+		"User-Agent":      []string{userAgent},
+		"Accept":          []string{"application/json"},
+		"Accept-Language": []string{"en-US,en;q=0.9"},
+		"Referer":         []string{homeURL},
+	}
+
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		var client *http.Client
+		if client, err = p.session(ctx, attempt > 0); err != nil {
+			return err
+		}
+		err = provider.GetJSONWith(ctx, client, u, header, v)
+		if code := provider.StatusCode(err); code != http.StatusUnauthorized && code != http.StatusForbidden {
+			return err
+		}
+	}
+	return err
+}
+
+// session returns a client holding the NSE cookies, visiting the home page
+// to obtain them if needed (or if renew is set).
+func (p *Provider) session(ctx context.Context, renew bool) (*http.Client, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.client != nil && !renew {
+		return p.client, nil
+	}
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, err
+	}
+	client := &http.Client{Jar: jar, Transport: provider.Client.Transport}
+	header := http.Header{"User-Agent": []string{userAgent}}
+	if _, err := provider.GetWith(ctx, client, homeURL, header); err != nil {
+		return nil, fmt.Errorf("unable to start NSE session: %v", err)
+	}
+	p.client = client
+	return client, nil
+}
@@ -0,0 +1,89 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package openfigi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/marcopaganini/quotes-exporter/provider"
+)
+
+const (
+	mappingURL = "https://api.openfigi.com/v3/mapping"
+
+	// Identifier types.
+	ISIN  = "ID_ISIN"
+	CUSIP = "ID_CUSIP"
+)
+
+// Listing is an instrument listed on an exchange.
+type Listing struct {
+	FIGI         string `json:"figi"`
+	Name         string `json:"name"`
+	Ticker       string `json:"ticker"`
+	ExchangeCode string `json:"exchCode"`
+	SecurityType string `json:"securityType"`
+}
+
+// Map returns the listings of the instrument with the given identifier. The
+// API key is optional, but raises the rate limits.
+func Map(ctx context.Context, idType, id, apiKey string) ([]Listing, error) {
+	body, err := json.Marshal([]map[string]string{{"idType": idType, "idValue": id}})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(http.MethodPost, mappingURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	if apiKey != "" {
+		req.Header.Set("X-OPENFIGI-APIKEY", apiKey)
+	}
+
+	resp, err := provider.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("upstream returned HTTP %d", resp.StatusCode)
+	}
+
+	// One result per job in the request.
+	var results []struct {
+		Data    []Listing `json:"data"`
+		Warning string    `json:"warning"`
+		Error   string    `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&results); err != nil {
+		return nil, fmt.Errorf("error decoding mapping data: %v", err)
+	}
+	if len(results) == 0 {
+		return nil, fmt.Errorf("empty mapping results for %s", id)
+	}
+	r := results[0]
+	switch {
+	case r.Error != "":
+		return nil, fmt.Errorf("upstream error: %s", r.Error)
+	case len(r.Data) == 0 && r.Warning != "":
+		return nil, fmt.Errorf("%s: %s", id, r.Warning)
+	case len(r.Data) == 0:
+		return nil, fmt.Errorf("no listings for %s", id)
+	}
+	return r.Data, nil
+}
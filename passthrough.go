@@ -0,0 +1,98 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/marcopaganini/quotes-exporter/provider"
+)
+
+const (
+	// providerTokenHeader holds a provider API token for a single request.
+	providerTokenHeader = "X-Provider-Token"
+	// providerTokenParam is the query parameter alternative to the header.
+	providerTokenParam = "token"
+)
+
+// tokenKey is the context key for the provider token of a request.
+type tokenKey struct{}
+
+// requestToken is a provider API token passed in a request, and the provider
+// it is meant for.
+type requestToken struct {
+	provider string
+	token    string
+}
+
+// providerToken returns the provider API token passed in a request, if any,
+// and the provider it is meant for. The token replaces the configured
+// credentials of that provider for that request only, so users of a shared
+// exporter consume their own quotas. Tokens must name their provider with
+// ?provider=, so they're never sent to other providers.
+func providerToken(r *http.Request) (string, string, error) {
+	token := r.Header.Get(providerTokenHeader)
+	if token == "" {
+		token = r.URL.Query().Get(providerTokenParam)
+	}
+	if token == "" {
+		return "", "", nil
+	}
+	pname := r.URL.Query().Get("provider")
+	if pname == "" {
+		return "", "", fmt.Errorf("provider tokens require the provider they're meant for (?provider=NAME)")
+	}
+	if !containsFold(providerNames(), pname) {
+		return "", "", fmt.Errorf("unknown provider %q (valid: %s)", pname, strings.Join(providerNames(), ","))
+	}
+	return strings.ToLower(pname), token, nil
+}
+
+// withProviderToken returns a context carrying the provider token of a
+// request, for the named provider only. An empty token removes any token.
+func withProviderToken(ctx context.Context, pname, token string) context.Context {
+	return context.WithValue(ctx, tokenKey{}, requestToken{provider: pname, token: token})
+}
+
+// tokenFor returns the request token in ctx meant for the named provider, if
+// any.
+func tokenFor(ctx context.Context, pname string) string {
+	if t, ok := ctx.Value(tokenKey{}).(requestToken); ok && t.provider == pname {
+		return t.token
+	}
+	return ""
+}
+
+// providerContext returns the context of a request to the named provider,
+// passing it the request token only if the token is meant for it.
+func providerContext(ctx context.Context, pname string) context.Context {
+	if token := tokenFor(ctx, pname); token != "" {
+		return provider.WithToken(ctx, token)
+	}
+	return ctx
+}
+
+// redactedURL returns the request URL with any provider token removed, for
+// logging.
+func redactedURL(r *http.Request) string {
+	q := r.URL.Query()
+	if q.Get(providerTokenParam) == "" {
+		return r.RequestURI
+	}
+	q.Set(providerTokenParam, "REDACTED")
+	u := url.URL{Path: r.URL.Path, RawQuery: q.Encode()}
+	return u.String()
+}
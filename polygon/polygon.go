@@ -0,0 +1,153 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package polygon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/marcopaganini/quotes-exporter/provider"
+)
+
+const (
+	snapshotURL  = "https://api.polygon.io/v2/snapshot/locale/us/markets/stocks/tickers?tickers=%s"
+	lastTradeURL = "https://api.polygon.io/v2/last/trade/%s"
+)
+
+// bar holds the daily aggregates of a ticker.
+type bar struct {
+	Open  float64 `json:"o"`
+	High  float64 `json:"h"`
+	Low   float64 `json:"l"`
+	Close float64 `json:"c"`
+}
+
+// trade holds a trade. Times are in nanoseconds since the epoch.
+type trade struct {
+	Price float64 `json:"p"`
+	Time  int64   `json:"t"`
+}
+
+// snapshotResponse is the response of the tickers snapshot API.
+type snapshotResponse struct {
+	Status  string `json:"status"`
+	Tickers []struct {
+		Ticker    string `json:"ticker"`
+		Day       bar    `json:"day"`
+		PrevDay   bar    `json:"prevDay"`
+		LastTrade trade  `json:"lastTrade"`
+	} `json:"tickers"`
+}
+
+// lastTradeResponse is the response of the last trade API.
+type lastTradeResponse struct {
+	Status  string `json:"status"`
+	Results trade  `json:"results"`
+}
+
+// Provider is the Polygon.io quote provider.
+type Provider struct {
+	// Key returns the API key.
+	Key func() string
+	// Limiter limits the requests sent upstream. Limits depend on the plan
+	// of the key (the free tier allows 5 requests per minute).
+	Limiter *provider.Limiter
+}
+
+// Quote returns the quotes of the given symbols. All symbols are fetched in
+// one snapshot request. Keys without access to snapshots fall back to the
+// last trade of each symbol.
+func (p *Provider) Quote(ctx context.Context, symbols []string) ([]provider.Quote, error) {
+	var key string
+	if p.Key != nil {
+		key = p.Key()
+	}
+	key = provider.Token(ctx, key)
+	if key == "" {
+		return nil, errors.New("missing Polygon.io API key")
+	}
+	header := http.Header{"Authorization": []string{"Bearer " + key}}
+
+	quotes, err := p.snapshot(ctx, symbols, header)
+	if provider.StatusCode(err) != http.StatusForbidden {
+		return quotes, err
+	}
+	return provider.Each(ctx, symbols, func(ctx context.Context, symbol string) (provider.Quote, error) {
+		return p.lastTrade(ctx, symbol, header)
+	})
+}
+
+// snapshot returns the quotes of symbols from the tickers snapshot.
+func (p *Provider) snapshot(ctx context.Context, symbols []string, header http.Header) ([]provider.Quote, error) {
+	if err := p.Limiter.Allow(); err != nil {
+		return nil, err
+	}
+
+	var resp snapshotResponse
+	u := fmt.Sprintf(snapshotURL, url.QueryEscape(strings.ToUpper(strings.Join(symbols, ","))))
+	if err := provider.GetJSON(ctx, u, header, &resp); err != nil {
+		return nil, err
+	}
+
+	var ret []provider.Quote
+	for _, t := range resp.Tickers {
+		// Outside trading hours, the current day may still be empty.
+		price := t.LastTrade.Price
+		if price == 0 {
+			price = t.Day.Close
+		}
+		if price == 0 {
+			continue
+		}
+		q := provider.Quote{
+			Symbol:        t.Ticker,
+			Price:         price,
+			Currency:      "USD",
+			Open:          t.Day.Open,
+			High:          t.Day.High,
+			Low:           t.Day.Low,
+			PreviousClose: t.PrevDay.Close,
+		}
+		if t.LastTrade.Time != 0 {
+			q.Time = time.Unix(0, t.LastTrade.Time)
+		}
+		ret = append(ret, q)
+	}
+	return ret, nil
+}
+
+// lastTrade returns the quote of a symbol from its last trade.
+func (p *Provider) lastTrade(ctx context.Context, symbol string, header http.Header) (provider.Quote, error) {
+	if err := p.Limiter.Allow(); err != nil {
+		return provider.Quote{}, fmt.Errorf("%s: %w", symbol, err)
+	}
+
+	var resp lastTradeResponse
+	u := fmt.Sprintf(lastTradeURL, url.PathEscape(strings.ToUpper(symbol)))
+	if err := provider.GetJSON(ctx, u, header, &resp); err != nil {
+		return provider.Quote{}, err
+	}
+	if resp.Results.Price == 0 {
+		return provider.Quote{}, fmt.Errorf("%w: %s", provider.ErrNotFound, symbol)
+	}
+	return provider.Quote{
+		Symbol:   symbol,
+		Price:    resp.Results.Price,
+		Currency: "USD",
+		Time:     time.Unix(0, resp.Results.Time),
+	}, nil
+}
@@ -0,0 +1,53 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"math"
+	"strings"
+)
+
+// precision returns the number of decimals to round the prices of a symbol
+// to, or a negative number for no rounding. Per asset type settings take
+// precedence over metrics.precision.
+func precision(symbol string) int {
+	if len(cfg.PrecisionByType) > 0 {
+		// Failures fall back to the default precision.
+		assetType := assetType(symbol)
+		for t, digits := range cfg.PrecisionByType {
+			if assetType != "" && strings.EqualFold(t, assetType) {
+				return digits
+			}
+		}
+	}
+	return cfg.Metrics.Precision
+}
+
+// assetType returns the Yahoo instrument type of a symbol (e.g. EQUITY or
+// CRYPTOCURRENCY), or an empty string if unknown.
+func assetType(symbol string) string {
+	if _, ok := volatilityIndex(upstreamSymbol(symbol)); ok {
+		return assetTypeIndex
+	}
+	meta, _ := symbolMeta(symbol)
+	return meta.InstrumentType
+}
+
+// roundPrice rounds a price of a symbol to the configured precision.
+func roundPrice(symbol string, v float64) float64 {
+	digits := precision(symbol)
+	if digits < 0 {
+		return v
+	}
+	p := math.Pow(10, float64(digits))
+	return math.Round(v*p) / p
+}
@@ -0,0 +1,123 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/marcopaganini/quotes-exporter/provider"
+)
+
+// quoteKey returns the cache key of the quote of a symbol from a provider.
+// An empty provider selects the best provider for the symbol. Like other
+// kinds of data, quotes are prefixed with their kind, so symbols containing
+// ":" can't collide with other keys.
+func quoteKey(symbol, pname string) string {
+	if pname == "" {
+		return "quote:" + symbol
+	}
+	return "quote:" + symbol + "@" + pname
+}
+
+// splitQuoteKey returns the symbol and provider of a quote cache key.
+func splitQuoteKey(key string) (string, string) {
+	key = strings.TrimPrefix(key, "quote:")
+	if i := strings.LastIndex(key, "@"); i > 0 {
+		return key[:i], key[i+1:]
+	}
+	return key, ""
+}
+
+// notFoundKey returns the negative cache key of a quote key, for symbols
+// unknown upstream.
+func notFoundKey(key string) string {
+	return "notfound:" + key
+}
+
+// notFound returns true if err means upstream doesn't know a symbol.
+func notFound(err error) bool {
+	return errors.Is(err, provider.ErrNotFound) || provider.StatusCode(err) == http.StatusNotFound
+}
+
+// prefetch fetches the quotes of the given symbols missing from the cache
+// with one request per provider, and caches them. Symbols missing from the
+// results are fetched one by one later, when collected.
+func (c collector) prefetch(ctx context.Context, symbols []string) {
+	// Maps provider names to provider symbols to the requested symbols.
+	pending := map[string]map[string][]string{}
+	for _, symbol := range symbols {
+		requested := c.providers[symbol]
+		if _, ok := basket(symbol); ok {
+			continue
+		}
+		if _, ok := volatilityIndex(upstreamSymbol(symbol)); ok && requested == "" {
+			continue
+		}
+		if _, ok := cache.Storage.Get(quoteKey(symbol, requested)); ok {
+			continue
+		}
+		if _, ok := cache.Storage.Get(notFoundKey(quoteKey(symbol, requested))); ok {
+			continue
+		}
+		pname := providerFor(symbol, requested)
+		if pending[pname] == nil {
+			pending[pname] = map[string][]string{}
+		}
+		psym := strings.ToUpper(providerSymbol(pname, symbol))
+		pending[pname][psym] = append(pending[pname][psym], symbol)
+	}
+
+	for pname, psyms := range pending {
+		// A single symbol gains nothing from a batch request.
+		if len(psyms) < 2 {
+			continue
+		}
+		p, ok := findProvider(pname)
+		if !ok || unhealthy(pname) || allowCircuit(pname) != nil {
+			continue
+		}
+		if err := allowProvider(pname); err != nil {
+			recordProviderResult(pname, err)
+			continue
+		}
+		var batch []string
+		for psym := range psyms {
+			batch = append(batch, psym)
+		}
+
+		atomic.AddInt64(&quoteFetches, 1)
+		pctx, cancel := upstreamContext(providerContext(ctx, pname))
+		start := time.Now()
+		quotes, err := p.provider.Quote(pctx, batch)
+		providerDuration.WithLabelValues(pname).Observe(time.Since(start).Seconds())
+		cancel()
+		recordProviderResult(pname, err)
+		recordThrottle(pname, err)
+		if err != nil {
+			logger("collector").Warn("Error prefetching quotes", "provider", pname, "error", err)
+			continue
+		}
+		now := time.Now()
+		for _, pq := range quotes {
+			saveLastQuote(pname, pq.Symbol, quote{Quote: pq, fetched: now, provider: pname})
+			for _, symbol := range psyms[strings.ToUpper(pq.Symbol)] {
+				q := quote{Quote: pq, fetched: now, provider: pname}
+				cache.Storage.Set(quoteKey(symbol, c.providers[symbol]), q, quoteTTL(symbol, q))
+			}
+		}
+	}
+}
@@ -0,0 +1,147 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Client is the HTTP client used by providers.
+var Client = &http.Client{Transport: transport}
+
+// HTTPError is returned for upstream responses other than HTTP 2xx.
+type HTTPError struct {
+	StatusCode int
+	// Body holds the start of the response body.
+	Body string
+	// RetryAfter is when upstream asked to be called again, if it did.
+	RetryAfter time.Time
+}
+
+// NewHTTPError returns the error for an upstream response other than HTTP
+// 2xx, reading the start of its body.
+func NewHTTPError(resp *http.Response) *HTTPError {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 256))
+	return &HTTPError{
+		StatusCode: resp.StatusCode,
+		Body:       strings.TrimSpace(string(body)),
+		RetryAfter: retryAfter(resp.Header, time.Now()),
+	}
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("HTTP %d from upstream: %s", e.StatusCode, e.Body)
+}
+
+// StatusCode returns the HTTP status of an HTTPError, or zero for other
+// errors.
+func StatusCode(err error) int {
+	var herr *HTTPError
+	if errors.As(err, &herr) {
+		return herr.StatusCode
+	}
+	return 0
+}
+
+// RetryAfter returns when upstream asked to be called again in an
+// HTTPError, or the zero time for other errors.
+func RetryAfter(err error) time.Time {
+	var herr *HTTPError
+	if errors.As(err, &herr) {
+		return herr.RetryAfter
+	}
+	return time.Time{}
+}
+
+// retryAfter returns when a response asks to be called again, from the
+// Retry-After header (in seconds or as a date) or, when no requests remain,
+// the X-RateLimit-Reset header. It returns the zero time if neither is set.
+func retryAfter(h http.Header, now time.Time) time.Time {
+	if v := h.Get("Retry-After"); v != "" {
+		if secs, err := strconv.Atoi(v); err == nil {
+			return now.Add(time.Duration(secs) * time.Second)
+		}
+		if t, err := http.ParseTime(v); err == nil {
+			return t
+		}
+	}
+	if h.Get("X-RateLimit-Remaining") != "0" {
+		return time.Time{}
+	}
+	reset, err := strconv.ParseFloat(h.Get("X-RateLimit-Reset"), 64)
+	if err != nil || reset <= 0 {
+		return time.Time{}
+	}
+	// Some providers send the reset time as a Unix timestamp, others as
+	// seconds from now.
+	if reset > 1e9 {
+		return time.Unix(int64(reset), 0)
+	}
+	return now.Add(time.Duration(reset * float64(time.Second)))
+}
+
+// maxBody is the maximum size of a response body.
+const maxBody = 8 << 20
+
+// Get fetches a URL and returns the response body. Headers are added to the
+// request. Responses other than HTTP 2xx are errors.
+func Get(ctx context.Context, u string, header http.Header) ([]byte, error) {
+	return GetWith(ctx, Client, u, header)
+}
+
+// GetWith is like Get, using the given client (e.g. one with a cookie jar).
+func GetWith(ctx context.Context, client *http.Client, u string, header http.Header) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	for k, vs := range header {
+		req.Header[k] = vs
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, NewHTTPError(resp)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, maxBody))
+}
+
+// GetJSON fetches a URL with Get and decodes the JSON response into v.
+func GetJSON(ctx context.Context, u string, header http.Header, v interface{}) error {
+	return GetJSONWith(ctx, Client, u, header, v)
+}
+
+// GetJSONWith is like GetJSON, using the given client.
+func GetJSONWith(ctx context.Context, client *http.Client, u string, header http.Header, v interface{}) error {
+	body, err := GetWith(ctx, client, u, header)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("error decoding response: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package provider
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrRateLimited is returned when a request would exceed the rate limit of
+// a provider.
+var ErrRateLimited = errors.New("provider rate limit reached")
+
+// Limiter is a token bucket rate limiter, for upstream requests and clients
+// of the exporter alike. A nil Limiter allows all requests.
+type Limiter struct {
+	sync.Mutex
+	rate   float64 // Tokens added per second.
+	burst  float64 // Maximum number of tokens.
+	tokens float64
+	last   time.Time
+}
+
+// NewLimiter returns a limiter allowing perMinute requests per minute on
+// average, with bursts of up to burst requests. A rate of zero or less
+// returns nil (unlimited).
+func NewLimiter(perMinute float64, burst int) *Limiter {
+	if perMinute <= 0 {
+		return nil
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	return &Limiter{
+		rate:   perMinute / 60,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// Allow consumes one token, returning ErrRateLimited if none is available.
+func (l *Limiter) Allow() error {
+	if l == nil {
+		return nil
+	}
+	l.Lock()
+	defer l.Unlock()
+
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+
+	if l.tokens < 1 {
+		return ErrRateLimited
+	}
+	l.tokens--
+	return nil
+}
@@ -0,0 +1,110 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+)
+
+// ErrNotFound is returned when upstream doesn't know a symbol.
+var ErrNotFound = errors.New("symbol not found")
+
+// Quote holds the price of a symbol, as returned by a provider.
+type Quote struct {
+	Symbol string
+	Price  float64
+	// Currency is the ISO 4217 code of the price, if known.
+	Currency string
+	// Name is the name of the asset, if known.
+	Name string
+	// Time is the time of the last trade, if known.
+	Time time.Time
+
+	// Optional figures. Zero means unknown.
+	Open          float64
+	High          float64
+	Low           float64
+	PreviousClose float64
+	Volume        float64
+	MarketCap     float64
+	PE            float64
+	// ChangePercent is the change from the previous close (or over the last
+	// 24 hours, for markets that never close), in percent.
+	ChangePercent float64
+	// Confidence is the half width of the confidence interval of the price,
+	// for sources publishing one (e.g. oracles).
+	Confidence float64
+}
+
+// Provider fetches quotes from an upstream source.
+type Provider interface {
+	// Quote returns the quotes of the given symbols. Symbols without a quote
+	// are left out of the result. An error means no quote could be fetched.
+	Quote(ctx context.Context, symbols []string) ([]Quote, error)
+}
+
+// Each fetches the quotes of symbols one at a time with fetch, for providers
+// without batch requests. It returns the quotes fetched successfully, or the
+// first error if none was.
+func Each(ctx context.Context, symbols []string, fetch func(ctx context.Context, symbol string) (Quote, error)) ([]Quote, error) {
+	var (
+		ret      []Quote
+		firstErr error
+	)
+	for _, symbol := range symbols {
+		q, err := fetch(ctx, symbol)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		ret = append(ret, q)
+	}
+	if len(ret) == 0 && firstErr != nil {
+		return nil, firstErr
+	}
+	return ret, nil
+}
+
+// Find returns the quote for a symbol, ignoring case.
+func Find(quotes []Quote, symbol string) (Quote, bool) {
+	for _, q := range quotes {
+		if strings.EqualFold(q.Symbol, symbol) {
+			return q, true
+		}
+	}
+	return Quote{}, false
+}
+
+// tokenKey is the context key for per-request API tokens.
+type tokenKey struct{}
+
+// WithToken returns a context carrying an API token for a single request.
+func WithToken(ctx context.Context, token string) context.Context {
+	if token == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, tokenKey{}, token)
+}
+
+// Token returns the per-request API token in ctx, or def if there's none.
+// Token-based providers use it instead of their configured token.
+func Token(ctx context.Context, def string) string {
+	if t, ok := ctx.Value(tokenKey{}).(string); ok {
+		return t
+	}
+	return def
+}
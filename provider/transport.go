@@ -0,0 +1,92 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package provider
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/url"
+	"sync"
+)
+
+// headerTransport adds the configured headers to all upstream requests,
+// replacing those set by providers, and sends them through base.
+type headerTransport struct {
+	sync.Mutex
+	base   http.RoundTripper
+	header http.Header
+	// userAgents are used in turn, one per request.
+	userAgents []string
+	next       int
+}
+
+// transport is the transport of Client. Clients of providers needing their
+// own (e.g. with a cookie jar) use it too.
+var transport = &headerTransport{base: http.DefaultTransport}
+
+// SetHeaders sets the headers added to all upstream requests. If more than
+// one user agent is given, requests use them in turn.
+func SetHeaders(userAgents []string, header http.Header) {
+	transport.Lock()
+	defer transport.Unlock()
+	transport.userAgents = userAgents
+	transport.header = header
+	transport.next = 0
+}
+
+// SetTransport sets the proxy and TLS configuration of all upstream
+// requests. A nil proxy uses the proxy set in the environment (HTTP_PROXY,
+// HTTPS_PROXY and NO_PROXY), if any, and a nil TLS configuration the
+// defaults. Idle connections of the previous transport are closed.
+func SetTransport(proxy *url.URL, tlsConfig *tls.Config) {
+	base := http.DefaultTransport.(*http.Transport).Clone()
+	if proxy != nil {
+		base.Proxy = http.ProxyURL(proxy)
+	}
+	if tlsConfig != nil {
+		base.TLSClientConfig = tlsConfig
+	}
+	transport.Lock()
+	old := transport.base
+	transport.base = base
+	transport.Unlock()
+
+	// Requests in flight keep using the old transport until done.
+	if t, ok := old.(interface{ CloseIdleConnections() }); ok {
+		t.CloseIdleConnections()
+	}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.Lock()
+	base := t.base
+	header := t.header
+	var ua string
+	if len(t.userAgents) > 0 {
+		ua = t.userAgents[t.next%len(t.userAgents)]
+		t.next++
+	}
+	t.Unlock()
+
+	if len(header) > 0 || ua != "" {
+		// Round trippers must not modify the original request.
+		req = req.Clone(req.Context())
+		for k, vs := range header {
+			req.Header[http.CanonicalHeaderKey(k)] = vs
+		}
+		if ua != "" {
+			req.Header.Set("User-Agent", ua)
+		}
+	}
+	return base.RoundTrip(req)
+}
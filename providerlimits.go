@@ -0,0 +1,165 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/marcopaganini/quotes-exporter/provider"
+)
+
+var throttledUntilDesc = prometheus.NewDesc(
+	"quotes_exporter_provider_throttled_until_timestamp",
+	"Time until which upstream asked not to be called again (Unix timestamp).",
+	[]string{"provider"}, nil,
+)
+
+// rateLimitConfig sets the rate limit of a provider.
+type rateLimitConfig struct {
+	RequestsPerMinute float64 `json:"requests_per_minute"`
+	Burst             int     `json:"burst"`
+}
+
+// providerLimiters holds the limiters of providers without a limiter of
+// their own, for those with a rate limit set in the configuration file.
+// limited holds the names of the providers with a limiter of their own.
+var providerLimiters = struct {
+	sync.Mutex
+	limiters map[string]*provider.Limiter
+	limited  map[string]bool
+}{limiters: map[string]*provider.Limiter{}, limited: map[string]bool{}}
+
+// limiterState holds a limiter and the settings it was created with.
+type limiterState struct {
+	limiter   *provider.Limiter
+	perMinute float64
+	burst     int
+}
+
+// limiters holds the limiters created so far, by name, so reloading the
+// configuration keeps the budgets of limits that didn't change.
+var limiters = struct {
+	sync.Mutex
+	states map[string]limiterState
+}{states: map[string]limiterState{}}
+
+// sharedLimiter returns a limiter allowing perMinute requests per minute,
+// with bursts of up to burst requests. The limiter previously returned for
+// name is returned again if its settings didn't change.
+func sharedLimiter(name string, perMinute float64, burst int) *provider.Limiter {
+	limiters.Lock()
+	defer limiters.Unlock()
+
+	if st, ok := limiters.states[name]; ok && st.perMinute == perMinute && st.burst == burst {
+		return st.limiter
+	}
+	l := provider.NewLimiter(perMinute, burst)
+	limiters.states[name] = limiterState{limiter: l, perMinute: perMinute, burst: burst}
+	return l
+}
+
+// providerThrottles holds when each provider asked to be called again, for
+// providers that did.
+var providerThrottles = struct {
+	sync.Mutex
+	until map[string]time.Time
+}{until: map[string]time.Time{}}
+
+// providerLimiter returns the limiter of a provider with a limiter of its
+// own, allowing perMinute requests per minute (bursts of one), unless the
+// configuration file sets its rate limit.
+func providerLimiter(name string, perMinute float64) *provider.Limiter {
+	providerLimiters.Lock()
+	providerLimiters.limited[name] = true
+	providerLimiters.Unlock()
+
+	if rl, ok := cfg.RateLimits[name]; ok {
+		return sharedLimiter(name, rl.RequestsPerMinute, rl.Burst)
+	}
+	return sharedLimiter(name, perMinute, 1)
+}
+
+// limitOtherProviders creates the limiters of the providers without a
+// limiter of their own with a rate limit in the configuration file. Must be
+// called after providerLimiter has been called for all other providers.
+func limitOtherProviders() {
+	providerLimiters.Lock()
+	defer providerLimiters.Unlock()
+
+	providerLimiters.limiters = map[string]*provider.Limiter{}
+	for name, rl := range cfg.RateLimits {
+		if !providerLimiters.limited[name] {
+			providerLimiters.limiters[name] = sharedLimiter(name, rl.RequestsPerMinute, rl.Burst)
+		}
+	}
+}
+
+// allowProvider returns provider.ErrRateLimited if upstream asked not to be
+// called yet, and otherwise consumes a request from the rate limit of a
+// provider without a limiter of its own, failing the same way if none is
+// left.
+func allowProvider(name string) error {
+	providerThrottles.Lock()
+	until := providerThrottles.until[name]
+	providerThrottles.Unlock()
+	if time.Now().Before(until) {
+		return fmt.Errorf("%w: upstream asked to wait until %s", provider.ErrRateLimited, until.Format(time.RFC3339))
+	}
+
+	providerLimiters.Lock()
+	l := providerLimiters.limiters[name]
+	providerLimiters.Unlock()
+	return l.Allow()
+}
+
+// recordThrottle delays calls to a provider if upstream asked to be called
+// again later in err.
+func recordThrottle(name string, err error) {
+	// With several API keys, the next key is used instead.
+	if provider.StatusCode(err) == http.StatusTooManyRequests && rotateKey(name) {
+		return
+	}
+	t := provider.RetryAfter(err)
+	if t.IsZero() {
+		return
+	}
+	providerThrottles.Lock()
+	defer providerThrottles.Unlock()
+
+	if t.After(providerThrottles.until[name]) {
+		providerThrottles.until[name] = t
+		logger("ratelimit").Warn("Provider throttled by upstream", "provider", name, "until", t)
+	}
+}
+
+// throttleCollector exports when throttled providers can be called again.
+type throttleCollector struct{}
+
+// Describe outputs description for prometheus timeseries.
+func (throttleCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- throttledUntilDesc
+}
+
+// Collect outputs the provider throttle gauges.
+func (throttleCollector) Collect(ch chan<- prometheus.Metric) {
+	providerThrottles.Lock()
+	defer providerThrottles.Unlock()
+
+	for name, t := range providerThrottles.until {
+		ch <- prometheus.MustNewConstMetric(throttledUntilDesc, prometheus.GaugeValue, float64(t.Unix()), name)
+	}
+}
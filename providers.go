@@ -0,0 +1,441 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/marcopaganini/quotes-exporter/alpaca"
+	"github.com/marcopaganini/quotes-exporter/alphavantage"
+	"github.com/marcopaganini/quotes-exporter/brapi"
+	"github.com/marcopaganini/quotes-exporter/chainlink"
+	"github.com/marcopaganini/quotes-exporter/coingecko"
+	"github.com/marcopaganini/quotes-exporter/coinmarketcap"
+	"github.com/marcopaganini/quotes-exporter/eodhd"
+	"github.com/marcopaganini/quotes-exporter/exchange"
+	"github.com/marcopaganini/quotes-exporter/finnhub"
+	"github.com/marcopaganini/quotes-exporter/fmp"
+	"github.com/marcopaganini/quotes-exporter/forex"
+	"github.com/marcopaganini/quotes-exporter/fred"
+	"github.com/marcopaganini/quotes-exporter/ftfunds"
+	"github.com/marcopaganini/quotes-exporter/htmlpage"
+	"github.com/marcopaganini/quotes-exporter/investing"
+	"github.com/marcopaganini/quotes-exporter/jsonapi"
+	"github.com/marcopaganini/quotes-exporter/localfile"
+	"github.com/marcopaganini/quotes-exporter/marketstack"
+	"github.com/marcopaganini/quotes-exporter/metals"
+	"github.com/marcopaganini/quotes-exporter/mock"
+	"github.com/marcopaganini/quotes-exporter/morningstar"
+	"github.com/marcopaganini/quotes-exporter/nasdaqdatalink"
+	"github.com/marcopaganini/quotes-exporter/nse"
+	"github.com/marcopaganini/quotes-exporter/polygon"
+	"github.com/marcopaganini/quotes-exporter/provider"
+	"github.com/marcopaganini/quotes-exporter/pyth"
+	"github.com/marcopaganini/quotes-exporter/stockdata"
+	"github.com/marcopaganini/quotes-exporter/stonks"
+	"github.com/marcopaganini/quotes-exporter/tiingo"
+	"github.com/marcopaganini/quotes-exporter/tradier"
+	"github.com/marcopaganini/quotes-exporter/tsp"
+	"github.com/marcopaganini/quotes-exporter/twelvedata"
+	"github.com/marcopaganini/quotes-exporter/yahoo"
+)
+
+// providerInfo describes a quote provider.
+type providerInfo struct {
+	name     string
+	provider provider.Provider
+	// credentials lists the settings required to use the provider.
+	credentials []string
+	// assetTypes lists the kinds of assets the provider can quote.
+	assetTypes []string
+	// metrics lists the metrics the provider can populate.
+	metrics []string
+	// labels returns extra labels for the price of a symbol, if any.
+	labels func(symbol string) map[string]string
+	// isins is true for providers taking ISINs as symbols, which are then
+	// not resolved to tickers.
+	isins bool
+}
+
+// Providers configured at startup.
+var (
+	alphaVantageProvider      = &alphavantage.Provider{}
+	finnhubProvider           = &finnhub.Provider{}
+	polygonProvider           = &polygon.Provider{}
+	tiingoProvider            = &tiingo.Provider{}
+	twelveDataProvider        = &twelvedata.Provider{}
+	marketstackProvider       = &marketstack.Provider{}
+	eodhdProvider             = &eodhd.Provider{}
+	fmpProvider               = &fmp.Provider{}
+	coinGeckoProvider         = &coingecko.Provider{}
+	coinMarketCapProvider     = &coinmarketcap.Provider{}
+	openExchangeRatesProvider = &forex.OpenExchangeRates{}
+	alpacaProvider            = &alpaca.Provider{}
+	tradierProvider           = &tradier.Provider{}
+	nasdaqDataLinkProvider    = &nasdaqdatalink.Provider{}
+	fredProvider              = &fred.Provider{}
+	morningstarProvider       = &morningstar.Provider{}
+	ftFundsProvider           = &ftfunds.Provider{}
+	brapiProvider             = &brapi.Provider{}
+	nseProvider               = &nse.Provider{}
+	tspProvider               = &tsp.Provider{}
+	metalsProvider            = &metals.Provider{}
+	investingProvider         = &investing.Provider{}
+	chainlinkProvider         = &chainlink.Provider{}
+	pythProvider              = &pyth.Provider{}
+	jsonAPIProvider           = &jsonapi.Provider{}
+	htmlProvider              = &htmlpage.Provider{}
+	localFileProvider         = &localfile.Provider{}
+	mockProvider              = &mock.Provider{}
+	stockDataProvider         = &stockdata.Provider{}
+)
+
+// providers holds all quote providers, in order of preference.
+var providers = []providerInfo{
+	{
+		name:       "stonks",
+		provider:   stonks.Provider{},
+		assetTypes: []string{"equity", "etf", "mutualfund", "crypto"},
+		metrics:    []string{"price", "currency", "change-percent"},
+	},
+	{
+		name:       "yahoo",
+		provider:   yahoo.Provider{},
+		assetTypes: []string{"equity", "etf", "mutualfund", "crypto", "index", "currency", "future"},
+		metrics:    []string{"price", "currency", "history", "events", "etf-holdings"},
+	},
+	{
+		name:        "alphavantage",
+		provider:    alphaVantageProvider,
+		credentials: []string{"alphavantage.token"},
+		assetTypes:  []string{"equity", "etf", "mutualfund"},
+		metrics:     []string{"price"},
+	},
+	{
+		name:        "finnhub",
+		provider:    finnhubProvider,
+		credentials: []string{"finnhub.token"},
+		assetTypes:  []string{"equity", "etf", "crypto", "currency"},
+		metrics:     []string{"price", "day-range", "previous-close"},
+	},
+	{
+		name:        "polygon",
+		provider:    polygonProvider,
+		credentials: []string{"polygon.token"},
+		assetTypes:  []string{"equity", "etf"},
+		metrics:     []string{"price", "currency", "day-range", "previous-close"},
+	},
+	{
+		name:        "tiingo",
+		provider:    tiingoProvider,
+		credentials: []string{"tiingo.token"},
+		assetTypes:  []string{"equity", "etf", "mutualfund"},
+		metrics:     []string{"price", "currency", "day-range", "previous-close"},
+	},
+	{
+		name:        "twelvedata",
+		provider:    twelveDataProvider,
+		credentials: []string{"twelvedata.token"},
+		assetTypes:  []string{"equity", "etf", "mutualfund", "crypto", "currency", "index"},
+		metrics:     []string{"price", "currency", "day-range", "previous-close"},
+	},
+	{
+		name:        "marketstack",
+		provider:    marketstackProvider,
+		credentials: []string{"marketstack.access-key"},
+		assetTypes:  []string{"equity", "etf", "index"},
+		metrics:     []string{"price", "day-range"},
+	},
+	{
+		name:        "eodhd",
+		provider:    eodhdProvider,
+		credentials: []string{"eodhd.token"},
+		assetTypes:  []string{"equity", "etf", "mutualfund", "crypto", "currency", "index"},
+		metrics:     []string{"price", "day-range", "previous-close"},
+	},
+	{
+		name:        "fmp",
+		provider:    fmpProvider,
+		credentials: []string{"fmp.token"},
+		assetTypes:  []string{"equity", "etf", "mutualfund", "crypto", "currency", "index"},
+		metrics:     []string{"price", "day-range", "previous-close", "volume", "market-cap", "pe"},
+	},
+	{
+		name:       "coingecko",
+		provider:   coinGeckoProvider,
+		assetTypes: []string{"crypto"},
+		metrics:    []string{"price", "currency", "volume", "market-cap"},
+	},
+	{
+		name:        "coinmarketcap",
+		provider:    coinMarketCapProvider,
+		credentials: []string{"coinmarketcap.token"},
+		assetTypes:  []string{"crypto"},
+		metrics:     []string{"price", "currency", "volume", "market-cap", "change-percent"},
+	},
+	{
+		name:       "kraken",
+		provider:   exchange.Kraken{},
+		assetTypes: []string{"crypto"},
+		metrics:    []string{"price", "currency", "day-range", "volume"},
+	},
+	{
+		name:       "coinbase",
+		provider:   exchange.Coinbase{Price: exchange.CoinbaseSpot},
+		assetTypes: []string{"crypto"},
+		metrics:    []string{"price", "currency"},
+	},
+	{
+		name:       "coinbase-buy",
+		provider:   exchange.Coinbase{Price: exchange.CoinbaseBuy},
+		assetTypes: []string{"crypto"},
+		metrics:    []string{"price", "currency"},
+	},
+	{
+		name:       "coinbase-sell",
+		provider:   exchange.Coinbase{Price: exchange.CoinbaseSell},
+		assetTypes: []string{"crypto"},
+		metrics:    []string{"price", "currency"},
+	},
+	{
+		name:       "frankfurter",
+		provider:   forex.Frankfurter{},
+		assetTypes: []string{"currency"},
+		metrics:    []string{"price", "currency"},
+		labels:     pairLabels,
+	},
+	{
+		name:        "openexchangerates",
+		provider:    openExchangeRatesProvider,
+		credentials: []string{"openexchangerates.app-id"},
+		assetTypes:  []string{"currency"},
+		metrics:     []string{"price", "currency"},
+		labels:      pairLabels,
+	},
+	{
+		name:        "alpaca",
+		provider:    alpacaProvider,
+		credentials: []string{"alpaca.key-id", "alpaca.secret-key"},
+		assetTypes:  []string{"equity", "etf"},
+		metrics:     []string{"price", "currency", "day-range", "previous-close", "volume"},
+	},
+	{
+		name:        "tradier",
+		provider:    tradierProvider,
+		credentials: []string{"tradier.token"},
+		assetTypes:  []string{"equity", "etf", "mutualfund", "index", "option"},
+		metrics:     []string{"price", "currency", "day-range", "previous-close", "volume", "change-percent"},
+	},
+	{
+		name:        "nasdaqdatalink",
+		provider:    nasdaqDataLinkProvider,
+		credentials: []string{"nasdaqdatalink.token"},
+		assetTypes:  []string{"commodity", "dataset"},
+		metrics:     []string{"price"},
+	},
+	{
+		name:        "fred",
+		provider:    fredProvider,
+		credentials: []string{"fred.api-key"},
+		assetTypes:  []string{"economic-series"},
+		metrics:     []string{"price"},
+		labels:      fred.Labels,
+	},
+	{
+		name:       "morningstar",
+		provider:   morningstarProvider,
+		assetTypes: []string{"mutualfund"},
+		metrics:    []string{"price", "currency", "price-timestamp"},
+		isins:      true,
+	},
+	{
+		name:       "ftfunds",
+		provider:   ftFundsProvider,
+		assetTypes: []string{"mutualfund"},
+		metrics:    []string{"price", "currency"},
+		isins:      true,
+	},
+	{
+		name:        "brapi",
+		provider:    brapiProvider,
+		credentials: []string{"brapi.token"},
+		assetTypes:  []string{"equity", "etf", "reit"},
+		metrics:     []string{"price", "currency", "day-range", "previous-close", "volume", "market-cap", "pe", "change-percent", "price-timestamp"},
+	},
+	{
+		name:       "nse",
+		provider:   nseProvider,
+		assetTypes: []string{"equity", "etf"},
+		metrics:    []string{"price", "currency", "day-range", "previous-close", "change-percent", "price-timestamp"},
+	},
+	{
+		name:       "tsp",
+		provider:   tspProvider,
+		assetTypes: []string{"mutualfund"},
+		metrics:    []string{"price", "currency", "price-timestamp"},
+	},
+	{
+		name:        "metals",
+		provider:    metalsProvider,
+		credentials: []string{"metals.token"},
+		assetTypes:  []string{"commodity"},
+		metrics:     []string{"price", "currency", "price-timestamp"},
+		labels:      metals.Labels,
+	},
+	{
+		name:       "investing",
+		provider:   investingProvider,
+		assetTypes: []string{"equity", "etf", "mutualfund", "warrant", "certificate", "bond", "index", "commodity"},
+		metrics:    []string{"price", "currency", "change-percent"},
+	},
+	{
+		name:        "chainlink",
+		provider:    chainlinkProvider,
+		credentials: []string{"chainlink.rpc-url"},
+		assetTypes:  []string{"crypto", "currency", "commodity"},
+		metrics:     []string{"price", "currency", "price-timestamp"},
+	},
+	{
+		name:       "pyth",
+		provider:   pythProvider,
+		assetTypes: []string{"crypto", "equity", "currency", "commodity"},
+		metrics:    []string{"price", "currency", "price-confidence", "price-timestamp"},
+	},
+	{
+		name:       "jsonapi",
+		provider:   jsonAPIProvider,
+		assetTypes: []string{"any"},
+		metrics:    []string{"price", "currency", "name"},
+	},
+	{
+		name:       "html",
+		provider:   htmlProvider,
+		assetTypes: []string{"any"},
+		metrics:    []string{"price", "currency"},
+	},
+	{
+		name:       "localfile",
+		provider:   localFileProvider,
+		assetTypes: []string{"any"},
+		metrics:    []string{"price", "currency", "name", "price-timestamp"},
+	},
+	{
+		name:       "mock",
+		provider:   mockProvider,
+		assetTypes: []string{"any"},
+		metrics:    []string{"price", "currency", "previous-close", "change-percent", "price-timestamp"},
+	},
+	{
+		name:        "stockdata",
+		provider:    stockDataProvider,
+		credentials: []string{"stockdata.token"},
+		assetTypes:  []string{"equity", "etf", "index"},
+		metrics:     []string{"price", "currency", "name", "day-range", "previous-close", "volume", "market-cap", "change-percent"},
+	},
+}
+
+// providerNames returns the names of all providers.
+func providerNames() []string {
+	var names []string
+	for _, p := range providers {
+		names = append(names, p.name)
+	}
+	return names
+}
+
+// pairLabels returns the currency_pair label of forex symbols.
+func pairLabels(symbol string) map[string]string {
+	if pair := forex.PairLabel(symbol); pair != "" {
+		return map[string]string{"currency_pair": pair}
+	}
+	return nil
+}
+
+// findProvider returns the provider with the given name.
+func findProvider(name string) (providerInfo, bool) {
+	for _, p := range providers {
+		if strings.EqualFold(p.name, name) {
+			return p, true
+		}
+	}
+	return providerInfo{}, false
+}
+
+// providerFor returns the name of the provider to fetch a symbol from. An
+// empty requested provider selects the best provider for the symbol: the
+// provider of the first matching route, or the default provider.
+func providerFor(symbol, requested string) string {
+	if requested != "" {
+		return requested
+	}
+	if _, ok := basket(symbol); ok {
+		return "basket"
+	}
+	if _, ok := volatilityIndex(upstreamSymbol(symbol)); ok {
+		return "yahoo"
+	}
+	if name, ok := routedProvider(symbol); ok {
+		return name
+	}
+	return cfg.Provider
+}
+
+// providerChain returns the names of the providers to try for a symbol, in
+// order: the provider selected for it, then the fallback providers.
+func providerChain(symbol, requested string) []string {
+	chain := []string{providerFor(symbol, requested)}
+	for _, name := range cfg.ProviderFallback {
+		if !containsFold(chain, name) {
+			chain = append(chain, name)
+		}
+	}
+	return chain
+}
+
+// providerSymbol returns the symbol to send to a provider.
+func providerSymbol(name, symbol string) string {
+	if name == "yahoo" {
+		return yahooSymbol(symbol)
+	}
+	if p, ok := findProvider(name); ok && p.isins {
+		if a, ok := alias(symbol); ok && a.Symbol != "" {
+			return a.Symbol
+		}
+		return symbol
+	}
+	return upstreamSymbol(symbol)
+}
+
+// providersCmd prints the providers and what they support.
+func providersCmd(fs *flag.FlagSet) error {
+	none := func(s []string) string {
+		if len(s) == 0 {
+			return "none"
+		}
+		return strings.Join(s, ",")
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PROVIDER\tDEFAULT\tCREDENTIALS\tASSET TYPES\tMETRICS")
+	for _, p := range providers {
+		def := ""
+		if p.name == cfg.Provider {
+			def = "*"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", p.name, def, none(p.credentials), none(p.assetTypes), none(p.metrics))
+	}
+	return w.Flush()
+}
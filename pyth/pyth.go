@@ -0,0 +1,202 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package pyth
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/marcopaganini/quotes-exporter/provider"
+)
+
+const (
+	defaultEndpoint = "https://hermes.pyth.network"
+
+	feedsPath  = "/v2/price_feeds?query=%s"
+	latestPath = "/v2/updates/price/latest?parsed=true&%s"
+)
+
+var idRE = regexp.MustCompile(`^(0x)?[0-9a-fA-F]{64}$`)
+
+// feed is a price feed returned by the price feeds API.
+type feed struct {
+	ID         string `json:"id"`
+	Attributes struct {
+		Symbol        string `json:"symbol"`
+		QuoteCurrency string `json:"quote_currency"`
+	} `json:"attributes"`
+}
+
+// price is a price as published by Pyth: an integer and a decimal exponent.
+type price struct {
+	Price       string `json:"price"`
+	Conf        string `json:"conf"`
+	Expo        int    `json:"expo"`
+	PublishTime int64  `json:"publish_time"`
+}
+
+// latestResponse is the response of the latest price updates API.
+type latestResponse struct {
+	Parsed []struct {
+		ID    string `json:"id"`
+		Price price  `json:"price"`
+	} `json:"parsed"`
+}
+
+// Provider is the Pyth Network provider, using the Hermes API. Symbols are
+// Pyth feed symbols, with or without the asset class prefix (BTC/USD,
+// Crypto.BTC/USD, Equity.US.AAPL/USD, FX.EUR/USD), or feed IDs.
+type Provider struct {
+	// Endpoint is the Hermes endpoint URL (default: the public endpoint).
+	Endpoint string
+	// Limiter limits the requests sent upstream.
+	Limiter *provider.Limiter
+
+	mu    sync.Mutex
+	feeds map[string]feed
+}
+
+// Quote returns the latest prices of the given symbols, with one request
+// (plus one per symbol the first time its feed ID is looked up).
+func (p *Provider) Quote(ctx context.Context, symbols []string) ([]provider.Quote, error) {
+	var (
+		params   = url.Values{}
+		bySymbol = map[string]feed{}
+		firstErr error
+	)
+	for _, symbol := range symbols {
+		f, err := p.feed(ctx, symbol)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		bySymbol[symbol] = f
+		params.Add("ids[]", f.ID)
+	}
+	if len(bySymbol) == 0 {
+		return nil, firstErr
+	}
+
+	if err := p.Limiter.Allow(); err != nil {
+		return nil, err
+	}
+	var resp latestResponse
+	if err := provider.GetJSON(ctx, p.endpoint()+fmt.Sprintf(latestPath, params.Encode()), nil, &resp); err != nil {
+		return nil, err
+	}
+	prices := map[string]price{}
+	for _, u := range resp.Parsed {
+		prices[normalizeID(u.ID)] = u.Price
+	}
+
+	var ret []provider.Quote
+	for _, symbol := range symbols {
+		f, ok := bySymbol[symbol]
+		if !ok {
+			continue
+		}
+		pr, ok := prices[normalizeID(f.ID)]
+		if !ok {
+			continue
+		}
+		v, err := scale(pr.Price, pr.Expo)
+		if err != nil || v == 0 {
+			continue
+		}
+		conf, _ := scale(pr.Conf, pr.Expo)
+		q := provider.Quote{
+			Symbol:     symbol,
+			Price:      v,
+			Currency:   strings.ToUpper(f.Attributes.QuoteCurrency),
+			Confidence: conf,
+		}
+		if pr.PublishTime > 0 {
+			q.Time = time.Unix(pr.PublishTime, 0)
+		}
+		ret = append(ret, q)
+	}
+	return ret, nil
+}
+
+// feed returns the feed of a symbol, looking it up with the price feeds API
+// the first time.
+func (p *Provider) feed(ctx context.Context, symbol string) (feed, error) {
+	if idRE.MatchString(symbol) {
+		return feed{ID: normalizeID(symbol)}, nil
+	}
+	key := strings.ToUpper(symbol)
+
+	p.mu.Lock()
+	f, ok := p.feeds[key]
+	p.mu.Unlock()
+	if ok {
+		return f, nil
+	}
+
+	if err := p.Limiter.Allow(); err != nil {
+		return feed{}, fmt.Errorf("%s: %w", symbol, err)
+	}
+	var feeds []feed
+	if err := provider.GetJSON(ctx, p.endpoint()+fmt.Sprintf(feedsPath, url.QueryEscape(symbol)), nil, &feeds); err != nil {
+		return feed{}, err
+	}
+	found := false
+	for _, cand := range feeds {
+		s := strings.ToUpper(cand.Attributes.Symbol)
+		if s == key || strings.HasSuffix(s, "."+key) {
+			f, found = cand, true
+			break
+		}
+	}
+	if !found {
+		return feed{}, fmt.Errorf("no Pyth price feed for %s", symbol)
+	}
+
+	p.mu.Lock()
+	if p.feeds == nil {
+		p.feeds = map[string]feed{}
+	}
+	p.feeds[key] = f
+	p.mu.Unlock()
+	return f, nil
+}
+
+// endpoint returns the Hermes endpoint URL.
+func (p *Provider) endpoint() string {
+	if p.Endpoint == "" {
+		return defaultEndpoint
+	}
+	return strings.TrimSuffix(p.Endpoint, "/")
+}
+
+// scale returns the value of a Pyth integer with the given exponent.
+func scale(s string, expo int) (float64, error) {
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	return float64(n) * math.Pow10(expo), nil
+}
+
+// normalizeID returns a feed ID in lower case, without the 0x prefix.
+func normalizeID(id string) string {
+	return strings.ToLower(strings.TrimPrefix(id, "0x"))
+}
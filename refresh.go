@@ -0,0 +1,103 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// quoteLookups holds when each quote key was last looked up, so only quotes
+// still being scraped are refreshed ahead of expiry.
+var quoteLookups = struct {
+	sync.Mutex
+	last map[string]time.Time
+}{last: map[string]time.Time{}}
+
+// recordLookup records a lookup of a quote key.
+func recordLookup(key string) {
+	if cfg.Cache.RefreshAhead <= 0 {
+		return
+	}
+	quoteLookups.Lock()
+	defer quoteLookups.Unlock()
+	quoteLookups.last[key] = time.Now()
+}
+
+// expiringQuotes returns the keys of the cached quotes looked up within their
+// TTL that expire within a random time between half and all of ahead,
+// so refreshes spread out instead of coming in bursts.
+func expiringQuotes(ahead time.Duration) []string {
+	now := time.Now()
+	quoteLookups.Lock()
+	defer quoteLookups.Unlock()
+
+	var keys []string
+	for key, item := range cache.Storage.Items() {
+		if cacheKind(key) != "quote" {
+			continue
+		}
+		last, ok := quoteLookups.last[key]
+		if !ok {
+			continue
+		}
+		symbol, _ := splitQuoteKey(key)
+		if q, _ := item.Object.(quote); now.Sub(last) > quoteTTL(symbol, q) {
+			delete(quoteLookups.last, key)
+			continue
+		}
+		jitter := time.Duration(rand.Int63n(int64(ahead)/2 + 1))
+		if time.Unix(0, item.Expiration).Sub(now) < ahead/2+jitter {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// refreshQuotes fetches the quotes about to expire from the cache and caches
+// them again.
+func refreshQuotes(ahead time.Duration) {
+	cfgMu.RLock()
+	keys := expiringQuotes(ahead)
+	cfgMu.RUnlock()
+
+	for _, key := range keys {
+		refreshQuote(key)
+	}
+}
+
+// refreshQuote fetches the quote of a quote key and caches it again, holding
+// the configuration like scrapes do.
+func refreshQuote(key string) {
+	cfgMu.RLock()
+	defer cfgMu.RUnlock()
+
+	symbol, pname := splitQuoteKey(key)
+	qret, err := quoteFetcher(context.Background(), symbol, pname)()
+	if err != nil {
+		// The quote is left to expire and be fetched when scraped.
+		logger("cache").Debug("Error refreshing quote ahead of expiry", "symbol", symbol, "error", err)
+		return
+	}
+	cache.Storage.Set(key, qret, quoteTTL(symbol, qret.(quote)))
+	saveServedQuote(key, qret.(quote))
+}
+
+// refreshAhead refreshes cached quotes shortly before they expire, checking
+// four times per ahead. It never returns.
+func refreshAhead(ahead time.Duration) {
+	for range time.Tick(ahead / 4) {
+		refreshQuotes(ahead)
+	}
+}
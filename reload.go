@@ -0,0 +1,132 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"flag"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+)
+
+var (
+	// cfgMu protects cfg during reloads. Requests hold a read lock for their
+	// whole duration, so they see a consistent configuration.
+	cfgMu sync.RWMutex
+
+	// cmdArgs holds the command-line arguments of the running command.
+	cmdArgs []string
+)
+
+// withConfig holds a read lock on the configuration while serving a request.
+func withConfig(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cfgMu.RLock()
+		defer cfgMu.RUnlock()
+		h.ServeHTTP(w, r)
+	})
+}
+
+// reloadConfig reads the configuration again and applies it. Listener
+// (web.*) and cache (cache.*) settings only change on restart. If the new configuration is
+// invalid, the running configuration is kept.
+func reloadConfig() error {
+	var c config
+	fs := newFlagSet("serve", &c)
+	fs.Init("serve", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	if err := parseConfig(fs, &c, cmdArgs); err != nil {
+		return err
+	}
+
+	cfgMu.Lock()
+	defer cfgMu.Unlock()
+
+	old := cfg
+	c.Web = old.Web
+	c.Cache = old.Cache
+	cfg = c
+	if err := applyConfig(); err != nil {
+		cfg = old
+		applyConfig()
+		return err
+	}
+	return nil
+}
+
+// applyConfig sets up the components initialized from the configuration.
+func applyConfig() error {
+	if err := setupLogging(cfg.Log.Level, cfg.Log.Format); err != nil {
+		return err
+	}
+	if err := loadProviderSecrets(); err != nil {
+		return err
+	}
+	if err := watchlists.load(cfg.Watchlists, cfg.Web.StateFile); err != nil {
+		return err
+	}
+	return loadTenants(cfg.Tokens)
+}
+
+// handleReloads reloads the configuration on SIGHUP and, if interval is not
+// zero, whenever the configuration file changes. It never returns.
+func handleReloads(interval time.Duration) {
+	log := logger("config")
+	reload := func(reason string) {
+		if err := reloadConfig(); err != nil {
+			log.Error("Invalid configuration, keeping the running one", "reason", reason, "error", err)
+			return
+		}
+		log.Info("Configuration reloaded", "reason", reason)
+	}
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	var tick <-chan time.Time
+	if interval > 0 {
+		tick = time.Tick(interval)
+	}
+	last := fileVersion(cfg.File)
+
+	for {
+		select {
+		case <-hup:
+			reload("signal")
+		case <-tick:
+			cfgMu.RLock()
+			filename := cfg.File
+			cfgMu.RUnlock()
+			if v := fileVersion(filename); v != last {
+				last = v
+				reload("file changed")
+			}
+		}
+	}
+}
+
+// fileVersion returns a string that changes when a file is modified.
+func fileVersion(filename string) string {
+	if filename == "" {
+		return ""
+	}
+	fi, err := os.Stat(filename)
+	if err != nil {
+		return ""
+	}
+	return fi.ModTime().String() + "/" + strconv.FormatInt(fi.Size(), 10)
+}
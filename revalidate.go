@@ -0,0 +1,101 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var staleServed = prometheus.NewCounter(
+	prometheus.CounterOpts{
+		Name: "quotes_exporter_stale_served_total",
+		Help: "Count of expired quotes served while being refreshed in the background.",
+	},
+)
+
+// servedQuotes holds the last quote served for each quote key, kept past
+// cache expiration, and the keys being refreshed in the background.
+var servedQuotes = struct {
+	sync.Mutex
+	quotes     map[string]quote
+	refreshing map[string]bool
+}{quotes: map[string]quote{}, refreshing: map[string]bool{}}
+
+// saveServedQuote saves the quote served for a quote key.
+func saveServedQuote(key string, q quote) {
+	if cfg.Cache.StaleWhileRevalidate <= 0 {
+		return
+	}
+	servedQuotes.Lock()
+	defer servedQuotes.Unlock()
+	servedQuotes.quotes[key] = q
+}
+
+// staleQuote returns the last quote served for the quote key of a symbol if
+// its cache entry expired less than cache.stale-while-revalidate ago.
+func staleQuote(key, symbol string) (quote, bool) {
+	if cfg.Cache.StaleWhileRevalidate <= 0 {
+		return quote{}, false
+	}
+	if _, ok := cache.Storage.Get(key); ok {
+		return quote{}, false
+	}
+	servedQuotes.Lock()
+	defer servedQuotes.Unlock()
+
+	q, ok := servedQuotes.quotes[key]
+	if !ok {
+		return quote{}, false
+	}
+	if time.Since(q.fetched) > quoteTTL(symbol, q)+cfg.Cache.StaleWhileRevalidate {
+		delete(servedQuotes.quotes, key)
+		return quote{}, false
+	}
+	return q, true
+}
+
+// revalidate refreshes the cache entry of a quote key in the background,
+// unless a refresh is already running.
+func revalidate(key, symbol string, fetcher func() (interface{}, error)) {
+	servedQuotes.Lock()
+	if servedQuotes.refreshing[key] {
+		servedQuotes.Unlock()
+		return
+	}
+	servedQuotes.refreshing[key] = true
+	servedQuotes.Unlock()
+
+	go func() {
+		cfgMu.RLock()
+		defer cfgMu.RUnlock()
+		defer func() {
+			servedQuotes.Lock()
+			delete(servedQuotes.refreshing, key)
+			servedQuotes.Unlock()
+		}()
+
+		qret, err, _ := memoizeQuote(key, symbol, fetcher)
+		if err != nil {
+			errorCount.Inc()
+			logger("collector").Warn("Error refreshing stale quote", "symbol", symbol, "error", err)
+			return
+		}
+		q, ok := qret.(quote)
+		if !ok {
+			return
+		}
+		saveServedQuote(key, q)
+	}()
+}
@@ -0,0 +1,36 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"regexp"
+)
+
+// routeConfig routes symbols matching a pattern to a provider.
+type routeConfig struct {
+	// Pattern is a regular expression matched against symbols.
+	Pattern string `json:"pattern"`
+	// Provider is the name of the provider.
+	Provider string `json:"provider"`
+
+	re *regexp.Regexp
+}
+
+// routedProvider returns the provider of the first route matching a symbol.
+func routedProvider(symbol string) (string, bool) {
+	for _, r := range cfg.Routes {
+		if r.re != nil && r.re.MatchString(symbol) {
+			return r.Provider, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,160 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package scrape
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// currencySymbols maps currency symbols to ISO 4217 codes. Longer symbols
+// must be matched first, so "R$" is not mistaken for "$".
+var currencySymbols = []struct {
+	symbol   string
+	currency string
+}{
+	{"US$", "USD"},
+	{"R$", "BRL"},
+	{"C$", "CAD"},
+	{"A$", "AUD"},
+	{"NZ$", "NZD"},
+	{"HK$", "HKD"},
+	{"S$", "SGD"},
+	{"MX$", "MXN"},
+	{"$", "USD"},
+	{"€", "EUR"},
+	{"£", "GBP"},
+	{"¥", "JPY"},
+	{"₹", "INR"},
+	{"₩", "KRW"},
+	{"₽", "RUB"},
+	{"₺", "TRY"},
+	{"₿", "BTC"},
+	{"zł", "PLN"},
+	{"p", "GBp"},
+}
+
+// ParsePrice parses a price as shown on web pages and text APIs, like
+// "$1,234.56", "1.234,56 €", "R$ 34,12" or "CHF 1'234.50". It returns the
+// value and the currency detected in the text (an ISO 4217 code, or an empty
+// string if no currency was found). Separators are guessed from the text; use
+// ParsePriceDecimal when the decimal separator is known.
+func ParsePrice(s string) (float64, string, error) {
+	return ParsePriceDecimal(s, "")
+}
+
+// ParsePriceDecimal is like ParsePrice, using decimal ("." or ",") as the
+// decimal separator. An empty decimal guesses it from the text.
+func ParsePriceDecimal(s, decimal string) (float64, string, error) {
+	if decimal != "" && decimal != "." && decimal != "," {
+		return 0, "", fmt.Errorf("invalid decimal separator %q", decimal)
+	}
+	s = strings.TrimSpace(s)
+	sign := ""
+	if strings.HasPrefix(s, "-") || strings.HasPrefix(s, "+") {
+		sign, s = s[:1], s[1:]
+	}
+	num, currency := splitCurrency(s)
+	if num == "" {
+		return 0, "", fmt.Errorf("no number in price %q", s)
+	}
+
+	num = strings.Map(func(r rune) rune {
+		// Spaces and apostrophes are used as thousands separators.
+		if unicode.IsSpace(r) || r == '\'' || r == '’' {
+			return -1
+		}
+		return r
+	}, num)
+
+	v, err := strconv.ParseFloat(sign+normalizeSeparators(num, decimal), 64)
+	if err != nil {
+		return 0, "", fmt.Errorf("invalid price %q: %v", s, err)
+	}
+	return v, currency, nil
+}
+
+// splitCurrency removes a currency symbol or code from the start or end of a
+// price, returning the number and the currency.
+func splitCurrency(s string) (string, string) {
+	// Three letter codes (e.g. "EUR 12.30" or "12.30 EUR").
+	if len(s) > 4 && isCode(s[:3]) && !isDigit(s[3]) {
+		return strings.TrimSpace(s[3:]), s[:3]
+	}
+	if len(s) > 4 && isCode(s[len(s)-3:]) && !isDigit(s[len(s)-4]) {
+		return strings.TrimSpace(s[:len(s)-3]), s[len(s)-3:]
+	}
+
+	for _, cs := range currencySymbols {
+		if strings.HasPrefix(s, cs.symbol) {
+			return strings.TrimSpace(strings.TrimPrefix(s, cs.symbol)), cs.currency
+		}
+		if strings.HasSuffix(s, cs.symbol) {
+			return strings.TrimSpace(strings.TrimSuffix(s, cs.symbol)), cs.currency
+		}
+	}
+	return s, ""
+}
+
+// normalizeSeparators converts a number using any combination of decimal and
+// thousands separators to the format understood by strconv. The decimal
+// separator is guessed if decimal is empty.
+func normalizeSeparators(s, decimal string) string {
+	switch decimal {
+	case ".":
+		return strings.Replace(s, ",", "", -1)
+	case ",":
+		return strings.Replace(strings.Replace(s, ".", "", -1), ",", ".", 1)
+	}
+
+	dot, comma := strings.LastIndex(s, "."), strings.LastIndex(s, ",")
+	switch {
+	case dot >= 0 && comma >= 0:
+		// Both present: the last one is the decimal separator.
+		if comma > dot {
+			return strings.Replace(strings.Replace(s, ".", "", -1), ",", ".", 1)
+		}
+		return strings.Replace(s, ",", "", -1)
+
+	case comma >= 0:
+		// A single comma followed by other than three digits, or after a
+		// zero, is a decimal separator ("34,12" or "0,123"). Otherwise it
+		// separates thousands ("1,234"), which is ambiguous with prices
+		// like "12,500" in locales using a decimal comma.
+		if strings.Count(s, ",") == 1 && (len(s)-comma-1 != 3 || strings.TrimLeft(s[:comma], "0") == "") {
+			return strings.Replace(s, ",", ".", 1)
+		}
+		return strings.Replace(s, ",", "", -1)
+
+	case strings.Count(s, ".") > 1:
+		// Several dots can only separate thousands ("1.234.567").
+		return strings.Replace(s, ".", "", -1)
+	}
+	return s
+}
+
+// isCode returns true if s looks like an ISO 4217 currency code.
+func isCode(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < 'A' || s[i] > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+// isDigit returns true if b is an ASCII digit.
+func isDigit(b byte) bool {
+	return b >= '0' && b <= '9'
+}
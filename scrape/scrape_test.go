@@ -0,0 +1,60 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package scrape
+
+import "testing"
+
+func TestParsePriceDecimal(t *testing.T) {
+	tests := []struct {
+		s        string
+		decimal  string
+		want     float64
+		currency string
+	}{
+		{"1234.56", "", 1234.56, ""},
+		{"$1,234.56", "", 1234.56, "USD"},
+		{"1.234,56 €", "", 1234.56, "EUR"},
+		{"R$ 34,12", "", 34.12, "BRL"},
+		{"CHF 1'234.50", "", 1234.5, "CHF"},
+		{"1,234", "", 1234, ""},
+		{"1.234.567", "", 1234567, ""},
+		{"0,123", "", 0.123, ""},
+		{"-0,5", "", -0.5, ""},
+		{"12,500", "", 12500, ""},
+		{"12,500", ",", 12.5, ""},
+		{"12,500 EUR", ",", 12.5, "EUR"},
+		{"1.234", ",", 1234, ""},
+		{"1.234", ".", 1.234, ""},
+		{"1,234", ".", 1234, ""},
+	}
+	for _, tt := range tests {
+		got, currency, err := ParsePriceDecimal(tt.s, tt.decimal)
+		if err != nil {
+			t.Errorf("ParsePriceDecimal(%q, %q): unexpected error: %v", tt.s, tt.decimal, err)
+			continue
+		}
+		if got != tt.want || currency != tt.currency {
+			t.Errorf("ParsePriceDecimal(%q, %q) = %v, %q, want %v, %q", tt.s, tt.decimal, got, currency, tt.want, tt.currency)
+		}
+	}
+}
+
+func TestParsePriceErrors(t *testing.T) {
+	for _, s := range []string{"", "$", "abc", "1.2.3,4,5"} {
+		if _, _, err := ParsePrice(s); err == nil {
+			t.Errorf("ParsePrice(%q): expected an error", s)
+		}
+	}
+	if _, _, err := ParsePriceDecimal("1,5", ";"); err == nil {
+		t.Error("ParsePriceDecimal with an invalid separator: expected an error")
+	}
+}
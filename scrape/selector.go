@@ -0,0 +1,278 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package scrape
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+)
+
+var (
+	tagRE  = regexp.MustCompile(`^<(/?)([a-zA-Z][a-zA-Z0-9:-]*)((?:[^>"']|"[^"]*"|'[^']*')*)>`)
+	attrRE = regexp.MustCompile(`([^\s"'>/=]+)(?:\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'>]+)))?`)
+	// simpleRE matches the simple selectors of a compound selector.
+	simpleRE = regexp.MustCompile(`^(?:([a-zA-Z*][a-zA-Z0-9-]*)|#([\w-]+)|\.([\w-]+)|\[\s*([\w:-]+)\s*(?:=\s*(?:"([^"]*)"|'([^']*)'|([^\]\s]*)))?\s*\])`)
+)
+
+// voidElements never have content nor an end tag.
+var voidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true,
+	"hr": true, "img": true, "input": true, "link": true, "meta": true,
+	"param": true, "source": true, "track": true, "wbr": true,
+}
+
+// rawTextElements hold text that is not markup (and not page content).
+var rawTextElements = map[string]bool{"script": true, "style": true, "template": true}
+
+// compound is a compound selector, like div#main.price[data-field=nav].
+type compound struct {
+	tag     string
+	id      string
+	classes []string
+	attrs   []attrSelector
+	// child is true if the element must be a child (rather than any
+	// descendant) of the element matched by the previous compound.
+	child bool
+}
+
+// attrSelector matches an attribute, or its value if hasValue is true.
+type attrSelector struct {
+	name     string
+	value    string
+	hasValue bool
+}
+
+// Selector is a parsed CSS selector. Type, #id, .class, [attr] and
+// [attr=value] selectors are supported, joined by descendant and child (>)
+// combinators.
+type Selector []compound
+
+// element is an element open while parsing a document.
+type element struct {
+	tag   string
+	attrs map[string]string
+}
+
+// ParseSelector parses a CSS selector.
+func ParseSelector(s string) (Selector, error) {
+	var (
+		sel   Selector
+		child bool
+	)
+	for _, f := range strings.Fields(strings.Replace(s, ">", " > ", -1)) {
+		if f == ">" {
+			if len(sel) == 0 || child {
+				return nil, fmt.Errorf("misplaced > in selector %q", s)
+			}
+			child = true
+			continue
+		}
+		c := compound{child: child}
+		child = false
+		for rest := f; rest != ""; {
+			m := simpleRE.FindStringSubmatch(rest)
+			if m == nil {
+				return nil, fmt.Errorf("unsupported selector %q in %q", rest, s)
+			}
+			rest = rest[len(m[0]):]
+			switch {
+			case m[1] != "":
+				if c.tag != "" {
+					return nil, fmt.Errorf("two types in selector %q", f)
+				}
+				c.tag = strings.ToLower(m[1])
+			case m[2] != "":
+				c.id = m[2]
+			case m[3] != "":
+				c.classes = append(c.classes, m[3])
+			default:
+				a := attrSelector{name: strings.ToLower(m[4])}
+				if strings.Contains(m[0], "=") {
+					a.value, a.hasValue = m[5]+m[6]+m[7], true
+				}
+				c.attrs = append(c.attrs, a)
+			}
+		}
+		sel = append(sel, c)
+	}
+	if len(sel) == 0 || child {
+		return nil, fmt.Errorf("invalid selector %q", s)
+	}
+	return sel, nil
+}
+
+// Find returns the text and the attributes of the first element of an HTML
+// document matching the selector. Parsing is lenient: script and style
+// contents are skipped and stray end tags are ignored.
+func (s Selector) Find(doc string) (string, map[string]string, bool) {
+	var (
+		stack []element
+		// depth is the size of the stack when the matching element was
+		// opened, or zero before a match.
+		depth int
+		text  strings.Builder
+	)
+	for i := 0; i < len(doc); {
+		if doc[i] != '<' {
+			n := strings.IndexByte(doc[i:], '<')
+			if n < 0 {
+				n = len(doc) - i
+			}
+			if depth > 0 {
+				text.WriteString(doc[i : i+n])
+			}
+			i += n
+			continue
+		}
+
+		rest := doc[i:]
+		if strings.HasPrefix(rest, "<!--") {
+			end := strings.Index(rest, "-->")
+			if end < 0 {
+				break
+			}
+			i += end + 3
+			continue
+		}
+		m := tagRE.FindStringSubmatch(rest)
+		if m == nil {
+			// Doctype, processing instruction or a stray "<".
+			if strings.HasPrefix(rest, "<!") || strings.HasPrefix(rest, "<?") {
+				if end := strings.IndexByte(rest, '>'); end >= 0 {
+					i += end + 1
+					continue
+				}
+			}
+			if depth > 0 {
+				text.WriteByte('<')
+			}
+			i++
+			continue
+		}
+		i += len(m[0])
+		tag := strings.ToLower(m[2])
+
+		if m[1] == "/" {
+			// Close the innermost open element with this tag, if any.
+			for j := len(stack) - 1; j >= 0; j-- {
+				if stack[j].tag != tag {
+					continue
+				}
+				if depth > 0 && j < depth {
+					return collapse(text.String()), stack[depth-1].attrs, true
+				}
+				stack = stack[:j]
+				break
+			}
+			continue
+		}
+
+		e := element{tag: tag, attrs: parseAttrs(m[3])}
+		stack = append(stack, e)
+		if depth == 0 && s.matches(stack) {
+			depth = len(stack)
+		}
+		if voidElements[tag] || strings.HasSuffix(strings.TrimSpace(m[3]), "/") {
+			stack = stack[:len(stack)-1]
+			if depth > len(stack) {
+				return "", e.attrs, true
+			}
+			continue
+		}
+		if rawTextElements[tag] {
+			end := strings.Index(strings.ToLower(doc[i:]), "</"+tag)
+			if end < 0 {
+				break
+			}
+			i += end
+		}
+	}
+	if depth > 0 {
+		return collapse(text.String()), stack[depth-1].attrs, true
+	}
+	return "", nil, false
+}
+
+// matches returns true if the last element of the stack matches the
+// selector, given its ancestors.
+func (s Selector) matches(stack []element) bool {
+	return matchAt(s, len(s)-1, stack, len(stack)-1)
+}
+
+// matchAt matches compound i of the selector against element j of the stack,
+// then the previous compounds against its ancestors.
+func matchAt(s Selector, i int, stack []element, j int) bool {
+	if !s[i].matches(stack[j]) {
+		return false
+	}
+	if i == 0 {
+		return true
+	}
+	if s[i].child {
+		return j > 0 && matchAt(s, i-1, stack, j-1)
+	}
+	for k := j - 1; k >= 0; k-- {
+		if matchAt(s, i-1, stack, k) {
+			return true
+		}
+	}
+	return false
+}
+
+// matches returns true if an element matches the compound selector.
+func (c compound) matches(e element) bool {
+	if c.tag != "" && c.tag != "*" && c.tag != e.tag {
+		return false
+	}
+	if c.id != "" && e.attrs["id"] != c.id {
+		return false
+	}
+	classes := strings.Fields(e.attrs["class"])
+	for _, want := range c.classes {
+		found := false
+		for _, class := range classes {
+			if class == want {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	for _, a := range c.attrs {
+		v, ok := e.attrs[a.name]
+		if !ok || (a.hasValue && v != a.value) {
+			return false
+		}
+	}
+	return true
+}
+
+// parseAttrs parses the attributes of a start tag.
+func parseAttrs(s string) map[string]string {
+	attrs := map[string]string{}
+	for _, m := range attrRE.FindAllStringSubmatch(s, -1) {
+		name := strings.ToLower(m[1])
+		if _, ok := attrs[name]; !ok {
+			attrs[name] = html.UnescapeString(m[2] + m[3] + m[4])
+		}
+	}
+	return attrs
+}
+
+// collapse unescapes HTML text and collapses its white space.
+func collapse(s string) string {
+	return strings.Join(strings.Fields(html.UnescapeString(s)), " ")
+}
@@ -0,0 +1,58 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+)
+
+// targetGroup is a Prometheus http_sd target group.
+type targetGroup struct {
+	Targets []string          `json:"targets"`
+	Labels  map[string]string `json:"labels"`
+}
+
+// sdHandler handles the "/sd" endpoint. It returns one Prometheus http_sd
+// target group per watchlist, pointing at /price?list=NAME on this exporter.
+func sdHandler(w http.ResponseWriter, r *http.Request) {
+	target := cfg.Web.ExternalAddress
+	if target == "" {
+		target = r.Host
+	}
+
+	lists := watchlists.all()
+	var names []string
+	for name := range lists {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	// Always return a list, as Prometheus rejects a null response.
+	groups := []targetGroup{}
+	for _, name := range names {
+		groups = append(groups, targetGroup{
+			Targets: []string{target},
+			Labels: map[string]string{
+				"__metrics_path__": "/price",
+				"__param_list":     name,
+				"list":             name,
+			},
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(groups); err != nil {
+		logger("web").Error("Error encoding service discovery response", "error", err)
+	}
+}
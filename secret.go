@@ -0,0 +1,140 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A secret holds a credential (usually a provider API key). The value is
+// given by a spec, which can be:
+//
+//	file:PATH     The contents of a file (e.g. a mounted Kubernetes secret).
+//	exec:COMMAND  The output of a command (e.g. "exec:vault kv get -field=key secret/av").
+//	env:NAME      The value of an environment variable.
+//	anything else The literal value.
+//
+// File and exec secrets are re-resolved periodically, so keys can be rotated
+// without restarting the exporter.
+type secret struct {
+	sync.RWMutex
+	spec  string
+	value string
+}
+
+// secrets holds the secrets in use, by the part of the configuration they
+// belong to, for periodic refresh.
+var secrets = struct {
+	sync.Mutex
+	groups map[string][]*secret
+}{groups: map[string][]*secret{}}
+
+// newSecret returns a new secret for the given spec, resolving it
+// immediately. The secret is only refreshed by refreshSecrets once passed to
+// setSecrets.
+func newSecret(spec string) (*secret, error) {
+	s := &secret{spec: spec}
+	if err := s.refresh(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// setSecrets replaces the secrets of a part of the configuration (e.g.
+// "providers"), so secrets no longer in use stop being refreshed.
+func setSecrets(group string, list []*secret) {
+	secrets.Lock()
+	defer secrets.Unlock()
+	secrets.groups[group] = list
+}
+
+// Get returns the current value of the secret.
+func (s *secret) Get() string {
+	s.RLock()
+	defer s.RUnlock()
+	return s.value
+}
+
+// dynamic returns true if the secret value may change over time.
+func (s *secret) dynamic() bool {
+	return strings.HasPrefix(s.spec, "file:") || strings.HasPrefix(s.spec, "exec:")
+}
+
+// refresh resolves the secret spec and updates the value. On error, the
+// previous value is kept.
+func (s *secret) refresh() error {
+	var value string
+	switch {
+	case strings.HasPrefix(s.spec, "file:"):
+		data, err := os.ReadFile(strings.TrimPrefix(s.spec, "file:"))
+		if err != nil {
+			return err
+		}
+		value = string(data)
+	case strings.HasPrefix(s.spec, "exec:"):
+		args := strings.Fields(strings.TrimPrefix(s.spec, "exec:"))
+		if len(args) == 0 {
+			return fmt.Errorf("empty command in secret")
+		}
+		out, err := exec.Command(args[0], args[1:]...).Output()
+		if err != nil {
+			return fmt.Errorf("running %s: %v", args[0], err)
+		}
+		value = string(out)
+	case strings.HasPrefix(s.spec, "env:"):
+		value = os.Getenv(strings.TrimPrefix(s.spec, "env:"))
+	default:
+		value = s.spec
+	}
+
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return fmt.Errorf("secret resolved to an empty value")
+	}
+
+	s.Lock()
+	defer s.Unlock()
+	s.value = value
+	return nil
+}
+
+// refreshSecrets re-resolves all file and exec secrets every interval.
+// It never returns.
+func refreshSecrets(interval time.Duration) {
+	for range time.Tick(interval) {
+		var list []*secret
+		secrets.Lock()
+		for _, g := range secrets.groups {
+			list = append(list, g...)
+		}
+		secrets.Unlock()
+
+		for _, s := range list {
+			if !s.dynamic() {
+				continue
+			}
+			old := s.Get()
+			if err := s.refresh(); err != nil {
+				logger("secrets").Error("Error refreshing secret, keeping previous value", "error", err)
+				continue
+			}
+			if s.Get() != old {
+				logger("secrets").Info("Secret rotated")
+			}
+		}
+	}
+}
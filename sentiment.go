@@ -0,0 +1,50 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"context"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/marcopaganini/quotes-exporter/sentiment"
+)
+
+// collectSentiment emits the current value of each configured sentiment
+// index.
+func collectSentiment(ch chan<- prometheus.Metric) {
+	ls := []string{"index", "classification"}
+	desc := prometheus.NewDesc("quotes_exporter_sentiment_index", "Market sentiment index (0 = extreme fear, 100 = extreme greed).", ls, nil)
+
+	for _, name := range cfg.Metrics.Sentiment {
+		name := name
+		log := logger("sentiment").With("index", name)
+		fetcher := func() (interface{}, error) {
+			ctx, cancel := upstreamContext(context.Background())
+			defer cancel()
+			return sentiment.Index(ctx, name)
+		}
+		sret, err, _ := memoized("sentiment:"+name, fetcher)
+		if err != nil {
+			errorCount.Inc()
+			log.Error("Error fetching sentiment index", "error", err)
+			continue
+		}
+		r, ok := sret.(sentiment.Reading)
+		if !ok {
+			errorCount.Inc()
+			log.Error("Invalid sentiment data", "data", sret)
+			continue
+		}
+		log.Info("Retrieved sentiment index", "value", r.Value, "classification", r.Classification)
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, r.Value, name, r.Classification)
+	}
+}
@@ -0,0 +1,128 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package sentiment
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/marcopaganini/quotes-exporter/provider"
+)
+
+const (
+	cryptoURL = "https://api.alternative.me/fng/?limit=1"
+	cnnURL    = "https://production.dataviz.cnn.io/index/fearandgreed/graphdata"
+
+	// CNN rejects requests carrying the default Go User-Agent.
+	userAgent = "Mozilla/5.0 (X11; Linux x86_64; rv:109.0) Gecko/20100101 Firefox/115.0"
+)
+
+// Reading holds the value (0-100) and classification of a sentiment index.
+type Reading struct {
+	Value          float64
+	Classification string
+}
+
+// indices maps index names to the functions fetching them.
+var indices = map[string]func(context.Context) (Reading, error){
+	"crypto": crypto,
+	"cnn":    cnn,
+}
+
+// Names returns the names of all supported indices, sorted.
+func Names() []string {
+	var ret []string
+	for k := range indices {
+		ret = append(ret, k)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// Valid returns true if name is a supported index.
+func Valid(name string) bool {
+	_, ok := indices[name]
+	return ok
+}
+
+// Index returns the current reading of a sentiment index by name.
+func Index(ctx context.Context, name string) (Reading, error) {
+	fetch, ok := indices[name]
+	if !ok {
+		return Reading{}, fmt.Errorf("unknown sentiment index %q (valid: %s)", name, strings.Join(Names(), ","))
+	}
+	return fetch(ctx)
+}
+
+// crypto returns the alternative.me crypto Fear & Greed index.
+func crypto(ctx context.Context) (Reading, error) {
+	var r struct {
+		Data []struct {
+			Value          string `json:"value"`
+			Classification string `json:"value_classification"`
+		} `json:"data"`
+	}
+	if err := getJSON(ctx, cryptoURL, &r); err != nil {
+		return Reading{}, err
+	}
+	if len(r.Data) == 0 {
+		return Reading{}, fmt.Errorf("empty results from upstream")
+	}
+	val, err := strconv.ParseFloat(r.Data[0].Value, 64)
+	if err != nil {
+		return Reading{}, err
+	}
+	return Reading{Value: val, Classification: r.Data[0].Classification}, nil
+}
+
+// cnn returns the CNN (stock market) Fear & Greed index.
+func cnn(ctx context.Context) (Reading, error) {
+	var r struct {
+		FearAndGreed struct {
+			Score  float64 `json:"score"`
+			Rating string  `json:"rating"`
+		} `json:"fear_and_greed"`
+	}
+	if err := getJSON(ctx, cnnURL, &r); err != nil {
+		return Reading{}, err
+	}
+	if r.FearAndGreed.Rating == "" {
+		return Reading{}, fmt.Errorf("empty results from upstream")
+	}
+	return Reading{Value: r.FearAndGreed.Score, Classification: r.FearAndGreed.Rating}, nil
+}
+
+// getJSON fetches a URL and decodes the JSON response into v.
+func getJSON(ctx context.Context, u string, v interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("User-Agent", userAgent)
+
+	resp, err := provider.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("upstream returned HTTP %d", resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
@@ -0,0 +1,43 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// quoteAgeDesc describes the age of the quote exported for a symbol.
+var quoteAgeDesc = prometheus.NewDesc(
+	"quotes_exporter_quote_age_seconds",
+	"Age of the quote at collection time.",
+	[]string{"symbol"}, nil,
+)
+
+// snapshot returns a channel that forwards metrics to ch stamped with the
+// collection time ts, so all series in a scrape refer to the same moment. The
+// returned function must be called once all metrics have been sent.
+func snapshot(ch chan<- prometheus.Metric, ts time.Time) (chan<- prometheus.Metric, func()) {
+	out := make(chan prometheus.Metric)
+	done := make(chan struct{})
+	go func() {
+		for m := range out {
+			ch <- prometheus.NewMetricWithTimestamp(ts, m)
+		}
+		close(done)
+	}()
+	return out, func() {
+		close(out)
+		<-done
+	}
+}
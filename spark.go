@@ -0,0 +1,91 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/marcopaganini/quotes-exporter/yahoo"
+)
+
+// sparkPoint is a price point in the /spark response.
+type sparkPoint struct {
+	Timestamp int64   `json:"timestamp"`
+	Price     float64 `json:"price"`
+}
+
+// sparkHandler handles the "/spark" endpoint. It returns the intraday prices
+// of ?symbol= over ?range= (default 1d) at ?interval= (default 5m) as JSON,
+// for lightweight web widgets.
+func sparkHandler(w http.ResponseWriter, r *http.Request) {
+	log := logger("web").With("url", redactedURL(r), "remote", r.RemoteAddr)
+
+	q := r.URL.Query()
+	symbol, rng, interval := q.Get("symbol"), strings.ToLower(q.Get("range")), strings.ToLower(q.Get("interval"))
+	if symbol == "" {
+		http.Error(w, "missing symbol", http.StatusBadRequest)
+		return
+	}
+	if rng == "" {
+		rng = "1d"
+	}
+	if interval == "" {
+		interval = "5m"
+	}
+	if !containsFold(yahoo.Ranges, rng) {
+		http.Error(w, fmt.Sprintf("invalid range %q (valid: %s)", rng, strings.Join(yahoo.Ranges, ",")), http.StatusBadRequest)
+		return
+	}
+	if !containsFold(yahoo.Intervals, interval) {
+		http.Error(w, fmt.Sprintf("invalid interval %q (valid: %s)", interval, strings.Join(yahoo.Intervals, ",")), http.StatusBadRequest)
+		return
+	}
+	if !authorizeSymbols(w, r, []string{symbol}) {
+		return
+	}
+
+	fetcher := func() (interface{}, error) {
+		ctx, cancel := upstreamContext(r.Context())
+		defer cancel()
+		return yahoo.Intraday(ctx, yahooSymbol(symbol), rng, interval)
+	}
+	sret, err, _ := memoized(fmt.Sprintf("spark:%s:%s:%s", symbol, rng, interval), fetcher)
+	if err != nil {
+		log.Error("Error fetching intraday prices", "provider", "yahoo", "symbol", symbol, "error", err)
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	closes, ok := sret.([]yahoo.Close)
+	if !ok {
+		http.Error(w, "invalid intraday data", http.StatusInternalServerError)
+		return
+	}
+
+	points := []sparkPoint{}
+	for _, c := range closes {
+		points = append(points, sparkPoint{Timestamp: c.Time.Unix(), Price: c.Price})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	resp := struct {
+		Symbol   string       `json:"symbol"`
+		Range    string       `json:"range"`
+		Interval string       `json:"interval"`
+		Points   []sparkPoint `json:"points"`
+	}{symbol, rng, interval, points}
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		log.Error("Error encoding JSON response", "error", err)
+	}
+}
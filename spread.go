@@ -0,0 +1,91 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/marcopaganini/quotes-exporter/exchange"
+)
+
+var (
+	exchangePriceDesc = prometheus.NewDesc(
+		"quotes_exporter_exchange_price",
+		"Last price of a crypto pair on an exchange.",
+		[]string{"symbol", "name", "exchange"}, nil,
+	)
+	exchangeDeviationDesc = prometheus.NewDesc(
+		"quotes_exporter_exchange_deviation_percent",
+		"Deviation of the price on an exchange from the median of all exchanges, in percent.",
+		[]string{"symbol", "name", "exchange"}, nil,
+	)
+	exchangeSpreadDesc = prometheus.NewDesc(
+		"quotes_exporter_exchange_spread_percent",
+		"Difference between the highest and lowest exchange prices, relative to the median, in percent.",
+		[]string{"symbol", "name"}, nil,
+	)
+)
+
+// collectSpread emits the price of a crypto pair on each configured exchange,
+// along with the deviation of each price from the median and the spread
+// between exchanges. Symbols that are not crypto pairs are ignored.
+func collectSpread(ch chan<- prometheus.Metric, symbol string) {
+	base, quote, ok := exchange.Pair(upstreamSymbol(symbol))
+	if !ok {
+		return
+	}
+
+	prices := map[string]float64{}
+	for _, ex := range cfg.Metrics.Exchanges {
+		ex := ex
+		fetcher := func() (interface{}, error) {
+			ctx, cancel := upstreamContext(context.Background())
+			defer cancel()
+			return exchange.Price(ctx, ex, base, quote)
+		}
+		pret, err, _ := memoized(fmt.Sprintf("exchange:%s:%s-%s", ex, base, quote), fetcher)
+		if err != nil {
+			errorCount.Inc()
+			logger("exchange").Error("Error fetching exchange price", "provider", ex, "symbol", symbol, "error", err)
+			continue
+		}
+		price, ok := pret.(float64)
+		if !ok {
+			continue
+		}
+		prices[ex] = price
+		ch <- prometheus.MustNewConstMetric(exchangePriceDesc, prometheus.GaugeValue, price, symbol, displayName(symbol), ex)
+	}
+
+	// Deviations need at least two prices to be meaningful.
+	if len(prices) < 2 {
+		return
+	}
+	var sorted []float64
+	for _, p := range prices {
+		sorted = append(sorted, p)
+	}
+	sort.Float64s(sorted)
+	median := sorted[len(sorted)/2]
+	if len(sorted)%2 == 0 {
+		median = (sorted[len(sorted)/2-1] + sorted[len(sorted)/2]) / 2
+	}
+
+	for ex, p := range prices {
+		ch <- prometheus.MustNewConstMetric(exchangeDeviationDesc, prometheus.GaugeValue, (p-median)/median*100, symbol, displayName(symbol), ex)
+	}
+	ch <- prometheus.MustNewConstMetric(exchangeSpreadDesc, prometheus.GaugeValue, (sorted[len(sorted)-1]-sorted[0])/median*100, symbol, displayName(symbol))
+}
@@ -0,0 +1,62 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"context"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/marcopaganini/quotes-exporter/staking"
+)
+
+// stakingAPY holds a staking yield and where it came from.
+type stakingAPY struct {
+	apy    float64
+	source string
+}
+
+// collectStaking emits the current staking APY of proof-of-stake assets.
+// Symbols without a known staking yield source are silently ignored.
+func collectStaking(ch chan<- prometheus.Metric, symbol string) {
+	asset, ok := staking.Asset(upstreamSymbol(symbol))
+	if !ok {
+		return
+	}
+
+	// Yields are per asset, so cache them by asset, not symbol.
+	fetcher := func() (interface{}, error) {
+		ctx, cancel := upstreamContext(context.Background())
+		defer cancel()
+		apy, source, err := staking.APY(ctx, asset)
+		return stakingAPY{apy, source}, err
+	}
+	sret, err, _ := memoized("staking:"+asset, fetcher)
+	if err != nil {
+		errorCount.Inc()
+		logger("staking").Error("Error fetching staking APY", "symbol", symbol, "asset", asset, "error", err)
+		return
+	}
+	s, ok := sret.(stakingAPY)
+	if !ok {
+		errorCount.Inc()
+		logger("staking").Error("Invalid staking data", "symbol", symbol, "asset", asset, "data", sret)
+		return
+	}
+
+	ls := []string{"symbol", "name", "source"}
+	ch <- prometheus.MustNewConstMetric(
+		prometheus.NewDesc("quotes_exporter_staking_apy_percent", "Current staking APY, in percent.", ls, nil),
+		prometheus.GaugeValue,
+		s.apy,
+		symbol, displayName(symbol), s.source,
+	)
+}
@@ -0,0 +1,113 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+// Package staking fetches staking yields of proof-of-stake crypto assets
+// from the liquid staking protocols that publish them.
+package staking
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+
+	"github.com/marcopaganini/quotes-exporter/provider"
+)
+
+const (
+	lidoURL     = "https://eth-api.lido.fi/v1/protocol/steth/apr/sma"
+	marinadeURL = "https://api.marinade.finance/msol/apy/30d"
+)
+
+// source fetches the staking APY of an asset, in percent.
+type source struct {
+	name  string
+	fetch func(context.Context) (float64, error)
+}
+
+// sources maps asset tickers to the protocol publishing their staking yield.
+var sources = map[string]source{
+	"ETH": {"lido", lido},
+	"SOL": {"marinade", marinade},
+}
+
+// Asset returns the asset ticker of a crypto symbol ("ETH-USD" -> "ETH") and
+// whether it has a known staking yield source.
+func Asset(symbol string) (string, bool) {
+	asset := strings.ToUpper(strings.SplitN(symbol, "-", 2)[0])
+	_, ok := sources[asset]
+	return asset, ok
+}
+
+// APY returns the current staking APY of an asset, in percent, and the name
+// of the source it was obtained from.
+func APY(ctx context.Context, asset string) (float64, string, error) {
+	src, ok := sources[strings.ToUpper(asset)]
+	if !ok {
+		return 0, "", fmt.Errorf("no staking yield source for %s", asset)
+	}
+	apy, err := src.fetch(ctx)
+	if err != nil {
+		return 0, "", fmt.Errorf("%s: %v", src.name, err)
+	}
+	return apy, src.name, nil
+}
+
+// lido returns the stETH APY, derived from the 7-day moving average APR.
+func lido(ctx context.Context) (float64, error) {
+	var r struct {
+		Data struct {
+			SMAApr float64 `json:"smaApr"`
+		} `json:"data"`
+	}
+	if err := getJSON(ctx, lidoURL, &r); err != nil {
+		return 0, err
+	}
+	if r.Data.SMAApr == 0 {
+		return 0, fmt.Errorf("missing APR in response")
+	}
+	// stETH rebases daily, so compound the APR daily.
+	return (math.Pow(1+r.Data.SMAApr/100/365, 365) - 1) * 100, nil
+}
+
+// marinade returns the mSOL APY over the last 30 days.
+func marinade(ctx context.Context) (float64, error) {
+	var r struct {
+		Value float64 `json:"value"`
+	}
+	if err := getJSON(ctx, marinadeURL, &r); err != nil {
+		return 0, err
+	}
+	if r.Value == 0 {
+		return 0, fmt.Errorf("missing APY in response")
+	}
+	return r.Value * 100, nil
+}
+
+// getJSON fetches a URL and decodes the JSON response into v.
+func getJSON(ctx context.Context, u string, v interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := provider.Client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("upstream returned HTTP %d", resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
@@ -0,0 +1,51 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/marcopaganini/quotes-exporter/yahoo"
+)
+
+// Actions for quotes older than quote.max-age.
+const (
+	staleDrop  = "drop"
+	staleLabel = "label"
+	staleGauge = "gauge"
+)
+
+// staleActions holds the valid values of quote.stale-action.
+var staleActions = []string{staleDrop, staleLabel, staleGauge}
+
+// quoteSuccessDesc describes whether a fresh quote was retrieved for a symbol.
+var quoteSuccessDesc = prometheus.NewDesc(
+	"quotes_exporter_quote_success",
+	"Whether a fresh quote was retrieved (1) or the quote is stale (0).",
+	[]string{"symbol"}, nil,
+)
+
+// marketTime returns the time of the last trade in a Yahoo quote.
+func marketTime(meta yahoo.Meta) time.Time {
+	if meta.RegularMarketTime == 0 {
+		return time.Time{}
+	}
+	return time.Unix(meta.RegularMarketTime, 0)
+}
+
+// stale returns true if the last trade of a quote is older than maxAge.
+// Quotes from providers that don't report the market time are never stale.
+func (q quote) stale(maxAge time.Duration) bool {
+	return !q.Time.IsZero() && time.Since(q.Time) > maxAge
+}
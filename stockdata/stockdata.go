@@ -0,0 +1,127 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package stockdata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/marcopaganini/quotes-exporter/provider"
+)
+
+const (
+	quoteURL = "https://api.stockdata.org/v1/data/quote?symbols=%s&api_token=%s"
+
+	// maxBatch is the maximum number of symbols in a single quote request
+	// (the limit of the free plan).
+	maxBatch = 3
+)
+
+// quote is a quote returned by the quote API.
+type quote struct {
+	Ticker             string  `json:"ticker"`
+	Name               string  `json:"name"`
+	Currency           string  `json:"currency"`
+	Price              float64 `json:"price"`
+	DayOpen            float64 `json:"day_open"`
+	DayHigh            float64 `json:"day_high"`
+	DayLow             float64 `json:"day_low"`
+	PreviousClosePrice float64 `json:"previous_close_price"`
+	Volume             float64 `json:"volume"`
+	MarketCap          float64 `json:"market_cap"`
+	// DayChange is the change from the previous close, in percent.
+	DayChange     float64 `json:"day_change"`
+	LastTradeTime string  `json:"last_trade_time"`
+}
+
+// quoteResponse is the response of the quote API.
+type quoteResponse struct {
+	Data  []quote `json:"data"`
+	Error *struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// Provider is the StockData.org quote provider, the successor of
+// WorldTradingData.
+type Provider struct {
+	// Key returns the API token.
+	Key func() string
+	// Limiter limits the requests sent upstream.
+	Limiter *provider.Limiter
+}
+
+// Quote returns the quotes of the given symbols, using as few requests as
+// possible.
+func (p *Provider) Quote(ctx context.Context, symbols []string) ([]provider.Quote, error) {
+	var key string
+	if p.Key != nil {
+		key = p.Key()
+	}
+	key = provider.Token(ctx, key)
+	if key == "" {
+		return nil, errors.New("missing StockData.org API token")
+	}
+
+	var ret []provider.Quote
+	for len(symbols) > 0 {
+		n := len(symbols)
+		if n > maxBatch {
+			n = maxBatch
+		}
+		batch := symbols[:n]
+		symbols = symbols[n:]
+
+		if err := p.Limiter.Allow(); err != nil {
+			return nil, err
+		}
+		var resp quoteResponse
+		u := fmt.Sprintf(quoteURL, url.QueryEscape(strings.ToUpper(strings.Join(batch, ","))), url.QueryEscape(key))
+		if err := provider.GetJSON(ctx, u, nil, &resp); err != nil {
+			return nil, err
+		}
+		if e := resp.Error; e != nil {
+			return nil, fmt.Errorf("StockData.org error: %s: %s", e.Code, e.Message)
+		}
+
+		for _, q := range resp.Data {
+			if q.Price == 0 {
+				continue
+			}
+			pq := provider.Quote{
+				Symbol:        q.Ticker,
+				Price:         q.Price,
+				Currency:      strings.ToUpper(q.Currency),
+				Name:          q.Name,
+				Open:          q.DayOpen,
+				High:          q.DayHigh,
+				Low:           q.DayLow,
+				PreviousClose: q.PreviousClosePrice,
+				Volume:        q.Volume,
+				MarketCap:     q.MarketCap,
+				ChangePercent: q.DayChange,
+			}
+			// Trade times without a time zone (local to the exchange) are
+			// ignored.
+			if t, err := time.Parse(time.RFC3339, q.LastTradeTime); err == nil {
+				pq.Time = t
+			}
+			ret = append(ret, pq)
+		}
+	}
+	return ret, nil
+}
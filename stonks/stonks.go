@@ -12,25 +12,34 @@
 package stonks
 
 import (
+	"context"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
 	"strconv"
 	"strings"
+
+	"github.com/marcopaganini/quotes-exporter/provider"
+	"github.com/marcopaganini/quotes-exporter/scrape"
 )
 
 const (
 	stonksURL = "https://stonks.scd31.com/%s?f=i3"
 )
 
-// Quote returns the current value of a symbol.
-func Quote(symbol string) (float64, error) {
+// quote returns the current value of a symbol, its currency (empty if upstream
+// doesn't show it) and the daily change in percent.
+func quote(ctx context.Context, symbol string) (provider.Quote, error) {
 	symbol = strings.ToUpper(symbol)
 
-	resp, err := http.Get(fmt.Sprintf(stonksURL, symbol))
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf(stonksURL, symbol), nil)
+	if err != nil {
+		return provider.Quote{}, err
+	}
+	resp, err := provider.Client.Do(req.WithContext(ctx))
 	if err != nil {
-		return 0, err
+		return provider.Quote{}, err
 	}
 	defer resp.Body.Close()
 
@@ -39,33 +48,48 @@ func Quote(symbol string) (float64, error) {
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return 0, err
+		return provider.Quote{}, err
 	}
 	// Remove DOS CRLF cruft from output.
 	result := strings.Split(string(body), "\n")[0]
 	result = strings.TrimRight(result, "\r\n")
-	log.Printf("Results from scd31: %+v", result)
+	slog.Debug("Results from scd31", "component", "stonks", "provider", "stonks", "symbol", symbol, "result", result)
 
 	if result == "" {
-		return 0, fmt.Errorf("empty results from upstream: %v", result)
+		return provider.Quote{}, fmt.Errorf("empty results from upstream: %v", result)
 	}
 	if !strings.HasPrefix(result, symbol+":") {
-		return 0, fmt.Errorf("missing symbol name on output (invalid symbol?): %v", result)
+		return provider.Quote{}, fmt.Errorf("%w: missing symbol name on output: %v", provider.ErrNotFound, result)
 	}
 
-	// Parse price from input.
-	tok := strings.Split(result, " ")
-	if len(tok) < 2 {
-		return 0, fmt.Errorf("error parsing quote results: %v", result)
+	// Split the daily change from the price. The price itself may contain
+	// spaces (e.g. "R$ 34,12").
+	strval := strings.TrimSpace(strings.TrimPrefix(result, symbol+":"))
+	var change float64
+	if i := strings.LastIndex(strval, " "); i > 0 && strings.HasSuffix(strval, "%") {
+		// An unparseable change is not worth failing the quote for.
+		change, _ = strconv.ParseFloat(strings.TrimSuffix(strval[i+1:], "%"), 64)
+		strval = strval[:i]
 	}
-	strval := strings.Trim(tok[1], "$,")
 
-	val, err := strconv.ParseFloat(strval, 64)
+	val, currency, err := scrape.ParsePrice(strval)
 	if err != nil {
-		return 0, err
+		return provider.Quote{}, err
 	}
 	if val == 0 {
-		return 0, fmt.Errorf("query returned price=0: %v", result)
+		return provider.Quote{}, fmt.Errorf("query returned price=0: %v", result)
 	}
-	return val, nil
+	return provider.Quote{Symbol: symbol, Price: val, Currency: currency, ChangePercent: change}, nil
+}
+
+// Provider is the stonks quote provider.
+type Provider struct{}
+
+// Quote returns the quotes of the given symbols.
+func (Provider) Quote(ctx context.Context, symbols []string) ([]provider.Quote, error) {
+	return provider.Each(ctx, symbols, func(ctx context.Context, symbol string) (provider.Quote, error) {
+		q, err := quote(ctx, symbol)
+		q.Symbol = symbol
+		return q, err
+	})
 }
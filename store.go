@@ -0,0 +1,131 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/marcopaganini/quotes-exporter/yahoo"
+)
+
+// The local history store keeps the daily closes of each symbol in a CSV file
+// named after the symbol under the history directory. Each line holds a date
+// (YYYY-MM-DD) and the closing price.
+
+const storeDateFormat = "2006-01-02"
+
+// storeMu serializes access to the history store files.
+var storeMu sync.Mutex
+
+// storePath returns the name of the history store file for a symbol.
+func storePath(symbol string) string {
+	return filepath.Join(cfg.History.Dir, url.PathEscape(strings.ToUpper(symbol))+".csv")
+}
+
+// loadHistory returns the daily closes of a symbol from the history store,
+// oldest first.
+func loadHistory(symbol string) ([]yahoo.Close, error) {
+	storeMu.Lock()
+	defer storeMu.Unlock()
+	return readStore(storePath(symbol))
+}
+
+// saveHistory merges the given daily closes into the history store. Closes
+// for dates already in the store are replaced.
+func saveHistory(symbol string, closes []yahoo.Close) error {
+	storeMu.Lock()
+	defer storeMu.Unlock()
+
+	fname := storePath(symbol)
+	old, err := readStore(fname)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	byDate := map[string]float64{}
+	for _, c := range old {
+		byDate[c.Time.Format(storeDateFormat)] = c.Price
+	}
+	for _, c := range closes {
+		byDate[c.Time.UTC().Format(storeDateFormat)] = c.Price
+	}
+
+	var dates []string
+	for d := range byDate {
+		dates = append(dates, d)
+	}
+	sort.Strings(dates)
+
+	if err := os.MkdirAll(cfg.History.Dir, 0755); err != nil {
+		return err
+	}
+	// Write to a temporary file and rename, so readers never see partial data.
+	tmp, err := os.CreateTemp(cfg.History.Dir, ".history-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	w := csv.NewWriter(tmp)
+	for _, d := range dates {
+		w.Write([]string{d, strconv.FormatFloat(byDate[d], 'f', -1, 64)})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), fname)
+}
+
+// readStore reads a history store file. Must be called with storeMu held.
+func readStore(fname string) ([]yahoo.Close, error) {
+	f, err := os.Open(fname)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", fname, err)
+	}
+
+	var ret []yahoo.Close
+	for _, r := range records {
+		if len(r) != 2 {
+			return nil, fmt.Errorf("%s: invalid record: %v", fname, r)
+		}
+		t, err := time.Parse(storeDateFormat, r[0])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", fname, err)
+		}
+		price, err := strconv.ParseFloat(r[1], 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", fname, err)
+		}
+		ret = append(ret, yahoo.Close{Time: t, Price: price})
+	}
+	return ret, nil
+}
@@ -0,0 +1,154 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/marcopaganini/quotes-exporter/provider"
+)
+
+// tenantConfig holds the configuration of an access token for /price.
+type tenantConfig struct {
+	// Name identifies the token in logs.
+	Name string `json:"name"`
+	// Token is the bearer token (or a secret reference, e.g. file:PATH).
+	Token string `json:"token"`
+	// Watchlists and Symbols the token gives access to. Use "*" in Symbols
+	// to allow all symbols.
+	Watchlists []string `json:"watchlists"`
+	Symbols    []string `json:"symbols"`
+	// RateLimit is the maximum number of requests per minute (0 = unlimited).
+	RateLimit float64 `json:"rate_limit"`
+	// Burst is the number of requests allowed above the rate limit.
+	Burst int `json:"burst"`
+}
+
+// tenant holds a runtime access token.
+type tenant struct {
+	tenantConfig
+	token   *secret
+	limiter *provider.Limiter
+}
+
+// tenants holds the access tokens. When empty, /price requires no
+// authentication.
+var tenants []*tenant
+
+// loadTenants creates the runtime tenants from their configuration.
+func loadTenants(configs []tenantConfig) error {
+	tenants = nil
+	var list []*secret
+	for _, tc := range configs {
+		s, err := newSecret(tc.Token)
+		if err != nil {
+			return fmt.Errorf("token %q: %v", tc.Name, err)
+		}
+		list = append(list, s)
+		t := &tenant{tenantConfig: tc, token: s}
+		t.limiter = sharedLimiter("tenant:"+tc.Name, tc.RateLimit, tc.Burst)
+		tenants = append(tenants, t)
+	}
+	setSecrets("tenants", list)
+	return nil
+}
+
+// validateTenants checks the access token configuration.
+func validateTenants(configs []tenantConfig) error {
+	seen := map[string]bool{}
+	for i, tc := range configs {
+		if tc.Name == "" {
+			return fmt.Errorf("token #%d has no name", i+1)
+		}
+		if seen[tc.Name] {
+			return fmt.Errorf("duplicate token name %q", tc.Name)
+		}
+		seen[tc.Name] = true
+		if tc.Token == "" {
+			return fmt.Errorf("token %q has no token value", tc.Name)
+		}
+		if len(tc.Watchlists) == 0 && len(tc.Symbols) == 0 {
+			return fmt.Errorf("token %q gives access to no symbols", tc.Name)
+		}
+	}
+	return nil
+}
+
+// authenticate returns the tenant matching the bearer token in the request,
+// or nil if there's none.
+func authenticate(r *http.Request) *tenant {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Bearer ") {
+		return nil
+	}
+	token := []byte(strings.TrimPrefix(auth, "Bearer "))
+	for _, t := range tenants {
+		if subtle.ConstantTimeCompare(token, []byte(t.token.Get())) == 1 {
+			return t
+		}
+	}
+	return nil
+}
+
+// allowed returns true if the tenant can access the symbol.
+func (t *tenant) allowed(symbol string) bool {
+	if containsFold(t.Symbols, "*") || containsFold(t.Symbols, symbol) {
+		return true
+	}
+	for _, name := range t.Watchlists {
+		symbols, _ := watchlists.get(name)
+		if containsFold(symbols, symbol) {
+			return true
+		}
+	}
+	return false
+}
+
+// authorizeSymbols checks if the request may fetch the given symbols,
+// writing an error response and returning false if not.
+func authorizeSymbols(w http.ResponseWriter, r *http.Request, symbols []string) bool {
+	if len(tenants) == 0 {
+		return true
+	}
+	log := logger("web").With("url", redactedURL(r), "remote", r.RemoteAddr)
+
+	t := authenticate(r)
+	if t == nil {
+		log.Warn("Missing or invalid access token")
+		w.Header().Set("WWW-Authenticate", `Bearer realm="quotes-exporter"`)
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return false
+	}
+	log = log.With("tenant", t.Name)
+
+	if t.limiter.Allow() != nil {
+		log.Warn("Rate limit exceeded")
+		http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
+		return false
+	}
+
+	var forbidden []string
+	for _, s := range symbols {
+		if !t.allowed(s) {
+			forbidden = append(forbidden, s)
+		}
+	}
+	if len(forbidden) > 0 {
+		log.Warn("Access denied to symbols", "symbols", forbidden)
+		http.Error(w, "access denied to symbols: "+strings.Join(forbidden, ","), http.StatusForbidden)
+		return false
+	}
+	return true
+}
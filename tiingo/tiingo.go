@@ -0,0 +1,152 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package tiingo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/marcopaganini/quotes-exporter/provider"
+)
+
+const (
+	iexURL = "https://api.tiingo.com/iex/?tickers=%s"
+	eodURL = "https://api.tiingo.com/tiingo/daily/%s/prices"
+)
+
+// iexQuote is an entry in the response of the IEX API.
+type iexQuote struct {
+	Ticker    string    `json:"ticker"`
+	TngoLast  float64   `json:"tngoLast"`
+	Open      float64   `json:"open"`
+	High      float64   `json:"high"`
+	Low       float64   `json:"low"`
+	PrevClose float64   `json:"prevClose"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// eodPrice is an entry in the response of the end-of-day API.
+type eodPrice struct {
+	Date  time.Time `json:"date"`
+	Close float64   `json:"close"`
+	Open  float64   `json:"open"`
+	High  float64   `json:"high"`
+	Low   float64   `json:"low"`
+}
+
+// Provider is the Tiingo quote provider.
+type Provider struct {
+	// Key returns the API key.
+	Key func() string
+	// Limiter limits the requests sent upstream.
+	Limiter *provider.Limiter
+}
+
+// Quote returns the quotes of the given symbols. Symbols traded on IEX are
+// fetched in one request; others (e.g. mutual funds) use the latest
+// end-of-day price, one request per symbol.
+func (p *Provider) Quote(ctx context.Context, symbols []string) ([]provider.Quote, error) {
+	var key string
+	if p.Key != nil {
+		key = p.Key()
+	}
+	key = provider.Token(ctx, key)
+	if key == "" {
+		return nil, errors.New("missing Tiingo API key")
+	}
+	header := http.Header{"Authorization": []string{"Token " + key}}
+
+	ret, err := p.iex(ctx, symbols, header)
+	if err != nil {
+		return nil, err
+	}
+
+	var missing []string
+	for _, symbol := range symbols {
+		if _, ok := provider.Find(ret, symbol); !ok {
+			missing = append(missing, symbol)
+		}
+	}
+	if len(missing) == 0 {
+		return ret, nil
+	}
+	eod, err := provider.Each(ctx, missing, func(ctx context.Context, symbol string) (provider.Quote, error) {
+		return p.eod(ctx, symbol, header)
+	})
+	if err != nil && len(ret) == 0 {
+		return nil, err
+	}
+	return append(ret, eod...), nil
+}
+
+// iex returns the real-time quotes of symbols traded on IEX.
+func (p *Provider) iex(ctx context.Context, symbols []string, header http.Header) ([]provider.Quote, error) {
+	if err := p.Limiter.Allow(); err != nil {
+		return nil, err
+	}
+
+	var resp []iexQuote
+	u := fmt.Sprintf(iexURL, url.QueryEscape(strings.ToLower(strings.Join(symbols, ","))))
+	if err := provider.GetJSON(ctx, u, header, &resp); err != nil {
+		return nil, err
+	}
+
+	var ret []provider.Quote
+	for _, r := range resp {
+		if r.TngoLast == 0 {
+			continue
+		}
+		ret = append(ret, provider.Quote{
+			Symbol:        r.Ticker,
+			Price:         r.TngoLast,
+			Currency:      "USD",
+			Time:          r.Timestamp,
+			Open:          r.Open,
+			High:          r.High,
+			Low:           r.Low,
+			PreviousClose: r.PrevClose,
+		})
+	}
+	return ret, nil
+}
+
+// eod returns the latest end-of-day price of a symbol.
+func (p *Provider) eod(ctx context.Context, symbol string, header http.Header) (provider.Quote, error) {
+	if err := p.Limiter.Allow(); err != nil {
+		return provider.Quote{}, fmt.Errorf("%s: %w", symbol, err)
+	}
+
+	var resp []eodPrice
+	u := fmt.Sprintf(eodURL, url.PathEscape(strings.ToLower(symbol)))
+	if err := provider.GetJSON(ctx, u, header, &resp); err != nil {
+		return provider.Quote{}, err
+	}
+	if len(resp) == 0 || resp[len(resp)-1].Close == 0 {
+		return provider.Quote{}, fmt.Errorf("%w: %s", provider.ErrNotFound, symbol)
+	}
+	last := resp[len(resp)-1]
+	// The date of a close is midnight UTC; EOD prices don't report the time
+	// of the trade, so leave it unset.
+	return provider.Quote{
+		Symbol:   symbol,
+		Price:    last.Close,
+		Currency: "USD",
+		Open:     last.Open,
+		High:     last.High,
+		Low:      last.Low,
+	}, nil
+}
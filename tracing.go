@@ -0,0 +1,62 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// traceID returns the trace ID in the W3C traceparent header of a request
+// (e.g. "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"), or an
+// empty string if there's no valid header.
+func traceID(r *http.Request) string {
+	parts := strings.Split(r.Header.Get("traceparent"), "-")
+	if len(parts) != 4 || len(parts[1]) != 32 || strings.Trim(parts[1], "0") == "" {
+		return ""
+	}
+	for _, c := range parts[1] {
+		if !strings.ContainsRune("0123456789abcdef", c) {
+			return ""
+		}
+	}
+	return parts[1]
+}
+
+// exemplar returns the exemplar labels for a trace ID, or nil if there's no
+// trace ID.
+func exemplar(traceID string) prometheus.Labels {
+	if traceID == "" {
+		return nil
+	}
+	return prometheus.Labels{"trace_id": traceID}
+}
+
+// observe records a value in a histogram, with a trace ID exemplar if any.
+func observe(h prometheus.Histogram, v float64, traceID string) {
+	if eo, ok := h.(prometheus.ExemplarObserver); ok && traceID != "" {
+		eo.ObserveWithExemplar(v, exemplar(traceID))
+		return
+	}
+	h.Observe(v)
+}
+
+// inc increments a counter, with a trace ID exemplar if any.
+func inc(c prometheus.Counter, traceID string) {
+	if ea, ok := c.(prometheus.ExemplarAdder); ok && traceID != "" {
+		ea.AddWithExemplar(1, exemplar(traceID))
+		return
+	}
+	c.Inc()
+}
@@ -0,0 +1,131 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package tradier
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/marcopaganini/quotes-exporter/provider"
+)
+
+const (
+	quotesURL        = "https://api.tradier.com/v1/markets/quotes?symbols=%s"
+	sandboxQuotesURL = "https://sandbox.tradier.com/v1/markets/quotes?symbols=%s"
+)
+
+// quote is a quote returned by the quotes API.
+type quote struct {
+	Symbol           string  `json:"symbol"`
+	Last             float64 `json:"last"`
+	Open             float64 `json:"open"`
+	High             float64 `json:"high"`
+	Low              float64 `json:"low"`
+	PrevClose        float64 `json:"prevclose"`
+	Volume           float64 `json:"volume"`
+	ChangePercentage float64 `json:"change_percentage"`
+	// TradeDate is the time of the last trade, in milliseconds since the
+	// epoch.
+	TradeDate int64 `json:"trade_date"`
+}
+
+// quotesResponse is the response of the quotes API. A single quote is
+// returned as an object, several as an array.
+type quotesResponse struct {
+	Quotes struct {
+		Quote json.RawMessage `json:"quote"`
+	} `json:"quotes"`
+}
+
+// Provider is the Tradier quote provider. Option symbols use the OCC format
+// (e.g. SPY230721C00450000).
+type Provider struct {
+	// Key returns the access token.
+	Key func() string
+	// Sandbox selects the sandbox (delayed data) API.
+	Sandbox bool
+	// Limiter limits the requests sent upstream.
+	Limiter *provider.Limiter
+}
+
+// Quote returns the quotes of the given symbols with one request.
+func (p *Provider) Quote(ctx context.Context, symbols []string) ([]provider.Quote, error) {
+	var key string
+	if p.Key != nil {
+		key = p.Key()
+	}
+	key = provider.Token(ctx, key)
+	if key == "" {
+		return nil, errors.New("missing Tradier access token")
+	}
+	header := http.Header{
+		"Authorization": []string{"Bearer " + key},
+		"Accept":        []string{"application/json"},
+	}
+	base := quotesURL
+	if p.Sandbox {
+		base = sandboxQuotesURL
+	}
+	if err := p.Limiter.Allow(); err != nil {
+		return nil, err
+	}
+
+	var resp quotesResponse
+	u := fmt.Sprintf(base, url.QueryEscape(strings.ToUpper(strings.Join(symbols, ","))))
+	if err := provider.GetJSON(ctx, u, header, &resp); err != nil {
+		return nil, err
+	}
+
+	var quotes []quote
+	if raw := resp.Quotes.Quote; len(raw) > 0 {
+		if raw[0] == '[' {
+			if err := json.Unmarshal(raw, &quotes); err != nil {
+				return nil, fmt.Errorf("error decoding quotes: %v", err)
+			}
+		} else {
+			var q quote
+			if err := json.Unmarshal(raw, &q); err != nil {
+				return nil, fmt.Errorf("error decoding quote: %v", err)
+			}
+			quotes = append(quotes, q)
+		}
+	}
+
+	var ret []provider.Quote
+	for _, q := range quotes {
+		if q.Last == 0 {
+			continue
+		}
+		pq := provider.Quote{
+			Symbol:        q.Symbol,
+			Price:         q.Last,
+			Currency:      "USD",
+			Open:          q.Open,
+			High:          q.High,
+			Low:           q.Low,
+			PreviousClose: q.PrevClose,
+			Volume:        q.Volume,
+			ChangePercent: q.ChangePercentage,
+		}
+		if q.TradeDate != 0 {
+			pq.Time = time.Unix(0, q.TradeDate*int64(time.Millisecond))
+		}
+		ret = append(ret, pq)
+	}
+	return ret, nil
+}
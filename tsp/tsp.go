@@ -0,0 +1,129 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package tsp
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/marcopaganini/quotes-exporter/provider"
+)
+
+const (
+	pricesURL = "https://www.tsp.gov/data/fund-price-history.csv?startdate=%s&enddate=%s&Lfunds=1&InvFunds=1&download=1"
+
+	// lookback is how far back to fetch prices, enough to cover holidays.
+	lookback = 14 * 24 * time.Hour
+)
+
+// dateFormats are the formats of the date column seen in the CSV.
+var dateFormats = []string{"2006-01-02", "Jan 2, 2006", "01/02/2006"}
+
+// Provider is the Thrift Savings Plan provider. Symbols are fund names, as in
+// the tsp.gov share price table, with or without spaces and the "Fund" suffix:
+// "C", "G Fund", "L2050", "L Income".
+type Provider struct {
+	// Limiter limits the requests sent upstream.
+	Limiter *provider.Limiter
+}
+
+// Quote returns the latest share prices of the given funds, fetched with one
+// request.
+func (p *Provider) Quote(ctx context.Context, symbols []string) ([]provider.Quote, error) {
+	if err := p.Limiter.Allow(); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	u := fmt.Sprintf(pricesURL, now.Add(-lookback).Format("2006-01-02"), now.Format("2006-01-02"))
+	body, err := provider.Get(ctx, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	prices, date, err := latest(body)
+	if err != nil {
+		return nil, err
+	}
+
+	var ret []provider.Quote
+	for _, symbol := range symbols {
+		price, ok := prices[fund(symbol)]
+		if !ok || price == 0 {
+			continue
+		}
+		ret = append(ret, provider.Quote{Symbol: symbol, Price: price, Currency: "USD", Time: date})
+	}
+	return ret, nil
+}
+
+// latest returns the prices in the most recent row of the share price CSV,
+// keyed by fund, and the date of that row.
+func latest(body []byte) (map[string]float64, time.Time, error) {
+	r := csv.NewReader(bytes.NewReader(body))
+	r.FieldsPerRecord = -1
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("error parsing TSP prices: %v", err)
+	}
+	if len(records) < 2 {
+		return nil, time.Time{}, fmt.Errorf("no TSP prices returned")
+	}
+
+	var (
+		row  []string
+		date time.Time
+	)
+	for _, rec := range records[1:] {
+		d, err := parseDate(rec[0])
+		if err != nil || !d.After(date) {
+			continue
+		}
+		row, date = rec, d
+	}
+	if row == nil {
+		return nil, time.Time{}, fmt.Errorf("no dated rows in TSP prices")
+	}
+
+	prices := map[string]float64{}
+	for i, name := range records[0] {
+		if i == 0 || i >= len(row) {
+			continue
+		}
+		v, err := strconv.ParseFloat(strings.TrimSpace(row[i]), 64)
+		if err != nil {
+			continue
+		}
+		prices[fund(name)] = v
+	}
+	return prices, date, nil
+}
+
+// parseDate parses the date column of the CSV.
+func parseDate(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	for _, f := range dateFormats {
+		if t, err := time.Parse(f, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q", s)
+}
+
+// fund returns the normalized name of a fund ("l 2050 fund" -> "L2050").
+func fund(name string) string {
+	name = strings.ToUpper(strings.Join(strings.Fields(name), ""))
+	return strings.TrimSuffix(name, "FUND")
+}
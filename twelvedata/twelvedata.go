@@ -0,0 +1,151 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package twelvedata
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/marcopaganini/quotes-exporter/provider"
+)
+
+const (
+	quoteURL = "https://api.twelvedata.com/quote?symbol=%s&apikey=%s"
+
+	// maxBatch is the maximum number of symbols in a single request.
+	maxBatch = 120
+)
+
+// quoteResponse is a quote returned by the quote API. Numbers are strings.
+type quoteResponse struct {
+	Symbol        string `json:"symbol"`
+	Currency      string `json:"currency"`
+	Timestamp     int64  `json:"timestamp"`
+	Open          string `json:"open"`
+	High          string `json:"high"`
+	Low           string `json:"low"`
+	Close         string `json:"close"`
+	PreviousClose string `json:"previous_close"`
+	// Errors are reported with a status of "error".
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
+
+// Provider is the Twelve Data quote provider.
+type Provider struct {
+	// Key returns the API key.
+	Key func() string
+	// Limiter limits the requests sent upstream.
+	Limiter *provider.Limiter
+}
+
+// Quote returns the quotes of the given symbols, in one request per batch
+// of up to 120 symbols.
+func (p *Provider) Quote(ctx context.Context, symbols []string) ([]provider.Quote, error) {
+	var key string
+	if p.Key != nil {
+		key = p.Key()
+	}
+	key = provider.Token(ctx, key)
+	if key == "" {
+		return nil, errors.New("missing Twelve Data API key")
+	}
+
+	var ret []provider.Quote
+	for len(symbols) > 0 {
+		n := len(symbols)
+		if n > maxBatch {
+			n = maxBatch
+		}
+		quotes, err := p.batch(ctx, symbols[:n], key)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, quotes...)
+		symbols = symbols[n:]
+	}
+	return ret, nil
+}
+
+// batch returns the quotes of a batch of symbols.
+func (p *Provider) batch(ctx context.Context, symbols []string, key string) ([]provider.Quote, error) {
+	if err := p.Limiter.Allow(); err != nil {
+		return nil, err
+	}
+
+	var raw json.RawMessage
+	u := fmt.Sprintf(quoteURL, url.QueryEscape(strings.ToUpper(strings.Join(symbols, ","))), url.QueryEscape(key))
+	if err := provider.GetJSON(ctx, u, nil, &raw); err != nil {
+		return nil, err
+	}
+
+	// A single symbol returns a quote, several return quotes keyed by
+	// symbol. Errors affecting the whole request return a single error.
+	var single quoteResponse
+	if err := json.Unmarshal(raw, &single); err != nil {
+		return nil, fmt.Errorf("error decoding response: %v", err)
+	}
+	if single.Status == "error" {
+		return nil, fmt.Errorf("upstream error: %s", single.Message)
+	}
+	resps := []quoteResponse{single}
+	if len(symbols) > 1 {
+		var multi map[string]quoteResponse
+		if err := json.Unmarshal(raw, &multi); err != nil {
+			return nil, fmt.Errorf("error decoding response: %v", err)
+		}
+		resps = nil
+		for _, r := range multi {
+			resps = append(resps, r)
+		}
+	}
+
+	var ret []provider.Quote
+	for _, r := range resps {
+		if r.Status == "error" {
+			continue
+		}
+		price := parseFloat(r.Close)
+		if price == 0 {
+			continue
+		}
+		q := provider.Quote{
+			Symbol:        r.Symbol,
+			Price:         price,
+			Currency:      r.Currency,
+			Open:          parseFloat(r.Open),
+			High:          parseFloat(r.High),
+			Low:           parseFloat(r.Low),
+			PreviousClose: parseFloat(r.PreviousClose),
+		}
+		if r.Timestamp != 0 {
+			q.Time = time.Unix(r.Timestamp, 0)
+		}
+		ret = append(ret, q)
+	}
+	return ret, nil
+}
+
+// parseFloat parses a number, returning zero (unknown) on errors.
+func parseFloat(s string) float64 {
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0
+	}
+	return v
+}
@@ -0,0 +1,71 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/marcopaganini/quotes-exporter/provider"
+)
+
+// warmCache fetches the quotes of watchlist symbols missing from the cache,
+// so the first scrapes after a restart don't wait on upstream. Symbols of
+// providers out of requests are left for scrapes to fetch.
+func warmCache() {
+	cfgMu.RLock()
+	symbols := watchlists.symbols()
+	cfgMu.RUnlock()
+	if len(symbols) == 0 {
+		return
+	}
+	log := logger("cache")
+	log.Info("Warming up cache", "symbols", len(symbols))
+	start := time.Now()
+
+	// The configuration is held for one request at a time, like scrapes do,
+	// so a pending reload doesn't wait for the whole warm up.
+	ctx := context.Background()
+	cfgMu.RLock()
+	collector{symbols: symbols}.prefetch(ctx, symbols)
+	cfgMu.RUnlock()
+
+	limited := map[string]bool{}
+	var failed int
+	for _, symbol := range symbols {
+		cfgMu.RLock()
+		pname, err := warmQuote(ctx, symbol, limited)
+		cfgMu.RUnlock()
+		switch {
+		case errors.Is(err, provider.ErrRateLimited):
+			log.Warn("Provider rate limit reached, skipping warm up", "provider", pname)
+			limited[pname] = true
+		case err != nil:
+			failed++
+			log.Warn("Error warming up quote", "symbol", symbol, "error", err)
+		}
+	}
+	log.Info("Cache warmed up", "failed", failed, "duration", time.Since(start))
+}
+
+// warmQuote fetches the quote of a symbol missing from the cache, unless its
+// provider is out of requests, returning the name of the provider.
+func warmQuote(ctx context.Context, symbol string, limited map[string]bool) (string, error) {
+	pname := providerFor(symbol, "")
+	key := quoteKey(symbol, "")
+	if _, ok := cache.Storage.Get(key); ok || limited[pname] {
+		return pname, nil
+	}
+	_, err, _ := memoizeQuote(key, symbol, quoteFetcher(ctx, symbol, ""))
+	return pname, err
+}
@@ -0,0 +1,178 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// watchlistStore holds the watchlists. These start with the lists in the
+// configuration file, but can be changed at runtime using the admin API. If
+// a state file is configured, changes are persisted there and take
+// precedence over the configuration file on the next start.
+type watchlistStore struct {
+	sync.RWMutex
+	lists     map[string][]string
+	stateFile string
+}
+
+// watchlists holds the running watchlists.
+var watchlists watchlistStore
+
+// load initializes the store from the configured lists and the state file.
+func (w *watchlistStore) load(lists map[string][]string, stateFile string) error {
+	w.Lock()
+	defer w.Unlock()
+
+	w.stateFile = stateFile
+	w.lists = map[string][]string{}
+	for name, symbols := range lists {
+		w.lists[name] = append([]string(nil), symbols...)
+	}
+	if stateFile == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(stateFile)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var state map[string][]string
+	if err := json.Unmarshal(data, &state); err != nil {
+		return err
+	}
+	// A state file holding null leaves the map nil.
+	if state == nil {
+		state = map[string][]string{}
+	}
+	w.lists = state
+	return nil
+}
+
+// get returns the symbols in a watchlist.
+func (w *watchlistStore) get(name string) ([]string, bool) {
+	w.RLock()
+	defer w.RUnlock()
+	symbols, ok := w.lists[name]
+	return append([]string(nil), symbols...), ok
+}
+
+// all returns a copy of all watchlists.
+func (w *watchlistStore) all() map[string][]string {
+	w.RLock()
+	defer w.RUnlock()
+	ret := map[string][]string{}
+	for name, symbols := range w.lists {
+		ret[name] = append([]string(nil), symbols...)
+	}
+	return ret
+}
+
+// symbols returns the union of the symbols in all watchlists, sorted.
+func (w *watchlistStore) symbols() []string {
+	w.RLock()
+	defer w.RUnlock()
+
+	seen := map[string]bool{}
+	var ret []string
+	for _, symbols := range w.lists {
+		for _, s := range symbols {
+			if !seen[s] {
+				seen[s] = true
+				ret = append(ret, s)
+			}
+		}
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// add adds symbols to a watchlist, creating it if needed.
+func (w *watchlistStore) add(name string, symbols []string) error {
+	w.Lock()
+	defer w.Unlock()
+
+	for _, s := range symbols {
+		if !containsFold(w.lists[name], s) {
+			w.lists[name] = append(w.lists[name], s)
+		}
+	}
+	return w.save()
+}
+
+// remove removes symbols from a watchlist. Removing all symbols (or passing
+// no symbols at all) deletes the watchlist.
+func (w *watchlistStore) remove(name string, symbols []string) error {
+	w.Lock()
+	defer w.Unlock()
+
+	var keep []string
+	if len(symbols) > 0 {
+		for _, s := range w.lists[name] {
+			if !containsFold(symbols, s) {
+				keep = append(keep, s)
+			}
+		}
+	}
+	if len(keep) == 0 {
+		delete(w.lists, name)
+	} else {
+		w.lists[name] = keep
+	}
+	return w.save()
+}
+
+// save writes the watchlists to the state file, if configured. Must be
+// called with the lock held.
+func (w *watchlistStore) save() error {
+	if w.stateFile == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(w.lists, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file and rename, so a crash never leaves a
+	// truncated state file behind.
+	tmp, err := os.CreateTemp(filepath.Dir(w.stateFile), ".state-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), w.stateFile)
+}
+
+// containsFold returns true if list contains s, ignoring case.
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
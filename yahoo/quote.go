@@ -0,0 +1,82 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package yahoo
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+const (
+	quoteURL = "https://query1.finance.yahoo.com/v7/finance/quote?symbols=%s&crumb=%s"
+
+	// maxBatch is the maximum number of symbols in a single quote request.
+	maxBatch = 50
+)
+
+// quoteResponse is the response of the quote API.
+type quoteResponse struct {
+	QuoteResponse struct {
+		Result []struct {
+			Symbol                     string  `json:"symbol"`
+			Currency                   string  `json:"currency"`
+			QuoteType                  string  `json:"quoteType"`
+			RegularMarketPrice         float64 `json:"regularMarketPrice"`
+			RegularMarketTime          int64   `json:"regularMarketTime"`
+			RegularMarketPreviousClose float64 `json:"regularMarketPreviousClose"`
+			RegularMarketChangePercent float64 `json:"regularMarketChangePercent"`
+		} `json:"result"`
+		Error *struct {
+			Code        string `json:"code"`
+			Description string `json:"description"`
+		} `json:"error"`
+	} `json:"quoteResponse"`
+}
+
+// Quotes returns the metadata and latest price of many symbols, using as few
+// requests as possible. Symbols unknown to Yahoo are left out of the result.
+func Quotes(ctx context.Context, symbols []string) ([]Meta, error) {
+	var ret []Meta
+	for len(symbols) > 0 {
+		n := len(symbols)
+		if n > maxBatch {
+			n = maxBatch
+		}
+		batch := symbols[:n]
+		symbols = symbols[n:]
+
+		var quotes quoteResponse
+		u := func(crumb string) string {
+			return fmt.Sprintf(quoteURL, url.QueryEscape(strings.ToUpper(strings.Join(batch, ","))), url.QueryEscape(crumb))
+		}
+		if _, err := sess.getJSON(ctx, u, &quotes); err != nil {
+			return nil, fmt.Errorf("error fetching quotes: %w", err)
+		}
+		if e := quotes.QuoteResponse.Error; e != nil {
+			return nil, fmt.Errorf("upstream error: %s: %s", e.Code, e.Description)
+		}
+		for _, r := range quotes.QuoteResponse.Result {
+			ret = append(ret, Meta{
+				Symbol:             r.Symbol,
+				Currency:           r.Currency,
+				InstrumentType:     r.QuoteType,
+				RegularMarketPrice: r.RegularMarketPrice,
+				RegularMarketTime:  r.RegularMarketTime,
+				PreviousClose:      r.RegularMarketPreviousClose,
+				ChangePercent:      r.RegularMarketChangePercent,
+			})
+		}
+	}
+	return ret, nil
+}
@@ -0,0 +1,145 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package yahoo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"strings"
+	"sync"
+
+	"github.com/marcopaganini/quotes-exporter/provider"
+)
+
+const (
+	// Visiting this URL sets the cookie required to obtain a crumb.
+	cookieURL = "https://fc.yahoo.com"
+	crumbURL  = "https://query1.finance.yahoo.com/v1/test/getcrumb"
+)
+
+// session holds the cookies and crumb needed by the authenticated endpoints.
+type session struct {
+	sync.Mutex
+	client *http.Client
+	crumb  string
+}
+
+var sess session
+
+// login obtains a new cookie and crumb. Must be called with the session lock held.
+func (s *session) login(ctx context.Context) error {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return err
+	}
+	s.client = &http.Client{Jar: jar, Transport: provider.Client.Transport}
+
+	// This request usually returns an error status, but sets the cookie.
+	resp, err := s.get(ctx, cookieURL)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	resp, err = s.get(ctx, crumbURL)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	crumb := strings.TrimSpace(string(body))
+	if resp.StatusCode != http.StatusOK || crumb == "" || strings.Contains(crumb, " ") {
+		return fmt.Errorf("unable to obtain crumb (HTTP %d): %q", resp.StatusCode, crumb)
+	}
+	s.crumb = crumb
+	return nil
+}
+
+// get performs a GET request using the session client.
+func (s *session) get(ctx context.Context, u string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("User-Agent", userAgent)
+	return s.client.Do(req)
+}
+
+// credentials returns the session client and crumb, logging in if needed.
+func (s *session) credentials(ctx context.Context) (*http.Client, string, error) {
+	s.Lock()
+	defer s.Unlock()
+	if s.crumb == "" {
+		if err := s.login(ctx); err != nil {
+			return nil, "", err
+		}
+	}
+	return s.client, s.crumb, nil
+}
+
+// getJSON fetches the URL returned by u for the session crumb and decodes the
+// JSON response into v, returning the HTTP status. An expired cookie or crumb
+// (HTTP 401) causes a new login and one retry.
+func (s *session) getJSON(ctx context.Context, u func(crumb string) string, v interface{}) (int, error) {
+	for attempt := 0; ; attempt++ {
+		client, crumb, err := s.credentials(ctx)
+		if err != nil {
+			return 0, err
+		}
+
+		req, err := http.NewRequest(http.MethodGet, u(crumb), nil)
+		if err != nil {
+			return 0, err
+		}
+		req = req.WithContext(ctx)
+		req.Header.Set("User-Agent", userAgent)
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return 0, err
+		}
+
+		if resp.StatusCode == http.StatusUnauthorized {
+			resp.Body.Close()
+			s.Lock()
+			if s.crumb == crumb {
+				s.crumb = ""
+			}
+			s.Unlock()
+			if attempt == 0 {
+				continue
+			}
+			return resp.StatusCode, fmt.Errorf("unauthorized by upstream (HTTP %d)", resp.StatusCode)
+		}
+		if resp.StatusCode == http.StatusTooManyRequests {
+			err := provider.NewHTTPError(resp)
+			resp.Body.Close()
+			return resp.StatusCode, err
+		}
+
+		err = json.NewDecoder(resp.Body).Decode(v)
+		resp.Body.Close()
+		if err != nil {
+			return resp.StatusCode, fmt.Errorf("error decoding response (HTTP %d): %v", resp.StatusCode, err)
+		}
+		return resp.StatusCode, nil
+	}
+}
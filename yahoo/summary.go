@@ -0,0 +1,164 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package yahoo
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const summaryURL = "https://query2.finance.yahoo.com/v10/finance/quoteSummary/%s?modules=%s&crumb=%s"
+
+// Calendar holds the upcoming events of a symbol. Zero times mean unknown.
+type Calendar struct {
+	ExDividendDate time.Time
+	DividendDate   time.Time
+	EarningsDates  []time.Time
+}
+
+// Holding holds one of the top holdings of a fund.
+type Holding struct {
+	Symbol string
+	Name   string
+	// Weight of the holding in the fund, as a ratio (0.05 = 5%).
+	Weight float64
+}
+
+// Price holds the daily change and size of a symbol.
+type Price struct {
+	Currency      string
+	PreviousClose float64
+	// ChangePercent is the change from the previous close, in percent.
+	ChangePercent float64
+	// MarketCap is zero for assets without a market capitalization.
+	MarketCap float64
+}
+
+// rawValue is the representation of most numeric values in quoteSummary.
+type rawValue struct {
+	Raw float64 `json:"raw"`
+}
+
+// time returns the raw value as a time, or the zero time if unset.
+func (r *rawValue) time() time.Time {
+	if r == nil || r.Raw == 0 {
+		return time.Time{}
+	}
+	return time.Unix(int64(r.Raw), 0)
+}
+
+// summaryResponse mirrors the parts of the quoteSummary response we use.
+type summaryResponse struct {
+	QuoteSummary struct {
+		Result []struct {
+			CalendarEvents struct {
+				ExDividendDate *rawValue `json:"exDividendDate"`
+				DividendDate   *rawValue `json:"dividendDate"`
+				Earnings       struct {
+					EarningsDate []rawValue `json:"earningsDate"`
+				} `json:"earnings"`
+			} `json:"calendarEvents"`
+			Price struct {
+				Currency                   string   `json:"currency"`
+				RegularMarketPreviousClose rawValue `json:"regularMarketPreviousClose"`
+				RegularMarketChangePercent rawValue `json:"regularMarketChangePercent"`
+				MarketCap                  rawValue `json:"marketCap"`
+			} `json:"price"`
+			TopHoldings struct {
+				Holdings []struct {
+					Symbol         string   `json:"symbol"`
+					HoldingName    string   `json:"holdingName"`
+					HoldingPercent rawValue `json:"holdingPercent"`
+				} `json:"holdings"`
+			} `json:"topHoldings"`
+		} `json:"result"`
+		Error *struct {
+			Code        string `json:"code"`
+			Description string `json:"description"`
+		} `json:"error"`
+	} `json:"quoteSummary"`
+}
+
+// CalendarEvents returns the upcoming dividend and earnings dates of a symbol.
+func CalendarEvents(ctx context.Context, symbol string) (Calendar, error) {
+	summary, err := fetchSummary(ctx, symbol, "calendarEvents")
+	if err != nil {
+		return Calendar{}, err
+	}
+	ce := summary.QuoteSummary.Result[0].CalendarEvents
+
+	cal := Calendar{
+		ExDividendDate: ce.ExDividendDate.time(),
+		DividendDate:   ce.DividendDate.time(),
+	}
+	for _, e := range ce.Earnings.EarningsDate {
+		cal.EarningsDates = append(cal.EarningsDates, e.time())
+	}
+	return cal, nil
+}
+
+// PriceSummary returns the daily change and market capitalization of a symbol.
+func PriceSummary(ctx context.Context, symbol string) (Price, error) {
+	summary, err := fetchSummary(ctx, symbol, "price")
+	if err != nil {
+		return Price{}, err
+	}
+	p := summary.QuoteSummary.Result[0].Price
+	return Price{
+		Currency:      p.Currency,
+		PreviousClose: p.RegularMarketPreviousClose.Raw,
+		// Upstream returns the change as a ratio.
+		ChangePercent: p.RegularMarketChangePercent.Raw * 100,
+		MarketCap:     p.MarketCap.Raw,
+	}, nil
+}
+
+// TopHoldings returns the top holdings (usually ten) of an ETF or mutual fund.
+func TopHoldings(ctx context.Context, symbol string) ([]Holding, error) {
+	summary, err := fetchSummary(ctx, symbol, "topHoldings")
+	if err != nil {
+		return nil, err
+	}
+
+	var ret []Holding
+	for _, h := range summary.QuoteSummary.Result[0].TopHoldings.Holdings {
+		ret = append(ret, Holding{Symbol: h.Symbol, Name: h.HoldingName, Weight: h.HoldingPercent.Raw})
+	}
+	if len(ret) == 0 {
+		return nil, fmt.Errorf("no holdings data for %s", symbol)
+	}
+	return ret, nil
+}
+
+// fetchSummary retrieves the given quoteSummary modules for a symbol.
+func fetchSummary(ctx context.Context, symbol, modules string) (summaryResponse, error) {
+	var summary summaryResponse
+	symbol = strings.ToUpper(symbol)
+
+	u := func(crumb string) string {
+		return fmt.Sprintf(summaryURL, url.PathEscape(symbol), modules, url.QueryEscape(crumb))
+	}
+	status, err := sess.getJSON(ctx, u, &summary)
+	if err != nil {
+		return summary, fmt.Errorf("error fetching summary data: %w", err)
+	}
+	if summary.QuoteSummary.Error != nil {
+		return summary, fmt.Errorf("upstream error: %s: %s", summary.QuoteSummary.Error.Code, summary.QuoteSummary.Error.Description)
+	}
+	if len(summary.QuoteSummary.Result) == 0 {
+		return summary, fmt.Errorf("empty summary results (HTTP %d)", status)
+	}
+	return summary, nil
+}
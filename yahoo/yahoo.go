@@ -0,0 +1,272 @@
+// (C) 2023 by Marco Paganini <paganini@paganini.net>
+//
+// You may obtain a copy of the License at
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS, WITHOUT
+// WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.  See the
+// License for the specific language governing permissions and limitations
+// under the License.
+
+package yahoo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/marcopaganini/quotes-exporter/provider"
+)
+
+const (
+	chartURL = "https://query1.finance.yahoo.com/v8/finance/chart/%s?period1=%d&period2=%d&interval=1d"
+	metaURL  = "https://query1.finance.yahoo.com/v8/finance/chart/%s?range=1d&interval=1d"
+	eventURL = "https://query1.finance.yahoo.com/v8/finance/chart/%s?range=10y&interval=3mo&events=div,split"
+	rangeURL = "https://query1.finance.yahoo.com/v8/finance/chart/%s?range=%s&interval=%s"
+
+	// Yahoo tends to reject requests carrying the default Go User-Agent.
+	userAgent = "Mozilla/5.0 (X11; Linux x86_64; rv:109.0) Gecko/20100101 Firefox/115.0"
+)
+
+// Close holds the closing price of a symbol for one trading day.
+type Close struct {
+	Time  time.Time
+	Price float64
+}
+
+// Meta holds descriptive information and the latest price of a symbol.
+type Meta struct {
+	Symbol             string  `json:"symbol"`
+	Currency           string  `json:"currency"`
+	InstrumentType     string  `json:"instrumentType"`
+	RegularMarketPrice float64 `json:"regularMarketPrice"`
+	// RegularMarketTime is the time of the last trade, in seconds since
+	// the epoch.
+	RegularMarketTime int64 `json:"regularMarketTime"`
+	// PreviousClose is the close of the previous trading day.
+	PreviousClose float64 `json:"chartPreviousClose"`
+	// ChangePercent is the change from the previous close, in percent. Only
+	// the quote API reports it.
+	ChangePercent float64 `json:"regularMarketChangePercent"`
+}
+
+// Dividend holds a dividend payment.
+type Dividend struct {
+	Time   time.Time
+	Amount float64
+}
+
+// Split holds a stock split. A 4:1 split has Numerator=4 and Denominator=1.
+type Split struct {
+	Time        time.Time
+	Numerator   float64
+	Denominator float64
+	Ratio       string
+}
+
+// chartResponse mirrors the parts of the chart API response we care about.
+type chartResponse struct {
+	Chart struct {
+		Result []struct {
+			Meta       Meta    `json:"meta"`
+			Timestamp  []int64 `json:"timestamp"`
+			Indicators struct {
+				Quote []struct {
+					// Close values may be null for days without trading.
+					Close []*float64 `json:"close"`
+				} `json:"quote"`
+			} `json:"indicators"`
+			Events struct {
+				Dividends map[string]struct {
+					Amount float64 `json:"amount"`
+					Date   int64   `json:"date"`
+				} `json:"dividends"`
+				Splits map[string]struct {
+					Date        int64   `json:"date"`
+					Numerator   float64 `json:"numerator"`
+					Denominator float64 `json:"denominator"`
+					SplitRatio  string  `json:"splitRatio"`
+				} `json:"splits"`
+			} `json:"events"`
+		} `json:"result"`
+		Error *struct {
+			Code        string `json:"code"`
+			Description string `json:"description"`
+		} `json:"error"`
+	} `json:"chart"`
+}
+
+// History returns the daily closes for a symbol since the given time, oldest
+// first. The last element refers to the current (possibly ongoing) session.
+func History(ctx context.Context, symbol string, since time.Time) ([]Close, error) {
+	symbol = strings.ToUpper(symbol)
+
+	u := fmt.Sprintf(chartURL, url.PathEscape(symbol), since.Unix(), time.Now().Unix())
+	return fetchCloses(ctx, symbol, u)
+}
+
+// Intraday returns the prices of a symbol over a range (e.g. "1d" or "5d"),
+// sampled at an interval (e.g. "5m"), oldest first. Valid ranges and
+// intervals are listed in Ranges and Intervals.
+func Intraday(ctx context.Context, symbol, rng, interval string) ([]Close, error) {
+	symbol = strings.ToUpper(symbol)
+	return fetchCloses(ctx, symbol, fmt.Sprintf(rangeURL, url.PathEscape(symbol), url.QueryEscape(rng), url.QueryEscape(interval)))
+}
+
+// Ranges and Intervals hold the values accepted by the chart API.
+var (
+	Ranges    = []string{"1d", "5d", "1mo", "3mo", "6mo", "1y", "2y", "5y", "10y", "ytd", "max"}
+	Intervals = []string{"1m", "2m", "5m", "15m", "30m", "60m", "90m", "1h", "1d", "5d", "1wk", "1mo", "3mo"}
+)
+
+// fetchCloses retrieves a chart API URL and returns its prices.
+func fetchCloses(ctx context.Context, symbol, u string) ([]Close, error) {
+	chart, err := fetchChart(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+
+	result := chart.Chart.Result[0]
+	if len(result.Indicators.Quote) == 0 {
+		return nil, fmt.Errorf("no history data for %s", symbol)
+	}
+	closes := result.Indicators.Quote[0].Close
+
+	var ret []Close
+	for i, ts := range result.Timestamp {
+		if i >= len(closes) || closes[i] == nil {
+			continue
+		}
+		ret = append(ret, Close{Time: time.Unix(ts, 0), Price: *closes[i]})
+	}
+	if len(ret) == 0 {
+		return nil, fmt.Errorf("empty history for %s", symbol)
+	}
+	return ret, nil
+}
+
+// Quote returns the metadata and latest price of a symbol.
+func Quote(ctx context.Context, symbol string) (Meta, error) {
+	symbol = strings.ToUpper(symbol)
+
+	chart, err := fetchChart(ctx, fmt.Sprintf(metaURL, url.PathEscape(symbol)))
+	if err != nil {
+		return Meta{}, err
+	}
+	return chart.Chart.Result[0].Meta, nil
+}
+
+// Events returns the dividends and splits of a symbol over the last ten
+// years, oldest first.
+func Events(ctx context.Context, symbol string) ([]Dividend, []Split, error) {
+	symbol = strings.ToUpper(symbol)
+
+	chart, err := fetchChart(ctx, fmt.Sprintf(eventURL, url.PathEscape(symbol)))
+	if err != nil {
+		return nil, nil, err
+	}
+	events := chart.Chart.Result[0].Events
+
+	var divs []Dividend
+	for _, d := range events.Dividends {
+		divs = append(divs, Dividend{Time: time.Unix(d.Date, 0), Amount: d.Amount})
+	}
+	sort.Slice(divs, func(i, j int) bool { return divs[i].Time.Before(divs[j].Time) })
+
+	var splits []Split
+	for _, s := range events.Splits {
+		splits = append(splits, Split{
+			Time:        time.Unix(s.Date, 0),
+			Numerator:   s.Numerator,
+			Denominator: s.Denominator,
+			Ratio:       s.SplitRatio,
+		})
+	}
+	sort.Slice(splits, func(i, j int) bool { return splits[i].Time.Before(splits[j].Time) })
+
+	return divs, splits, nil
+}
+
+// fetchChart retrieves and decodes a chart API URL.
+func fetchChart(ctx context.Context, u string) (chartResponse, error) {
+	var chart chartResponse
+
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return chart, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("User-Agent", userAgent)
+
+	resp, err := provider.Client.Do(req)
+	if err != nil {
+		return chart, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return chart, provider.NewHTTPError(resp)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&chart); err != nil {
+		return chart, fmt.Errorf("error decoding chart data (HTTP %d): %v", resp.StatusCode, err)
+	}
+	if chart.Chart.Error != nil {
+		return chart, fmt.Errorf("upstream error: %s: %s", chart.Chart.Error.Code, chart.Chart.Error.Description)
+	}
+	if len(chart.Chart.Result) == 0 {
+		return chart, fmt.Errorf("empty chart results (HTTP %d)", resp.StatusCode)
+	}
+	return chart, nil
+}
+
+// Provider is the Yahoo Finance quote provider.
+type Provider struct{}
+
+// Quote returns the quotes of the given symbols. It uses the batch quote API
+// and falls back to one chart request per symbol if that fails.
+func (Provider) Quote(ctx context.Context, symbols []string) ([]provider.Quote, error) {
+	metas, err := Quotes(ctx, symbols)
+	if err != nil {
+		slog.Debug("Batch quote failed, using the chart API", "component", "yahoo", "provider", "yahoo", "error", err)
+		return provider.Each(ctx, symbols, func(ctx context.Context, symbol string) (provider.Quote, error) {
+			meta, err := Quote(ctx, symbol)
+			if err != nil {
+				return provider.Quote{}, err
+			}
+			if meta.RegularMarketPrice == 0 {
+				return provider.Quote{}, fmt.Errorf("query returned price=0 for %s", symbol)
+			}
+			return newQuote(meta), nil
+		})
+	}
+
+	var ret []provider.Quote
+	for _, meta := range metas {
+		if meta.RegularMarketPrice != 0 {
+			ret = append(ret, newQuote(meta))
+		}
+	}
+	return ret, nil
+}
+
+// newQuote converts Yahoo metadata to a provider quote.
+func newQuote(meta Meta) provider.Quote {
+	q := provider.Quote{
+		Symbol:        meta.Symbol,
+		Price:         meta.RegularMarketPrice,
+		Currency:      meta.Currency,
+		PreviousClose: meta.PreviousClose,
+		ChangePercent: meta.ChangePercent,
+	}
+	if meta.RegularMarketTime != 0 {
+		q.Time = time.Unix(meta.RegularMarketTime, 0)
+	}
+	return q
+}